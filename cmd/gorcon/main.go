@@ -11,13 +11,23 @@ import (
 // Can be replaced while compiling with flag `-ldflags "-X main.Version=${VERSION}"`.
 var Version = "develop"
 
+// Commit and BuildTime are the VCS revision and the build time of the binary.
+// Can be replaced while compiling with flag
+// `-ldflags "-X main.Commit=${COMMIT} -X main.BuildTime=${BUILD_TIME}"`.
+var (
+	Commit    string
+	BuildTime string
+)
+
 func main() {
 	exec := executor.NewExecutor(os.Stdin, os.Stdout, Version)
+	exec.SetBuild(Commit, BuildTime)
 
 	if err := exec.Run(os.Args); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		exec.Close()
-		os.Exit(1)
+
+		os.Exit(executor.ExitCode(err))
 	}
 
 	exec.Close()
@@ -0,0 +1,476 @@
+// Package a2s contains the client of the Source query protocol (A2S) which
+// returns read-only server info, players and rules without the password.
+//
+// Requests and responses are UDP packets which start with 0xFFFFFFFF
+// header. The server answers the request with S2C_CHALLENGE to prove the
+// client address, the request is repeated with the challenge number. Long
+// responses are split into packets with 0xFFFFFFFE header.
+package a2s
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"time"
+)
+
+// Packet headers.
+const (
+	HeaderSimple int32 = -1
+	HeaderSplit  int32 = -2
+)
+
+// Request and response types.
+const (
+	RequestInfo    byte = 'T'
+	RequestPlayer  byte = 'U'
+	RequestRules   byte = 'V'
+	ResponseInfo   byte = 'I'
+	ResponsePlayer byte = 'D'
+	ResponseRules  byte = 'E'
+	Challenge      byte = 'A'
+)
+
+// DefaultPort is the query port of Source servers which is used when the
+// address has no port.
+const DefaultPort = "27015"
+
+// InfoPayload is the payload of A2S_INFO request.
+const InfoPayload = "Source Engine Query\x00"
+
+// DefaultDialTimeout and DefaultDeadline are used when the options are not
+// set.
+const (
+	DefaultDialTimeout = 5 * time.Second
+	DefaultDeadline    = 5 * time.Second
+)
+
+// maxPacketSize is the maximum size of UDP datagram.
+const maxPacketSize = 65507
+
+// Extra data flags of A2S_INFO response.
+const (
+	edfGameID   = 0x01
+	edfSteamID  = 0x10
+	edfKeywords = 0x20
+	edfSpectate = 0x40
+	edfPort     = 0x80
+)
+
+// Errors.
+var (
+	// ErrInvalidPacket is returned when the received packet is truncated or
+	// has unexpected header or type.
+	ErrInvalidPacket = errors.New("invalid packet")
+
+	// ErrCompressed is returned when the split response is compressed with
+	// bzip2 which is used only by old engine versions.
+	ErrCompressed = errors.New("compressed responses are not supported")
+)
+
+// Settings contains options of Client.
+type Settings struct {
+	dialTimeout time.Duration
+	deadline    time.Duration
+}
+
+// Option allows to inject settings to Settings.
+type Option func(s *Settings)
+
+// SetDialTimeout injects the timeout of dial to Settings.
+func SetDialTimeout(timeout time.Duration) Option {
+	return func(s *Settings) {
+		s.dialTimeout = timeout
+	}
+}
+
+// SetDeadline injects the timeout of the query response to Settings.
+func SetDeadline(timeout time.Duration) Option {
+	return func(s *Settings) {
+		s.deadline = timeout
+	}
+}
+
+// Info is the response to A2S_INFO. Fields of the extra data are zero if
+// the server does not send them. Ping is the round trip time of the query.
+type Info struct {
+	Protocol    byte          `json:"protocol"`
+	Name        string        `json:"name"`
+	Map         string        `json:"map"`
+	Folder      string        `json:"folder"`
+	Game        string        `json:"game"`
+	AppID       uint16        `json:"app_id"`
+	Players     byte          `json:"players"`
+	MaxPlayers  byte          `json:"max_players"`
+	Bots        byte          `json:"bots"`
+	ServerType  string        `json:"server_type"`
+	Environment string        `json:"environment"`
+	Private     bool          `json:"private"`
+	VAC         bool          `json:"vac"`
+	Version     string        `json:"version"`
+	Port        uint16        `json:"port,omitempty"`
+	SteamID     uint64        `json:"steam_id,omitempty"`
+	Keywords    string        `json:"keywords,omitempty"`
+	GameID      uint64        `json:"game_id,omitempty"`
+	Ping        time.Duration `json:"ping"`
+}
+
+// Player is the player of A2S_PLAYER response. Duration is the time the
+// player has been connected.
+type Player struct {
+	Index    byte          `json:"index"`
+	Name     string        `json:"name"`
+	Score    int32         `json:"score"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Client sends queries to the server.
+type Client struct {
+	conn     net.Conn
+	settings Settings
+}
+
+// Dial creates the client of the server. UDP has no handshake, so errors
+// of the unreachable server are returned by the queries.
+func Dial(address string, options ...Option) (*Client, error) {
+	settings := Settings{dialTimeout: DefaultDialTimeout, deadline: DefaultDeadline}
+	for _, option := range options {
+		option(&settings)
+	}
+
+	conn, err := net.DialTimeout("udp", address, settings.dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{conn: conn, settings: settings}, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Info sends A2S_INFO request and returns the server info.
+func (c *Client) Info() (Info, error) {
+	start := time.Now()
+
+	payload, err := c.query(RequestInfo, []byte(InfoPayload), ResponseInfo, false)
+	if err != nil {
+		return Info{}, err
+	}
+
+	info, err := decodeInfo(payload)
+	info.Ping = time.Since(start)
+
+	return info, err
+}
+
+// Players sends A2S_PLAYER request and returns the players.
+func (c *Client) Players() ([]Player, error) {
+	payload, err := c.query(RequestPlayer, nil, ResponsePlayer, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodePlayers(payload)
+}
+
+// Rules sends A2S_RULES request and returns the server rules.
+func (c *Client) Rules() (map[string]string, error) {
+	payload, err := c.query(RequestRules, nil, ResponseRules, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeRules(payload)
+}
+
+// query sends the request and returns the payload of the response without
+// the type. The request is repeated once with the challenge number if the
+// server asks for it. Player and rules requests always carry the number,
+// -1 asks for a new one.
+func (c *Client) query(request byte, payload []byte, response byte, challenge bool) ([]byte, error) {
+	body := payload
+	if challenge {
+		body = append(payload, 0xFF, 0xFF, 0xFF, 0xFF)
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if err := c.write(request, body); err != nil {
+			return nil, err
+		}
+
+		data, err := c.read()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(data) == 0 {
+			return nil, fmt.Errorf("%w: empty response", ErrInvalidPacket)
+		}
+
+		switch data[0] {
+		case response:
+			return data[1:], nil
+		case Challenge:
+			if len(data) < 5 {
+				return nil, fmt.Errorf("%w: truncated challenge", ErrInvalidPacket)
+			}
+
+			body = append(append([]byte(nil), payload...), data[1:5]...)
+		default:
+			return nil, fmt.Errorf("%w: unexpected response type %q", ErrInvalidPacket, data[0])
+		}
+	}
+
+	return nil, fmt.Errorf("%w: server repeated the challenge", ErrInvalidPacket)
+}
+
+// write sends the request with the simple header.
+func (c *Client) write(request byte, body []byte) error {
+	if err := c.conn.SetDeadline(time.Now().Add(c.settings.deadline)); err != nil {
+		return err
+	}
+
+	_, err := c.conn.Write(Encode(request, body))
+
+	return err
+}
+
+// read receives the response and joins the packets of the split response.
+func (c *Client) read() ([]byte, error) {
+	var parts [][]byte
+
+	received := 0
+
+	for {
+		packet, err := c.readPacket()
+		if err != nil {
+			return nil, err
+		}
+
+		header := int32(binary.LittleEndian.Uint32(packet))
+		if header == HeaderSimple {
+			return packet[4:], nil
+		}
+
+		total, index, part, err := decodeSplit(packet)
+		if err != nil {
+			return nil, err
+		}
+
+		if parts == nil {
+			parts = make([][]byte, total)
+		}
+
+		if index < len(parts) && parts[index] == nil {
+			parts[index] = part
+			received++
+		}
+
+		if received == len(parts) {
+			// The joined payload starts with the simple header.
+			data := bytes.Join(parts, nil)
+			if len(data) < 4 || int32(binary.LittleEndian.Uint32(data)) != HeaderSimple {
+				return nil, fmt.Errorf("%w: split response has no header", ErrInvalidPacket)
+			}
+
+			return data[4:], nil
+		}
+	}
+}
+
+// readPacket receives the packet and checks its header.
+func (c *Client) readPacket() ([]byte, error) {
+	buf := make([]byte, maxPacketSize)
+
+	n, err := c.conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if n < 5 {
+		return nil, fmt.Errorf("%w: truncated packet", ErrInvalidPacket)
+	}
+
+	switch int32(binary.LittleEndian.Uint32(buf)) {
+	case HeaderSimple, HeaderSplit:
+		return buf[:n], nil
+	default:
+		return nil, fmt.Errorf("%w: unknown header", ErrInvalidPacket)
+	}
+}
+
+// decodeSplit returns the number of packets, the index and the payload of
+// the split packet: header, ID, total, number and maximum packet size.
+func decodeSplit(packet []byte) (int, int, []byte, error) {
+	const size = 12
+
+	if len(packet) < size {
+		return 0, 0, nil, fmt.Errorf("%w: truncated split packet", ErrInvalidPacket)
+	}
+
+	if binary.LittleEndian.Uint32(packet[4:])&0x80000000 != 0 {
+		return 0, 0, nil, ErrCompressed
+	}
+
+	total, index := int(packet[8]), int(packet[9])
+	if total == 0 || index >= total {
+		return 0, 0, nil, fmt.Errorf("%w: part %d of %d", ErrInvalidPacket, index, total)
+	}
+
+	return total, index, packet[size:], nil
+}
+
+// Encode returns the request of the type with the body and the simple
+// header.
+func Encode(request byte, body []byte) []byte {
+	packet := []byte{0xFF, 0xFF, 0xFF, 0xFF, request}
+
+	return append(packet, body...)
+}
+
+// reader reads the fields of the response payload.
+type reader struct {
+	data []byte
+	err  error
+}
+
+// byte reads one byte.
+func (r *reader) byte() byte {
+	if r.err != nil || len(r.data) < 1 {
+		r.fail()
+
+		return 0
+	}
+
+	b := r.data[0]
+	r.data = r.data[1:]
+
+	return b
+}
+
+// bytes reads n bytes.
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil || len(r.data) < n {
+		r.fail()
+
+		return make([]byte, n)
+	}
+
+	b := r.data[:n]
+	r.data = r.data[n:]
+
+	return b
+}
+
+// string reads the null-terminated string.
+func (r *reader) string() string {
+	i := bytes.IndexByte(r.data, 0)
+	if r.err != nil || i < 0 {
+		r.fail()
+
+		return ""
+	}
+
+	s := string(r.data[:i])
+	r.data = r.data[i+1:]
+
+	return s
+}
+
+// fail records the error of the truncated payload.
+func (r *reader) fail() {
+	if r.err == nil {
+		r.err = fmt.Errorf("%w: truncated payload", ErrInvalidPacket)
+	}
+}
+
+// decodeInfo decodes the payload of A2S_INFO response.
+func decodeInfo(payload []byte) (Info, error) {
+	r := reader{data: payload}
+
+	info := Info{
+		Protocol: r.byte(), Name: r.string(), Map: r.string(), Folder: r.string(), Game: r.string(),
+		AppID: binary.LittleEndian.Uint16(r.bytes(2)), Players: r.byte(), MaxPlayers: r.byte(), Bots: r.byte(),
+		ServerType: serverTypes[r.byte()], Environment: environments[r.byte()],
+		Private: r.byte() == 1, VAC: r.byte() == 1,
+	}
+
+	// The Ship has additional fields which are not decoded.
+	info.Version = r.string()
+
+	if r.err != nil || len(r.data) == 0 {
+		return info, r.err
+	}
+
+	edf := r.byte()
+
+	if edf&edfPort != 0 {
+		info.Port = binary.LittleEndian.Uint16(r.bytes(2))
+	}
+
+	if edf&edfSteamID != 0 {
+		info.SteamID = binary.LittleEndian.Uint64(r.bytes(8))
+	}
+
+	if edf&edfSpectate != 0 {
+		r.bytes(2)
+		r.string()
+	}
+
+	if edf&edfKeywords != 0 {
+		info.Keywords = r.string()
+	}
+
+	if edf&edfGameID != 0 {
+		info.GameID = binary.LittleEndian.Uint64(r.bytes(8))
+	}
+
+	return info, r.err
+}
+
+// serverTypes and environments map the codes of A2S_INFO response.
+var (
+	serverTypes  = map[byte]string{'d': "dedicated", 'l': "listen", 'p': "proxy"}
+	environments = map[byte]string{'l': "linux", 'w': "windows", 'm': "mac", 'o': "mac"}
+)
+
+// decodePlayers decodes the payload of A2S_PLAYER response.
+func decodePlayers(payload []byte) ([]Player, error) {
+	r := reader{data: payload}
+
+	count := int(r.byte())
+	players := make([]Player, 0, count)
+
+	for i := 0; i < count && r.err == nil; i++ {
+		player := Player{Index: r.byte(), Name: r.string()}
+		player.Score = int32(binary.LittleEndian.Uint32(r.bytes(4)))
+
+		seconds := math.Float32frombits(binary.LittleEndian.Uint32(r.bytes(4)))
+		player.Duration = time.Duration(float64(seconds) * float64(time.Second)).Truncate(time.Second)
+
+		players = append(players, player)
+	}
+
+	return players, r.err
+}
+
+// decodeRules decodes the payload of A2S_RULES response.
+func decodeRules(payload []byte) (map[string]string, error) {
+	r := reader{data: payload}
+
+	count := int(binary.LittleEndian.Uint16(r.bytes(2)))
+	rules := make(map[string]string, count)
+
+	for i := 0; i < count && r.err == nil; i++ {
+		name := r.string()
+		rules[name] = r.string()
+	}
+
+	return rules, r.err
+}
@@ -0,0 +1,80 @@
+package a2s_test
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/a2s"
+	"github.com/gorcon/rcon-cli/internal/a2s/a2stest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient(t *testing.T) {
+	players := []string{"Gordon", "Alyx", "Barney"}
+	rules := map[string]string{"mp_friendlyfire": "0", "sv_gravity": "800", "hostname": strings.Repeat("x", 100)}
+
+	server := a2stest.NewServer("Black Mesa", "de_dust2", players, rules)
+	defer server.Close()
+
+	client, err := a2s.Dial(server.Addr(), a2s.SetDeadline(time.Second))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer client.Close()
+
+	t.Run("info", func(t *testing.T) {
+		info, err := client.Info()
+		assert.NoError(t, err)
+		assert.Equal(t, "Black Mesa", info.Name)
+		assert.Equal(t, "de_dust2", info.Map)
+		assert.Equal(t, "Counter-Strike", info.Game)
+		assert.Equal(t, uint16(10), info.AppID)
+		assert.Equal(t, byte(3), info.Players)
+		assert.Equal(t, byte(32), info.MaxPlayers)
+		assert.Equal(t, "dedicated", info.ServerType)
+		assert.Equal(t, "linux", info.Environment)
+		assert.True(t, info.VAC)
+		assert.Equal(t, uint16(27015), info.Port)
+		assert.Equal(t, "secure", info.Keywords)
+		assert.Positive(t, info.Ping)
+	})
+
+	t.Run("players", func(t *testing.T) {
+		list, err := client.Players()
+		assert.NoError(t, err)
+		assert.Equal(t, []a2s.Player{
+			{Index: 0, Name: "Gordon"},
+			{Index: 1, Name: "Alyx", Score: 1, Duration: time.Second},
+			{Index: 2, Name: "Barney", Score: 2, Duration: 2 * time.Second},
+		}, list)
+	})
+
+	// The rules response is split into several packets.
+	t.Run("rules", func(t *testing.T) {
+		got, err := client.Rules()
+		assert.NoError(t, err)
+		assert.Equal(t, rules, got)
+	})
+}
+
+func TestClientTimeout(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	client, err := a2s.Dial(conn.LocalAddr().String(), a2s.SetDeadline(10*time.Millisecond))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer client.Close()
+
+	_, err = client.Info()
+
+	var netErr net.Error
+	assert.ErrorAs(t, err, &netErr)
+	assert.True(t, netErr.Timeout())
+}
@@ -0,0 +1,162 @@
+// Package a2stest contains Source query server for tests.
+package a2stest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net"
+	"sort"
+
+	"github.com/gorcon/rcon-cli/internal/a2s"
+)
+
+// PartSize is the maximum size of the response part. Longer responses are
+// split into several packets which are sent in reverse order.
+const PartSize = 64
+
+// challenge is the number the client must send back.
+var challenge = []byte{0x12, 0x34, 0x56, 0x78}
+
+// Server is Source query server which listens on a random local UDP port.
+// Every request must carry the challenge number.
+type Server struct {
+	conn    net.PacketConn
+	info    []byte
+	players []byte
+	rules   []byte
+}
+
+// NewServer starts the server which answers with the name, map and the
+// players, who all have the score and the duration of their index.
+func NewServer(name, mapName string, players []string, rules map[string]string) *Server {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+
+	s := Server{conn: conn}
+
+	var info bytes.Buffer
+	info.WriteByte(17)
+	writeStrings(&info, name, mapName, "cstrike", "Counter-Strike")
+	_ = binary.Write(&info, binary.LittleEndian, uint16(10))
+	info.Write([]byte{byte(len(players)), 32, 0, 'd', 'l', 0, 1})
+	writeStrings(&info, "1.0.0.0")
+	info.WriteByte(0x80 | 0x20)
+	_ = binary.Write(&info, binary.LittleEndian, uint16(27015))
+	writeStrings(&info, "secure")
+	s.info = info.Bytes()
+
+	var list bytes.Buffer
+	list.WriteByte(byte(len(players)))
+
+	for i, player := range players {
+		list.WriteByte(byte(i))
+		writeStrings(&list, player)
+		_ = binary.Write(&list, binary.LittleEndian, int32(i))
+		_ = binary.Write(&list, binary.LittleEndian, math.Float32bits(float32(i)))
+	}
+
+	s.players = list.Bytes()
+
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var table bytes.Buffer
+	_ = binary.Write(&table, binary.LittleEndian, uint16(len(rules)))
+
+	for _, name := range names {
+		writeStrings(&table, name, rules[name])
+	}
+
+	s.rules = table.Bytes()
+
+	go s.serve()
+
+	return &s
+}
+
+// Addr returns the address of the server.
+func (s *Server) Addr() string {
+	return s.conn.LocalAddr().String()
+}
+
+// Close stops the server.
+func (s *Server) Close() {
+	_ = s.conn.Close()
+}
+
+// serve answers the requests until the server is closed.
+func (s *Server) serve() {
+	buf := make([]byte, 1400)
+
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		if n < 5 {
+			continue
+		}
+
+		request, body := buf[4], buf[5:n]
+
+		var response byte
+		var payload []byte
+
+		switch request {
+		case a2s.RequestInfo:
+			response, payload = a2s.ResponseInfo, s.info
+			body = bytes.TrimPrefix(body, []byte(a2s.InfoPayload))
+		case a2s.RequestPlayer:
+			response, payload = a2s.ResponsePlayer, s.players
+		case a2s.RequestRules:
+			response, payload = a2s.ResponseRules, s.rules
+		default:
+			continue
+		}
+
+		if !bytes.Equal(body, challenge) {
+			_, _ = s.conn.WriteTo(a2s.Encode(a2s.Challenge, challenge), addr)
+
+			continue
+		}
+
+		s.write(addr, a2s.Encode(response, payload))
+	}
+}
+
+// write sends the response in one packet or splits it.
+func (s *Server) write(addr net.Addr, response []byte) {
+	if len(response) <= PartSize {
+		_, _ = s.conn.WriteTo(response, addr)
+
+		return
+	}
+
+	total := (len(response) + PartSize - 1) / PartSize
+	for i := total - 1; i >= 0; i-- {
+		var packet bytes.Buffer
+		_ = binary.Write(&packet, binary.LittleEndian, a2s.HeaderSplit)
+		_ = binary.Write(&packet, binary.LittleEndian, uint32(1))
+		packet.Write([]byte{byte(total), byte(i)})
+		_ = binary.Write(&packet, binary.LittleEndian, uint16(PartSize))
+		packet.Write(response[i*PartSize : min(len(response), (i+1)*PartSize)])
+
+		_, _ = s.conn.WriteTo(packet.Bytes(), addr)
+	}
+}
+
+// writeStrings writes null-terminated strings.
+func writeStrings(b *bytes.Buffer, values ...string) {
+	for _, value := range values {
+		b.WriteString(value)
+		b.WriteByte(0)
+	}
+}
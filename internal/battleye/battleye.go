@@ -0,0 +1,290 @@
+// Package battleye contains the client of the BattlEye RCon protocol which
+// is used by DayZ and Arma servers.
+//
+// The protocol works over UDP. Every packet starts with `BE`, the CRC32
+// checksum of the rest of the packet and 0xFF. Commands are numbered with
+// one byte sequence number, long responses are split into several packets.
+// The server drops the client which sends nothing for 45 seconds, so the
+// client sends empty commands to keep the session alive. Messages which the
+// server broadcasts to the clients must be acknowledged.
+package battleye
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"net"
+	"sync"
+	"time"
+)
+
+// Packet types.
+const (
+	PacketLogin   byte = 0x00
+	PacketCommand byte = 0x01
+	PacketMessage byte = 0x02
+)
+
+// DefaultDialTimeout and DefaultDeadline are used when the options are not
+// set.
+const (
+	DefaultDialTimeout = 5 * time.Second
+	DefaultDeadline    = 5 * time.Second
+)
+
+// KeepAliveInterval is the pause between empty commands which keep the idle
+// session alive. The server drops the client after 45 seconds.
+const KeepAliveInterval = 30 * time.Second
+
+// maxPacketSize is the maximum size of UDP datagram.
+const maxPacketSize = 65507
+
+// Errors.
+var (
+	// ErrAuthFailed is returned when the server rejected the password.
+	ErrAuthFailed = errors.New("authentication failed")
+
+	// ErrInvalidPacket is returned when the received packet has no BattlEye
+	// header or a wrong checksum.
+	ErrInvalidPacket = errors.New("invalid packet")
+)
+
+// Settings contains options of Conn.
+type Settings struct {
+	dialTimeout time.Duration
+	deadline    time.Duration
+}
+
+// Option allows to inject settings to Settings.
+type Option func(s *Settings)
+
+// SetDialTimeout injects the timeout of dial and login to Settings.
+func SetDialTimeout(timeout time.Duration) Option {
+	return func(s *Settings) {
+		s.dialTimeout = timeout
+	}
+}
+
+// SetDeadline injects the timeout of the command response to Settings.
+func SetDeadline(timeout time.Duration) Option {
+	return func(s *Settings) {
+		s.deadline = timeout
+	}
+}
+
+// Conn is the session of BattlEye RCon.
+type Conn struct {
+	conn     net.Conn
+	settings Settings
+
+	// mu serializes commands and keep-alive packets.
+	mu   sync.Mutex
+	seq  byte
+	done chan struct{}
+	once sync.Once
+}
+
+// Dial connects to the server and logs in with the password. The session is
+// kept alive until Close.
+func Dial(address string, password string, options ...Option) (*Conn, error) {
+	settings := Settings{dialTimeout: DefaultDialTimeout, deadline: DefaultDeadline}
+	for _, option := range options {
+		option(&settings)
+	}
+
+	conn, err := net.DialTimeout("udp", address, settings.dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	c := Conn{conn: conn, settings: settings, done: make(chan struct{})}
+	if err := c.login(password); err != nil {
+		_ = conn.Close()
+
+		return nil, err
+	}
+
+	go c.keepAlive()
+
+	return &c, nil
+}
+
+// Execute sends the command and returns the response. Parts of the
+// multi-packet response are joined in order.
+func (c *Conn) Execute(command string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seq := c.seq
+	c.seq++
+
+	if err := c.write(PacketCommand, append([]byte{seq}, command...)); err != nil {
+		return "", err
+	}
+
+	var parts [][]byte
+
+	received := 0
+
+	for {
+		payload, err := c.readCommand(seq)
+		if err != nil {
+			return "", err
+		}
+
+		// Multi-packet response: 0x00, number of packets, packet index.
+		if len(payload) < 3 || payload[0] != 0x00 {
+			return string(payload), nil
+		}
+
+		total, index := int(payload[1]), int(payload[2])
+		if total == 0 || index >= total {
+			return "", fmt.Errorf("%w: part %d of %d", ErrInvalidPacket, index, total)
+		}
+
+		if parts == nil {
+			parts = make([][]byte, total)
+		}
+
+		if index < len(parts) && parts[index] == nil {
+			parts[index] = payload[3:]
+			received++
+		}
+
+		if received == len(parts) {
+			return string(bytes.Join(parts, nil)), nil
+		}
+	}
+}
+
+// Close stops the keep-alive and closes the connection.
+func (c *Conn) Close() error {
+	c.once.Do(func() { close(c.done) })
+
+	return c.conn.Close()
+}
+
+// login sends the password and waits for the result.
+func (c *Conn) login(password string) error {
+	if err := c.conn.SetDeadline(time.Now().Add(c.settings.dialTimeout)); err != nil {
+		return err
+	}
+
+	if err := c.write(PacketLogin, []byte(password)); err != nil {
+		return err
+	}
+
+	for {
+		kind, payload, err := c.read()
+		if err != nil {
+			return err
+		}
+
+		if kind != PacketLogin || len(payload) == 0 {
+			continue
+		}
+
+		if payload[0] != 0x01 {
+			return ErrAuthFailed
+		}
+
+		return nil
+	}
+}
+
+// keepAlive sends empty commands while the connection is open. Responses
+// to them are skipped by the next Execute.
+func (c *Conn) keepAlive() {
+	ticker := time.NewTicker(KeepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.mu.Lock()
+			_ = c.write(PacketCommand, []byte{c.seq})
+			c.seq++
+			c.mu.Unlock()
+		}
+	}
+}
+
+// readCommand reads packets until the response to the command with the
+// sequence number is received and returns its payload without the number.
+// Server messages are acknowledged, responses to other commands are
+// skipped.
+func (c *Conn) readCommand(seq byte) ([]byte, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(c.settings.deadline)); err != nil {
+		return nil, err
+	}
+
+	for {
+		kind, payload, err := c.read()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(payload) == 0 {
+			continue
+		}
+
+		switch kind {
+		case PacketMessage:
+			if err := c.write(PacketMessage, payload[:1]); err != nil {
+				return nil, err
+			}
+		case PacketCommand:
+			if payload[0] == seq {
+				return payload[1:], nil
+			}
+		}
+	}
+}
+
+// write sends the packet of the type with the payload.
+func (c *Conn) write(kind byte, payload []byte) error {
+	_, err := c.conn.Write(Encode(kind, payload))
+
+	return err
+}
+
+// read receives the packet and returns its type and payload.
+func (c *Conn) read() (byte, []byte, error) {
+	buf := make([]byte, maxPacketSize)
+
+	n, err := c.conn.Read(buf)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return Decode(buf[:n])
+}
+
+// Encode returns the packet of the type with the payload.
+func Encode(kind byte, payload []byte) []byte {
+	body := append([]byte{0xFF, kind}, payload...)
+
+	packet := make([]byte, 6, 6+len(body))
+	packet[0], packet[1] = 'B', 'E'
+	binary.LittleEndian.PutUint32(packet[2:6], crc32.ChecksumIEEE(body))
+
+	return append(packet, body...)
+}
+
+// Decode checks the header and the checksum of the packet and returns its
+// type and payload.
+func Decode(packet []byte) (byte, []byte, error) {
+	if len(packet) < 8 || packet[0] != 'B' || packet[1] != 'E' || packet[6] != 0xFF {
+		return 0, nil, ErrInvalidPacket
+	}
+
+	if binary.LittleEndian.Uint32(packet[2:6]) != crc32.ChecksumIEEE(packet[6:]) {
+		return 0, nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidPacket)
+	}
+
+	return packet[7], packet[8:], nil
+}
@@ -0,0 +1,72 @@
+package battleye_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/battleye"
+	"github.com/gorcon/rcon-cli/internal/battleye/battleyetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func handler(command string) string {
+	switch command {
+	case "players":
+		return strings.Repeat("0 Survivor\n", 5) + "(5 players in total)"
+	case "version":
+		return "DayZ 1.25"
+	default:
+		return "Unknown command"
+	}
+}
+
+func TestDecode(t *testing.T) {
+	kind, payload, err := battleye.Decode(battleye.Encode(battleye.PacketCommand, []byte{7, 'h', 'i'}))
+	assert.NoError(t, err)
+	assert.Equal(t, battleye.PacketCommand, kind)
+	assert.Equal(t, []byte{7, 'h', 'i'}, payload)
+
+	packet := battleye.Encode(battleye.PacketCommand, []byte{7, 'h', 'i'})
+	packet[len(packet)-1] = 'o'
+
+	_, _, err = battleye.Decode(packet)
+	assert.ErrorIs(t, err, battleye.ErrInvalidPacket)
+
+	_, _, err = battleye.Decode([]byte("RCON"))
+	assert.ErrorIs(t, err, battleye.ErrInvalidPacket)
+}
+
+func TestConn(t *testing.T) {
+	server := battleyetest.NewServer("password", handler)
+	defer server.Close()
+
+	t.Run("auth failed", func(t *testing.T) {
+		_, err := battleye.Dial(server.Addr(), "wrong", battleye.SetDialTimeout(time.Second))
+		assert.ErrorIs(t, err, battleye.ErrAuthFailed)
+	})
+
+	t.Run("execute", func(t *testing.T) {
+		conn, err := battleye.Dial(server.Addr(), "password", battleye.SetDeadline(time.Second))
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+
+		response, err := conn.Execute("version")
+		assert.NoError(t, err)
+		assert.Equal(t, "DayZ 1.25", response)
+
+		// Parts of the long response are received in reverse order.
+		response, err = conn.Execute("players")
+		assert.NoError(t, err)
+		assert.Equal(t, handler("players"), response)
+
+		server.Message("Player #0 Survivor connected")
+
+		response, err = conn.Execute("unknown")
+		assert.NoError(t, err)
+		assert.Equal(t, "Unknown command", response)
+		assert.Eventually(t, func() bool { return string(server.Acked()) == "\x00" }, time.Second, 10*time.Millisecond)
+	})
+}
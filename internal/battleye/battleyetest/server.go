@@ -0,0 +1,140 @@
+// Package battleyetest contains BattlEye RCon server for tests.
+package battleyetest
+
+import (
+	"net"
+	"sync"
+
+	"github.com/gorcon/rcon-cli/internal/battleye"
+)
+
+// PartSize is the maximum size of the response part. Longer responses are
+// sent in several packets in reverse order.
+const PartSize = 16
+
+// Handler returns the response to the command.
+type Handler func(command string) string
+
+// Server is BattlEye RCon server which listens on a random local UDP port.
+type Server struct {
+	conn     net.PacketConn
+	password string
+	handler  Handler
+
+	mu     sync.Mutex
+	client net.Addr
+	acked  []byte
+	seq    byte
+}
+
+// NewServer starts the server which accepts the password and answers the
+// commands with the handler.
+func NewServer(password string, handler Handler) *Server {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+
+	s := Server{conn: conn, password: password, handler: handler}
+
+	go s.serve()
+
+	return &s
+}
+
+// Addr returns the address of the server.
+func (s *Server) Addr() string {
+	return s.conn.LocalAddr().String()
+}
+
+// Message sends the server message to the logged in client. The message
+// goes before the response to the next command.
+func (s *Server) Message(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client != nil {
+		_, _ = s.conn.WriteTo(battleye.Encode(battleye.PacketMessage, append([]byte{s.seq}, text...)), s.client)
+		s.seq++
+	}
+}
+
+// Acked returns sequence numbers of the acknowledged server messages.
+func (s *Server) Acked() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]byte(nil), s.acked...)
+}
+
+// Close stops the server.
+func (s *Server) Close() {
+	_ = s.conn.Close()
+}
+
+// serve answers the packets until the server is closed.
+func (s *Server) serve() {
+	buf := make([]byte, 65507)
+
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		kind, payload, err := battleye.Decode(buf[:n])
+		if err != nil {
+			continue
+		}
+
+		switch kind {
+		case battleye.PacketLogin:
+			s.login(addr, string(payload))
+		case battleye.PacketMessage:
+			s.mu.Lock()
+			s.acked = append(s.acked, payload...)
+			s.mu.Unlock()
+		case battleye.PacketCommand:
+			if len(payload) > 0 {
+				s.command(addr, payload[0], string(payload[1:]))
+			}
+		}
+	}
+}
+
+// login checks the password and remembers the client.
+func (s *Server) login(addr net.Addr, password string) {
+	result := byte(0x00)
+
+	if password == s.password {
+		result = 0x01
+
+		s.mu.Lock()
+		s.client = addr
+		s.mu.Unlock()
+	}
+
+	_, _ = s.conn.WriteTo(battleye.Encode(battleye.PacketLogin, []byte{result}), addr)
+}
+
+// command sends the response to the command. Keep-alive packets get empty
+// response.
+func (s *Server) command(addr net.Addr, seq byte, command string) {
+	response := ""
+	if command != "" {
+		response = s.handler(command)
+	}
+
+	if len(response) <= PartSize {
+		_, _ = s.conn.WriteTo(battleye.Encode(battleye.PacketCommand, append([]byte{seq}, response...)), addr)
+
+		return
+	}
+
+	total := (len(response) + PartSize - 1) / PartSize
+	for i := total - 1; i >= 0; i-- {
+		part := response[i*PartSize : min(len(response), (i+1)*PartSize)]
+		payload := append([]byte{seq, 0x00, byte(total), byte(i)}, part...)
+		_, _ = s.conn.WriteTo(battleye.Encode(battleye.PacketCommand, payload), addr)
+	}
+}
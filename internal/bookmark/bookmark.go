@@ -0,0 +1,56 @@
+// Package bookmark stores flagged command responses as JSON lines.
+package bookmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/adrg/xdg"
+)
+
+// DefaultFileName is the name of bookmarks file in XDG data directory.
+const DefaultFileName = "bookmarks.jsonl"
+
+// Bookmark contains flagged command and its response.
+type Bookmark struct {
+	Time     time.Time `json:"time"`
+	Address  string    `json:"address"`
+	Label    string    `json:"label,omitempty"`
+	Command  string    `json:"command"`
+	Response string    `json:"response"`
+}
+
+// DefaultFile returns path to the bookmarks file in XDG data directory.
+func DefaultFile() (string, error) {
+	return xdg.DataFile(filepath.Join("gorcon", DefaultFileName))
+}
+
+// Append adds bookmark to the end of the file. Creates the file and its
+// directory if they do not exist.
+func Append(name string, b Bookmark) error {
+	const dirPerm, filePerm = 0o700, 0o600
+
+	if err := os.MkdirAll(filepath.Dir(name), dirPerm); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+
+	line, err := json.Marshal(b)
+	if err != nil {
+		return fmt.Errorf("marshal bookmark: %w", err)
+	}
+
+	file, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, filePerm)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package bookmark_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/bookmark"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppend(t *testing.T) {
+	dir := "temp"
+	name := filepath.Join(dir, "bookmarks.jsonl")
+
+	defer os.RemoveAll(dir)
+
+	b := bookmark.Bookmark{
+		Time:     time.Date(2023, 3, 11, 18, 20, 0, 0, time.UTC),
+		Address:  "127.0.0.1:16260",
+		Label:    "lag",
+		Command:  "status",
+		Response: "players: 5",
+	}
+
+	assert.NoError(t, bookmark.Append(name, b))
+	assert.NoError(t, bookmark.Append(name, b))
+
+	data, err := os.ReadFile(name)
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	assert.Len(t, lines, 2)
+	assert.Equal(t, `{"time":"2023-03-11T18:20:00Z","address":"127.0.0.1:16260","label":"lag",`+
+		`"command":"status","response":"players: 5"}`, lines[0])
+}
@@ -0,0 +1,178 @@
+// Package changes keeps the log of state-changing commands as JSON lines.
+package changes
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/adrg/xdg"
+)
+
+// MaxResponseLength is the maximum length of the response excerpt in
+// characters.
+const MaxResponseLength = 200
+
+// Entry contains state-changing command and the excerpt of its response.
+type Entry struct {
+	Time     time.Time `json:"time"`
+	User     string    `json:"user"`
+	Env      string    `json:"env"`
+	RunID    string    `json:"run_id,omitempty"`
+	Seq      int       `json:"seq,omitempty"`
+	Command  string    `json:"command"`
+	Response string    `json:"response"`
+}
+
+// DefaultFile returns path to the changes file of the environment in XDG
+// data directory.
+func DefaultFile(env string) (string, error) {
+	return xdg.DataFile(filepath.Join("gorcon", "changes", env+".jsonl"))
+}
+
+// Excerpt returns the response shortened to MaxResponseLength characters.
+func Excerpt(response string) string {
+	if runes := []rune(response); len(runes) > MaxResponseLength {
+		return string(runes[:MaxResponseLength]) + "..."
+	}
+
+	return response
+}
+
+// Append adds entry to the end of the file and removes entries older than
+// keep. Zero keep disables removing. Creates the file and its directory if
+// they do not exist.
+func Append(name string, e Entry, keep time.Duration) error {
+	const dirPerm, filePerm = 0o700, 0o600
+
+	if err := os.MkdirAll(filepath.Dir(name), dirPerm); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal entry: %w", err)
+	}
+
+	file, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, filePerm)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	if keep > 0 {
+		return Prune(name, e.Time.Add(-keep))
+	}
+
+	return nil
+}
+
+// Read returns entries of the file which are not older than since. Missing
+// file has no entries.
+func Read(name string, since time.Time) ([]Entry, error) {
+	data, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	var entries []Entry
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+
+	for i := 1; scanner.Scan(); i++ {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i, err)
+		}
+
+		if !e.Time.Before(since) {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries, nil
+}
+
+// Prune removes entries older than before from the file. The file is
+// rewritten only if there are old entries.
+func Prune(name string, before time.Time) error {
+	all, err := Read(name, time.Time{})
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	kept := 0
+
+	for _, e := range all {
+		if e.Time.Before(before) {
+			continue
+		}
+
+		line, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("marshal entry: %w", err)
+		}
+
+		buf.Write(append(line, '\n'))
+		kept++
+	}
+
+	if kept == len(all) {
+		return nil
+	}
+
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	if err := os.Rename(tmp, name); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
+
+// UndoHint returns the suggested reversal of the command. Hints map regular
+// expressions of commands to reversal templates with $1-style references to
+// the capture groups. Patterns are tried in alphabetical order, empty string
+// is returned if no pattern matches.
+func UndoHint(hints map[string]string, command string) string {
+	patterns := make([]string, 0, len(hints))
+	for pattern := range hints {
+		patterns = append(patterns, pattern)
+	}
+
+	sort.Strings(patterns)
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			continue
+		}
+
+		if match := re.FindStringSubmatchIndex(command); match != nil {
+			return string(re.ExpandString(nil, hints[pattern], command, match))
+		}
+	}
+
+	return ""
+}
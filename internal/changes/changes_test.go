@@ -0,0 +1,65 @@
+package changes_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/changes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppend(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "changes", "prod.jsonl")
+	now := time.Date(2023, 3, 11, 18, 20, 0, 0, time.UTC)
+
+	old := changes.Entry{Time: now.Add(-48 * time.Hour), User: "bob", Env: "prod", Command: "ban griefer"}
+	recent := changes.Entry{Time: now.Add(-time.Hour), User: "alice", Env: "prod", Command: "whitelist add bob"}
+	last := changes.Entry{Time: now, User: "alice", Env: "prod", Command: "op bob", Response: "Made bob a server operator"}
+
+	assert.NoError(t, changes.Append(name, old, 0))
+	assert.NoError(t, changes.Append(name, recent, 0))
+
+	t.Run("read since", func(t *testing.T) {
+		entries, err := changes.Read(name, now.Add(-24*time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, []changes.Entry{recent}, entries)
+	})
+
+	t.Run("prune on append", func(t *testing.T) {
+		assert.NoError(t, changes.Append(name, last, 24*time.Hour))
+
+		entries, err := changes.Read(name, time.Time{})
+		assert.NoError(t, err)
+		assert.Equal(t, []changes.Entry{recent, last}, entries)
+
+		info, err := os.Stat(name)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		entries, err := changes.Read(filepath.Join(t.TempDir(), "missing.jsonl"), time.Time{})
+		assert.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+}
+
+func TestExcerpt(t *testing.T) {
+	assert.Equal(t, "ok", changes.Excerpt("ok"))
+	assert.Equal(t, strings.Repeat("a", changes.MaxResponseLength)+"...", changes.Excerpt(strings.Repeat("a", 300)))
+}
+
+func TestUndoHint(t *testing.T) {
+	hints := map[string]string{
+		`^whitelist add (\S+)`: "whitelist remove $1",
+		`^op (\S+)`:            "deop $1",
+	}
+
+	assert.Equal(t, "whitelist remove bob", changes.UndoHint(hints, "whitelist add bob"))
+	assert.Equal(t, "deop alice", changes.UndoHint(hints, "op alice"))
+	assert.Equal(t, "", changes.UndoHint(hints, "say hello"))
+	assert.Equal(t, "", changes.UndoHint(nil, "op alice"))
+}
@@ -1,25 +1,60 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode/utf8"
 
 	"gopkg.in/yaml.v3"
 
 	"github.com/adrg/xdg"
+	"github.com/gorcon/rcon-cli/internal/logger"
+	"github.com/gorcon/rcon-cli/internal/redact"
 )
 
 // DefaultConfigName sets the default config file name.
 const DefaultConfigName = "rcon.yaml"
 
+// SchemaVersion is the current version of the config file schema.
+const SchemaVersion = 1
+
+// SchemaVersionKey is the reserved top level config key that contains the
+// schema version of the config file.
+const SchemaVersionKey = "schema_version"
+
+// BackupFileExt is appended to the config file name to keep the previous
+// version of the file when it is overwritten.
+const BackupFileExt = ".bak"
+
+// BackupCopiesKey is the reserved top level config key that contains the
+// number of previous versions of the config file kept on overwrite.
+const BackupCopiesKey = "backup_copies"
+
+// DefaultBackupCopies is the number of kept previous versions of the config
+// file unless the config sets another value.
+const DefaultBackupCopies = 1
+
 // DefaultConfigEnv is the name of the environment, which is taken
 // as default unless another value is passed.
 const DefaultConfigEnv = "default"
 
+// NoConfigFileSource is returned from Sources when no config file was found
+// and the empty default environment was synthesized.
+const NoConfigFileSource = "no config file found"
+
 var (
 	// ErrConfigValidation is when config validation completed with errors.
 	ErrConfigValidation = errors.New("config validation error")
@@ -27,10 +62,27 @@ var (
 	// ErrUnsupportedFileExt is returned when config file has an unsupported
 	// extension. Allowed extensions is `.json`, `.yml`, `.yaml`.
 	ErrUnsupportedFileExt = errors.New("unsupported file extension")
+
+	// ErrSessionNotFound is returned when config has no session of the
+	// requested environment.
+	ErrSessionNotFound = errors.New("session not found")
+
+	// ErrEnvNotFound is the previous name of ErrSessionNotFound.
+	//
+	// Deprecated: Use ErrSessionNotFound.
+	ErrEnvNotFound = ErrSessionNotFound
+
+	// ErrEnvExists is returned when environment is renamed to the name which
+	// is already taken by another environment.
+	ErrEnvExists = errors.New("environment already exists")
 )
 
 var AllowXDGConfig = true
 
+// renameFile moves the written temporary file over the config file. It is
+// replaced in tests to simulate a crash before the rename.
+var renameFile = os.Rename
+
 // Config allows to take a remote server address and password from
 // the configuration file. This enables not to specify these flags when
 // running the CLI.
@@ -45,9 +97,23 @@ var AllowXDGConfig = true
 // ```.
 type Config map[string]Session
 
+// File is the config loaded from the file. It keeps the paths of the files
+// the config was loaded from and the settings of the file which are not
+// environments.
+type File struct {
+	Config
+
+	sources []string
+	// backupCopies is the backup_copies value of the file. Nil means
+	// DefaultBackupCopies.
+	backupCopies *int
+	// doc is the node tree of the parsed file which is patched on write.
+	doc *document
+}
+
 // NewConfig finds and parses config file with remote server credentials.
-func NewConfig(name string) (*Config, error) {
-	cfg := new(Config)
+func NewConfig(name string) (*File, error) {
+	cfg := new(File)
 	if err := cfg.ParseFromFile(name); err != nil {
 		return nil, err
 	}
@@ -59,11 +125,34 @@ func NewConfig(name string) (*Config, error) {
 	return cfg, nil
 }
 
+// NewConfigFromFlags builds the config with the single environment from
+// the connection flags. Empty env is replaced with DefaultConfigEnv. The
+// file system is not touched, so the config has no sources.
+func NewConfigFromFlags(env, addr, pass, protocol string) *Config {
+	if env == "" {
+		env = DefaultConfigEnv
+	}
+
+	return &Config{env: {Address: addr, Password: pass, Type: protocol}}
+}
+
 // ParseFromFile reads a configuration file from disk and loads its contents into
-// the application's config structure. YAML and JSON files are supported.
-func (cfg *Config) ParseFromFile(name string) error {
+// the application's config structure. YAML and JSON files are supported,
+// files with EncryptedFileExt are decrypted first. Names with GitSourcePrefix
+// are read from a clone of the Git repository.
+func (cfg *File) ParseFromFile(name string) error {
+	if IsGitSource(name) {
+		return cfg.parseGit(name)
+	}
+
 	if name != "" {
-		return cfg.parse(name)
+		if err := cfg.parse(name); err != nil {
+			return err
+		}
+
+		cfg.setSources(name)
+
+		return nil
 	}
 
 	var err error
@@ -77,12 +166,25 @@ func (cfg *Config) ParseFromFile(name string) error {
 
 	return cfg.parseFirstExist(
 		configPath,
+		configPath+EncryptedFileExt,
 		DefaultConfigName,
+		DefaultConfigName+EncryptedFileExt,
 	)
 }
 
+// DefaultFile returns the path where a new config file is created. It is in
+// the XDG config directory if it is allowed, otherwise in the working
+// directory.
+func DefaultFile() (string, error) {
+	if !AllowXDGConfig {
+		return DefaultConfigName, nil
+	}
+
+	return xdg.ConfigFile(filepath.Join("gorcon", DefaultConfigName))
+}
+
 // Parse the first file that exists from the provided names.
-func (cfg *Config) parseFirstExist(names ...string) error {
+func (cfg *File) parseFirstExist(names ...string) error {
 	var err error
 	for _, name := range names {
 		if name == "" {
@@ -90,6 +192,8 @@ func (cfg *Config) parseFirstExist(names ...string) error {
 		}
 
 		if err = cfg.parse(name); err == nil {
+			cfg.setSources(name)
+
 			return nil
 		}
 		if !errors.Is(err, os.ErrNotExist) {
@@ -97,46 +201,1067 @@ func (cfg *Config) parseFirstExist(names ...string) error {
 		}
 	}
 
-	*cfg = Config{DefaultConfigEnv: {}}
+	*cfg = File{Config: Config{DefaultConfigEnv: {}}}
+	cfg.setSources(NoConfigFileSource)
+
+	return nil
+}
+
+// Sources returns paths to the files the config was loaded from. If no file
+// was found NoConfigFileSource is returned.
+func (cfg *File) Sources() []string {
+	return cfg.sources
+}
+
+func (cfg *File) setSources(names ...string) {
+	paths := make([]string, 0, len(names))
+
+	for _, name := range names {
+		if abs, err := filepath.Abs(name); err == nil && name != NoConfigFileSource {
+			name = abs
+		}
+
+		paths = append(paths, name)
+	}
+
+	cfg.sources = paths
+}
+
+// HasEnv reports whether the config contains the environment.
+func (cfg *Config) HasEnv(name string) bool {
+	if cfg == nil {
+		return false
+	}
+
+	_, ok := (*cfg)[name]
+
+	return ok && name != DefaultsKey
+}
+
+// GetEnv returns session of the environment with the fields inherited from
+// other environments and the defaults. ErrSessionNotFound is returned if
+// the config has no such environment.
+func (cfg *Config) GetEnv(name string) (Session, error) {
+	if !cfg.HasEnv(name) {
+		return Session{}, fmt.Errorf("%w: %s", ErrSessionNotFound, name)
+	}
+
+	ses, err := cfg.resolve(name)
+	if err != nil {
+		return Session{}, fmt.Errorf("%w: %s environment: %w", ErrConfigValidation, name, err)
+	}
+
+	return ses, nil
+}
+
+// Copy copies all fields of the environment to the new environment. Another
+// environment with the new name is replaced only if force is set.
+func (cfg *Config) Copy(from, to string, force bool) error {
+	if !cfg.HasEnv(from) {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, from)
+	}
+
+	ses := (*cfg)[from]
+
+	if isReserved(to) {
+		return fmt.Errorf("%w: invalid environment name %q", ErrConfigValidation, to)
+	}
+
+	if cfg.HasEnv(to) && !force {
+		return fmt.Errorf("%w: %s", ErrEnvExists, to)
+	}
+
+	ses.RedactPatterns = slices.Clone(ses.RedactPatterns)
+	ses.Macros = maps.Clone(ses.Macros)
+	ses.Metrics = slices.Clone(ses.Metrics)
+	(*cfg)[to] = ses
+
+	return nil
+}
+
+// Rename renames the environment keeping all its fields. Environments which
+// inherit it are updated. Another environment with the new name is replaced
+// only if force is set.
+func (cfg *Config) Rename(from, to string, force bool) error {
+	if !cfg.HasEnv(from) {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, from)
+	}
+
+	ses := (*cfg)[from]
+
+	if isReserved(to) {
+		return fmt.Errorf("%w: invalid environment name %q", ErrConfigValidation, to)
+	}
+
+	if from == to {
+		return nil
+	}
+
+	if cfg.HasEnv(to) && !force {
+		return fmt.Errorf("%w: %s", ErrEnvExists, to)
+	}
+
+	(*cfg)[to] = ses
+	delete(*cfg, from)
+
+	for _, name := range cfg.inheritors(from) {
+		child := (*cfg)[name]
+		child.Inherits = to
+		(*cfg)[name] = child
+	}
+
+	return nil
+}
+
+// Add adds the environment to the config. Another environment with the
+// same name is replaced only if force is set.
+func (cfg *Config) Add(name string, ses Session, force bool) error {
+	if isReserved(name) {
+		return fmt.Errorf("%w: invalid environment name %q", ErrConfigValidation, name)
+	}
+
+	if cfg.HasEnv(name) && !force {
+		return fmt.Errorf("%w: %s", ErrEnvExists, name)
+	}
+
+	(*cfg)[name] = ses
+
+	return nil
+}
+
+// Remove deletes the environment from the config. Environments which are
+// inherited by other environments are not removed.
+func (cfg *Config) Remove(name string) error {
+	if !cfg.HasEnv(name) {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, name)
+	}
+
+	if inheritors := cfg.inheritors(name); len(inheritors) > 0 {
+		return fmt.Errorf("%w: %s by %s", ErrEnvInherited, name, strings.Join(inheritors, ", "))
+	}
+
+	delete(*cfg, name)
 
 	return nil
 }
 
+// Import adds environments of the other config with the prefix prepended
+// to their names and returns the imported names. Inherited fields are
+// copied to the environments. For every environment
+// which already exists replace is asked whether to replace it, skipped
+// environments are not returned. If replace is nil conflicts fail the
+// import. The config is left unchanged on error.
+func (cfg *Config) Import(other Config, prefix string, replace func(name string) bool) ([]string, error) {
+	names := other.Names()
+
+	for _, name := range names {
+		to := prefix + name
+		if isReserved(to) {
+			return nil, fmt.Errorf("%w: invalid environment name %q", ErrConfigValidation, to)
+		}
+
+		if replace == nil && cfg.HasEnv(to) {
+			return nil, fmt.Errorf("%w: %s", ErrEnvExists, to)
+		}
+	}
+
+	imported := make([]string, 0, len(names))
+
+	for _, name := range names {
+		to := prefix + name
+		if cfg.HasEnv(to) && !replace(to) {
+			continue
+		}
+
+		(*cfg)[to] = other.flatten(name)
+		imported = append(imported, to)
+	}
+
+	return imported, nil
+}
+
+// Export returns environments which names start with the prefix. The
+// prefix is removed from the names, so the result has the names of the
+// imported config. Inherited fields are copied to the environments.
+func (cfg Config) Export(prefix string) Config {
+	exported := make(Config)
+
+	for _, name := range cfg.Names() {
+		if to, ok := strings.CutPrefix(name, prefix); ok && to != "" {
+			exported[to] = cfg.flatten(name)
+		}
+	}
+
+	return exported
+}
+
 // Validate validates the config fields.
 func (cfg *Config) Validate() error {
 	if cfg == nil {
 		return fmt.Errorf("%w: config is not set", ErrConfigValidation)
 	}
 
-	for key, ses := range *cfg {
+	if defaults, ok := (*cfg)[DefaultsKey]; ok && defaults.Inherits != "" {
+		return fmt.Errorf("%w: %s must not inherit environments", ErrConfigValidation, DefaultsKey)
+	}
+
+	for _, key := range cfg.Names() {
+		ses, err := cfg.resolve(key)
+		if err != nil {
+			return fmt.Errorf("%w: %s environment: %w", ErrConfigValidation, key, err)
+		}
+
 		switch ses.Type {
-		case "", ProtocolRCON, ProtocolTELNET, ProtocolWebRCON:
+		case "", ProtocolRCON, ProtocolTELNET, ProtocolWebRCON, ProtocolBattlEye, ProtocolPterodactyl:
 		default:
 			return fmt.Errorf("%w: unsupported type in %s environment", ErrConfigValidation, key)
 		}
+
+		if sources := ses.passwordSources(); len(sources) > 1 {
+			last := len(sources) - 1
+
+			return fmt.Errorf("%w: %s and %s are mutually exclusive in %s environment",
+				ErrConfigValidation, strings.Join(sources[:last], ", "), sources[last], key)
+		}
+
+		if ses.SSH != nil {
+			if ses.ProxyCommand != "" {
+				return fmt.Errorf("%w: ssh and proxy_command are mutually exclusive in %s environment",
+					ErrConfigValidation, key)
+			}
+
+			if err := ses.SSH.validate(); err != nil {
+				return fmt.Errorf("%w: %s environment: %w", ErrConfigValidation, key, err)
+			}
+		}
+
+		if ses.PasswordKeyring != "" {
+			if _, _, err := parseKeyringRef(ses.PasswordKeyring); err != nil {
+				return fmt.Errorf("%w: %s environment: %w", ErrConfigValidation, key, err)
+			}
+		}
+
+		if _, err := redact.New(ses.RedactPatterns); err != nil {
+			return fmt.Errorf("%w: %s environment: %w", ErrConfigValidation, key, err)
+		}
+
+		if _, err := regexp.Compile(ses.ResponseGrep); err != nil {
+			return fmt.Errorf("%w: %s environment: response_grep: %w", ErrConfigValidation, key, err)
+		}
+
+		if ses.BannerLines < 0 {
+			return fmt.Errorf("%w: banner_lines must not be negative in %s environment", ErrConfigValidation, key)
+		}
+
+		for _, size := range []Size{ses.ReadBufferSize, ses.WriteBufferSize} {
+			if size != 0 && size < MinBufferSize {
+				return fmt.Errorf("%w: buffer sizes must be at least %d bytes in %s environment",
+					ErrConfigValidation, MinBufferSize, key)
+			}
+		}
+
+		if ses.RequestHMACFormat != "" && !strings.Contains(ses.RequestHMACFormat, "{hmac}") {
+			return fmt.Errorf("%w: request_hmac_format must contain {hmac} in %s environment", ErrConfigValidation, key)
+		}
+
+		if _, err := regexp.Compile(ses.ReadyExpect); err != nil {
+			return fmt.Errorf("%w: %s environment: ready_expect: %w", ErrConfigValidation, key, err)
+		}
+
+		if err := validateParsers(ses); err != nil {
+			return fmt.Errorf("%w: %s environment: %w", ErrConfigValidation, key, err)
+		}
+
+		if err := validateChanges(ses); err != nil {
+			return fmt.Errorf("%w: %s environment: %w", ErrConfigValidation, key, err)
+		}
+
+		if err := validateRetry(ses); err != nil {
+			return fmt.Errorf("%w: %s environment: %w", ErrConfigValidation, key, err)
+		}
+
+		if err := validateTransport(ses); err != nil {
+			return fmt.Errorf("%w: %s environment: %w", ErrConfigValidation, key, err)
+		}
+
+		if err := validatePterodactyl(ses); err != nil {
+			return fmt.Errorf("%w: %s environment: %w", ErrConfigValidation, key, err)
+		}
+
+		if err := validateMacros(ses); err != nil {
+			return fmt.Errorf("%w: %s environment: %w", ErrConfigValidation, key, err)
+		}
+
+		if err := validateTELNET(ses); err != nil {
+			return fmt.Errorf("%w: %s environment: %w", ErrConfigValidation, key, err)
+		}
+
+		if err := validateMetrics(ses); err != nil {
+			return fmt.Errorf("%w: %s environment: %w", ErrConfigValidation, key, err)
+		}
+
+		switch ses.Game {
+		case "", GameSevenDaysToDie:
+		default:
+			return fmt.Errorf("%w: unsupported game in %s environment", ErrConfigValidation, key)
+		}
+
+		if err := validateCluster(ses); err != nil {
+			return fmt.Errorf("%w: %s environment: %w", ErrConfigValidation, key, err)
+		}
+
+		switch ses.PasteMode {
+		case "", PasteModeAsk, PasteModeSeparate, PasteModeJoin:
+		default:
+			return fmt.Errorf("%w: unsupported paste_mode in %s environment", ErrConfigValidation, key)
+		}
+
+		if err := validateLog(ses); err != nil {
+			return fmt.Errorf("%w: %s environment: %w", ErrConfigValidation, key, err)
+		}
+
+		switch ses.Color {
+		case "", ColorAuto, ColorAlways, ColorNever, ColorStrip:
+		default:
+			return fmt.Errorf("%w: unsupported color in %s environment", ErrConfigValidation, key)
+		}
+
+		if utf8.RuneCountInString(ses.Description) > MaxNoteLength || utf8.RuneCountInString(ses.Owner) > MaxNoteLength {
+			return fmt.Errorf("%w: description and owner must be at most %d characters in %s environment",
+				ErrConfigValidation, MaxNoteLength, key)
+		}
+	}
+
+	return nil
+}
+
+// BackupCopies returns the number of previous versions of the config file
+// which are kept on overwrite.
+func (cfg *File) BackupCopies() int {
+	if cfg.backupCopies != nil {
+		return *cfg.backupCopies
+	}
+
+	return DefaultBackupCopies
+}
+
+// validateChanges checks regular expressions of record_changes and
+// undo_hint fields.
+func validateChanges(ses Session) error {
+	for _, pattern := range ses.RecordChanges {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("record_changes: %w", err)
+		}
+	}
+
+	for pattern := range ses.UndoHint {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("undo_hint: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// validateCluster checks addresses and the mode of the cluster.
+func validateCluster(ses Session) error {
+	switch ses.ClusterMode {
+	case "", ClusterModeFailover, ClusterModePrimary, ClusterModeBroadcast:
+	default:
+		return fmt.Errorf("unsupported cluster_mode %q", ses.ClusterMode)
+	}
+
+	for _, address := range ses.Addresses {
+		if address == "" {
+			return errors.New("addresses must not contain empty address")
+		}
+	}
+
+	return nil
+}
+
+// validateLog checks the format and the rotation of the log file.
+func validateLog(ses Session) error {
+	switch ses.LogFormat {
+	case "", logger.FormatText, logger.FormatJSON:
+	default:
+		return fmt.Errorf("unsupported log_format %q", ses.LogFormat)
+	}
+
+	switch ses.LogRotate {
+	case "", logger.RotateDaily, logger.RotateHourly:
+	default:
+		return fmt.Errorf("unsupported log_rotate %q", ses.LogRotate)
+	}
+
+	if ses.LogMaxBackups < 0 {
+		return errors.New("log_max_backups must not be negative")
+	}
+
+	return nil
+}
+
+// validatePterodactyl checks the fields of pterodactyl type. The client
+// dials the console by itself, so the features of the local forwarder are
+// not supported.
+func validatePterodactyl(ses Session) error {
+	if ses.Type != ProtocolPterodactyl {
+		return nil
+	}
+
+	if ses.ServerID == "" {
+		return errors.New("server_id is required by pterodactyl type")
+	}
+
+	if ses.Address != "" {
+		if u, err := url.Parse(ses.Address); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("address of pterodactyl type must be the panel URL: %q", ses.Address)
+		}
+	}
+
+	if ses.Proxy != "" || ses.ProxyCommand != "" || ses.SSH != nil || ses.MinReadRate > 0 || ses.ReadBufferSize > 0 ||
+		ses.WriteBufferSize > 0 || ses.KeepaliveInterval > 0 {
+		return errors.New("proxy, proxy_command, ssh, min_read_rate, buffer sizes and keepalive_interval are " +
+			"not supported by pterodactyl type")
+	}
+
+	return nil
+}
+
+// validateRetry checks patterns and limits of the retry_on and reconnect
+// fields.
+func validateRetry(ses Session) error {
+	for _, pattern := range ses.RetryOn {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("retry_on: %w", err)
+		}
+	}
+
+	if ses.MaxRetries < 0 || ses.RetryBackoff < 0 || ses.ReconnectRetries < 0 || ses.KeepaliveInterval < 0 {
+		return errors.New("max_retries, retry_backoff, reconnect_retries and keepalive_interval must not be negative")
+	}
+
+	return nil
+}
+
+// validateParsers checks that response parsers have unique names and their
+// fields are named groups of the patterns. The response template must be
+// valid Go template.
+func validateParsers(ses Session) error {
+	if err := ValidateParsersPreset(ses.ResponseParsersPreset); err != nil {
+		return err
+	}
+
+	names := make(map[string]bool, len(ses.ResponseParsers))
+
+	for _, p := range ses.ResponseParsers {
+		if p.Name == "" || names[p.Name] {
+			return fmt.Errorf("response_parsers: name %q must be unique and not empty", p.Name)
+		}
+
+		names[p.Name] = true
+
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return fmt.Errorf("response_parsers: %s: %w", p.Name, err)
+		}
+
+		for _, field := range p.Fields {
+			if re.SubexpIndex(field) < 0 {
+				return fmt.Errorf("response_parsers: %s: pattern has no %q group", p.Name, field)
+			}
+		}
+	}
+
+	if _, err := template.New("response").Parse(ses.ResponseTemplate); err != nil {
+		return fmt.Errorf("response_template: %w", err)
 	}
 
 	return nil
 }
 
-func (cfg *Config) parse(name string) error {
-	file, err := os.ReadFile(name)
+// WriteToFile serializes the config to the file in the format chosen by the
+// file extension. Every command that modifies the config file must use it,
+// so the file is replaced atomically and previous versions are kept as
+// backups. Encrypted files are encrypted again with Passphrase.
+func (cfg *File) WriteToFile(name string) error {
+	data, err := cfg.Marshal(name)
 	if err != nil {
-		return fmt.Errorf("read file %s: %w", name, err)
+		return err
+	}
+
+	if data, err = seal(name, data); err != nil {
+		return fmt.Errorf("write file %s: %w", name, err)
+	}
+
+	return writeFile(name, data, cfg.BackupCopies())
+}
+
+// Marshal serializes the config in the format chosen by the file extension.
+// The file the config was parsed from is patched: only changed fields and
+// environments are written, comments and the order of keys are kept. Other
+// configs are serialized in the canonical form: the default environment goes
+// first, other environments follow in alphabetical order. Encrypted files
+// are serialized as plain text.
+func (cfg *File) Marshal(name string) ([]byte, error) {
+	var data []byte
+	var err error
+
+	ext := formatExt(name)
+
+	if cfg.doc != nil && (ext == ".json") == cfg.doc.json && ext != "" {
+		if err = cfg.doc.patch(cfg); err == nil {
+			data, err = cfg.doc.bytes()
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("serialize file %s: %w", name, err)
+		}
+
+		return data, nil
 	}
 
-	switch ext := path.Ext(name); ext {
+	switch ext {
 	case ".yml", ".yaml":
-		err = yaml.Unmarshal(file, cfg)
+		var node interface{}
+		if node, err = cfg.MarshalYAML(); err == nil {
+			cfg.addBackupCopies(node.(*yaml.Node))
+			data, err = yaml.Marshal(node)
+		}
 	case ".json":
-		err = json.Unmarshal(file, cfg)
+		var buf bytes.Buffer
+		if data, err = cfg.marshalJSON(); err == nil {
+			if err = json.Indent(&buf, data, "", "  "); err == nil {
+				data = buf.Bytes()
+			}
+		}
 	default:
 		err = fmt.Errorf("%w %s", ErrUnsupportedFileExt, ext)
 	}
 
 	if err != nil {
+		return nil, fmt.Errorf("serialize file %s: %w", name, err)
+	}
+
+	return data, nil
+}
+
+// addBackupCopies adds backup_copies key after the schema version if the
+// config has non default value.
+func (cfg *File) addBackupCopies(node *yaml.Node) {
+	copies := cfg.BackupCopies()
+	if copies == DefaultBackupCopies {
+		return
+	}
+
+	node.Content = append(node.Content[:2], append([]*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: BackupCopiesKey},
+		{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(copies)},
+	}, node.Content[2:]...)...)
+}
+
+// setBackupCopies parses and stores backup_copies value of the file.
+func (cfg *File) setBackupCopies(value string) error {
+	copies, err := strconv.Atoi(value)
+	if err != nil || copies < 0 {
+		return fmt.Errorf("%w: %s must be a non-negative integer", ErrConfigValidation, BackupCopiesKey)
+	}
+
+	cfg.backupCopies = &copies
+
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler. Reserved top level keys are
+// skipped.
+func (cfg *Config) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: config must be a mapping of environments", value.Line)
+	}
+
+	*cfg = make(Config, len(value.Content)/2)
+
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		key := value.Content[i].Value
+		if key == SchemaVersionKey || key == BackupCopiesKey {
+			continue
+		}
+
+		if node := value.Content[i+1]; node.Kind == yaml.MappingNode {
+			seen := make(map[string]string, len(node.Content)/2)
+
+			for j := 0; j+1 < len(node.Content); j += 2 {
+				field := node.Content[j]
+				if err := checkUnitField(key, field.Value, node.Content[j+1].Value); err != nil {
+					return err
+				}
+
+				if err := checkKeyAlias(key, seen, field.Value); err != nil {
+					return err
+				}
+
+				field.Value = resolveKeyAlias(field.Value)
+			}
+		}
+
+		var ses Session
+		if err := value.Content[i+1].Decode(&ses); err != nil {
+			return fmt.Errorf("%s environment: %w", key, err)
+		}
+
+		(*cfg)[key] = ses
+	}
+
+	return nil
+}
+
+// keyAliases maps alternative keys of the session fields to the keys which
+// the config is written with.
+var keyAliases = map[string]string{
+	"dial_timeout": "connect_timeout",
+	"exec_timeout": "command_timeout",
+	"retries":      "reconnect_retries",
+}
+
+// resolveKeyAlias returns the key which the alias stands for. Other keys are
+// returned as is.
+func resolveKeyAlias(key string) string {
+	if name, ok := keyAliases[key]; ok {
+		return name
+	}
+
+	return key
+}
+
+// checkKeyAlias returns error if the environment sets the same field with
+// the key and its alias. Seen maps resolved keys to the keys as written.
+func checkKeyAlias(env string, seen map[string]string, key string) error {
+	name := resolveKeyAlias(key)
+	if other, ok := seen[name]; ok && other != key {
+		alias := key
+		if alias == name {
+			alias = other
+		}
+
+		return fmt.Errorf("%w: %s environment: %s is alias of %s, set only one of them",
+			ErrConfigValidation, env, alias, name)
+	}
+
+	seen[name] = key
+
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Reserved top level keys are
+// skipped.
+func (cfg *Config) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*cfg = make(Config, len(raw))
+
+	for key, value := range raw {
+		if key == SchemaVersionKey || key == BackupCopiesKey {
+			continue
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(value, &fields); err == nil {
+			seen := make(map[string]string, len(fields))
+			aliases := make(map[string]json.RawMessage)
+
+			for field, raw := range fields {
+				if err := checkJSONUnitField(key, field, raw); err != nil {
+					return err
+				}
+
+				if err := checkKeyAlias(key, seen, field); err != nil {
+					return err
+				}
+
+				if name := resolveKeyAlias(field); name != field {
+					aliases[field] = raw
+				}
+			}
+
+			for field, raw := range aliases {
+				fields[resolveKeyAlias(field)] = raw
+				delete(fields, field)
+			}
+
+			if len(aliases) > 0 {
+				if value, err = json.Marshal(fields); err != nil {
+					return fmt.Errorf("%s environment: %w", key, err)
+				}
+			}
+		}
+
+		var ses Session
+		if err := json.Unmarshal(value, &ses); err != nil {
+			return fmt.Errorf("%s environment: %w", key, err)
+		}
+
+		(*cfg)[key] = ses
+	}
+
+	return nil
+}
+
+// MarshalYAML implements yaml.Marshaler. The schema version is written
+// first, the defaults and environments follow in the order of Names.
+func (cfg Config) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	node.Content = append(node.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: SchemaVersionKey},
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(SchemaVersion)},
+	)
+
+	keys := cfg.Names()
+	if _, ok := cfg[DefaultsKey]; ok {
+		keys = append([]string{DefaultsKey}, keys...)
+	}
+
+	for _, key := range keys {
+		ses := cfg[key]
+
+		value := &yaml.Node{}
+		if err := value.Encode(&ses); err != nil {
+			return nil, fmt.Errorf("%s environment: %w", key, err)
+		}
+
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	}
+
+	return node, nil
+}
+
+// MarshalJSON implements json.Marshaler. The schema version is written
+// first, the defaults and environments follow in the order of Names.
+// Session keys are sorted.
+func (cfg Config) MarshalJSON() ([]byte, error) {
+	return cfg.marshalJSONWith("")
+}
+
+// marshalJSON serializes the config like MarshalJSON and keeps non default
+// backup_copies value.
+func (cfg *File) marshalJSON() ([]byte, error) {
+	var reserved string
+	if copies := cfg.BackupCopies(); copies != DefaultBackupCopies {
+		reserved = `,"` + BackupCopiesKey + `":` + strconv.Itoa(copies)
+	}
+
+	return cfg.marshalJSONWith(reserved)
+}
+
+// marshalJSONWith serializes the config with the reserved keys written
+// after the schema version.
+func (cfg Config) marshalJSONWith(reserved string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteString(`{"` + SchemaVersionKey + `":` + strconv.Itoa(SchemaVersion) + reserved)
+
+	keys := cfg.Names()
+	if _, ok := cfg[DefaultsKey]; ok {
+		keys = append([]string{DefaultsKey}, keys...)
+	}
+
+	for _, key := range keys {
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := marshalSorted(cfg[key])
+		if err != nil {
+			return nil, fmt.Errorf("%s environment: %w", key, err)
+		}
+
+		buf.WriteByte(',')
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// marshalSorted serializes the session to JSON object with keys in
+// alphabetical order.
+func marshalSorted(ses Session) ([]byte, error) {
+	data, err := json.Marshal(ses)
+	if err != nil {
+		return nil, err
+	}
+
+	// Maps are encoded with sorted keys.
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(fields)
+}
+
+// Names returns environment names in alphabetical order. The default
+// environment goes first.
+func (cfg Config) Names() []string {
+	names := make([]string, 0, len(cfg))
+	for key := range cfg {
+		if key != DefaultsKey {
+			names = append(names, key)
+		}
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		if names[i] == DefaultConfigEnv || names[j] == DefaultConfigEnv {
+			return names[i] == DefaultConfigEnv && names[j] != DefaultConfigEnv
+		}
+
+		return names[i] < names[j]
+	})
+
+	return names
+}
+
+// ConfigStats contains aggregate statistics about the config environments.
+type ConfigStats struct {
+	TotalEnvs       int            `json:"total_envs"`
+	EnvsByType      map[string]int `json:"envs_by_type"`
+	EnvsWithTags    int            `json:"envs_with_tags"`
+	EnvsWithTimeout int            `json:"envs_with_timeout"`
+	EnvsWithTLS     int            `json:"envs_with_tls"`
+}
+
+// Stats returns aggregate statistics about the config environments.
+// Environments without type are counted as DefaultProtocol.
+func (cfg Config) Stats() ConfigStats {
+	names := cfg.Names()
+	stats := ConfigStats{TotalEnvs: len(names), EnvsByType: make(map[string]int)}
+
+	for _, name := range names {
+		ses := cfg.flatten(name)
+
+		protocol := ses.Type
+		if protocol == "" {
+			protocol = DefaultProtocol
+		}
+
+		stats.EnvsByType[protocol]++
+
+		if len(ses.Tags) > 0 {
+			stats.EnvsWithTags++
+		}
+
+		if ses.Timeout > 0 {
+			stats.EnvsWithTimeout++
+		}
+
+		if ses.TLS != nil && ses.TLS.Enabled {
+			stats.EnvsWithTLS++
+		}
+	}
+
+	return stats
+}
+
+// AllEnvs selects every environment of the config in Match.
+const AllEnvs = "all"
+
+// IsEnvPattern reports whether the environment name selects several
+// environments: AllEnvs, comma separated list or glob pattern.
+func IsEnvPattern(name string) bool {
+	return name == AllEnvs || strings.ContainsAny(name, ",*?[")
+}
+
+// Match returns names of the environments selected by AllEnvs, comma
+// separated list of names or glob patterns in Names order.
+// ErrSessionNotFound is returned if nothing matches.
+func (cfg Config) Match(selector string) ([]string, error) {
+	patterns := strings.Split(selector, ",")
+	matched := make([]string, 0, len(cfg))
+
+	for _, name := range cfg.Names() {
+		for _, pattern := range patterns {
+			ok, err := path.Match(strings.TrimSpace(pattern), name)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", err, pattern)
+			}
+
+			if ok || selector == AllEnvs {
+				matched = append(matched, name)
+
+				break
+			}
+		}
+	}
+
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, selector)
+	}
+
+	return matched, nil
+}
+
+// filePerm is the permission of the written config and backup files. The
+// config contains passwords, so it is readable only by the owner.
+const filePerm = 0o600
+
+// writeFile atomically replaces the file with data. Data is written to
+// a temporary file in the same directory, which is synced and renamed over
+// the original file, so the original file is never left truncated. If the
+// file exists up to copies of its previous versions are kept.
+func writeFile(name string, data []byte, copies int) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("write file %s: %w", name, err)
+	}
+
+	// Removing fails after successful rename, which is expected.
+	defer os.Remove(tmp.Name())
+
+	if err := writeSync(tmp, data); err != nil {
+		return fmt.Errorf("write file %s: %w", name, err)
+	}
+
+	if err := backup(name, copies); err != nil {
+		return fmt.Errorf("backup file %s: %w", name, err)
+	}
+
+	if err := renameFile(tmp.Name(), name); err != nil {
+		return fmt.Errorf("write file %s: %w", name, err)
+	}
+
+	// Persist the rename. Directories cannot be synced on some systems, so
+	// the error is ignored.
+	if dir, err := os.Open(filepath.Dir(name)); err == nil {
+		_ = dir.Sync()
+		_ = dir.Close()
+	}
+
+	return nil
+}
+
+// writeSync writes data to the file, flushes it to the disk and closes
+// the file.
+func writeSync(file *os.File, data []byte) error {
+	if err := file.Chmod(filePerm); err != nil {
+		_ = file.Close()
+
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+
+		return err
+	}
+
+	return file.Close()
+}
+
+// backup keeps up to copies previous versions of the file. The newest one
+// has BackupFileExt extension, older ones have numeric suffixes:
+// rcon.yaml.bak, rcon.yaml.bak.1, rcon.yaml.bak.2 and so on.
+func backup(name string, copies int) error {
+	if copies <= 0 {
+		return nil
+	}
+
+	old, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for i := copies - 1; i > 0; i-- {
+		if err := os.Rename(backupName(name, i-1), backupName(name, i)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return os.WriteFile(backupName(name, 0), old, filePerm)
+}
+
+// backupName returns name of the n-th previous version of the file.
+func backupName(name string, n int) string {
+	if n == 0 {
+		return name + BackupFileExt
+	}
+
+	return name + BackupFileExt + "." + strconv.Itoa(n)
+}
+
+func (cfg *File) parse(name string) error {
+	file, err := ReadFile(name)
+	if err != nil {
+		return fmt.Errorf("read file %s: %w", name, err)
+	}
+
+	if err := cfg.unmarshal(name, file); err != nil {
 		return fmt.Errorf("parse file %s: %w", name, err)
 	}
 
 	return nil
 }
+
+// unmarshal decodes data of the file in the format chosen by the file
+// extension: the environments to Config and backup_copies to the file.
+func (cfg *File) unmarshal(name string, data []byte) error {
+	var reserved struct {
+		YAML yaml.Node       `yaml:"backup_copies" json:"-"`
+		JSON json.RawMessage `yaml:"-" json:"backup_copies"`
+	}
+
+	var err error
+
+	ext := formatExt(name)
+
+	switch ext {
+	case ".yml", ".yaml":
+		if err = yaml.Unmarshal(data, &cfg.Config); err == nil {
+			err = yaml.Unmarshal(data, &reserved)
+		}
+	case ".json":
+		if err = json.Unmarshal(data, &cfg.Config); err == nil {
+			err = json.Unmarshal(data, &reserved)
+		}
+	default:
+		err = fmt.Errorf("%w %s", ErrUnsupportedFileExt, ext)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if cfg.doc, err = parseDocument(ext, data); err != nil {
+		return err
+	}
+
+	if cfg.doc != nil {
+		if err := cfg.doc.snapshot(cfg.Config); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case reserved.YAML.Kind != 0:
+		return cfg.setBackupCopies(reserved.YAML.Value)
+	case reserved.JSON != nil:
+		return cfg.setBackupCopies(string(reserved.JSON))
+	}
+
+	return nil
+}
@@ -0,0 +1,578 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// document is the node tree of the parsed config file. Commands which
+// modify the config patch the tree, so comments, key order and formatting
+// of untouched environments and fields survive the write.
+type document struct {
+	root *yaml.Node
+	json bool
+	// indent is the indentation of the file. Zero means compact JSON.
+	indent string
+	// newline reports whether the JSON file ends with the line break.
+	newline bool
+	// base contains the encoded fields of the environments as they were
+	// parsed. Only fields which differ from it are written.
+	base map[string]map[string]string
+}
+
+// parseDocument parses the node tree of the config file in the format chosen
+// by the file extension. Files which are not a mapping have no document.
+func parseDocument(ext string, data []byte) (*document, error) {
+	doc := &document{json: ext == ".json"}
+
+	if doc.json {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+
+		node, err := decodeJSONNode(decoder)
+		if err != nil {
+			return nil, err
+		}
+
+		doc.root = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{node}}
+		doc.newline = bytes.HasSuffix(data, []byte("\n"))
+	} else {
+		doc.root = &yaml.Node{}
+		if err := yaml.Unmarshal(data, doc.root); err != nil {
+			return nil, err
+		}
+	}
+
+	// Empty file has nothing to preserve.
+	if doc.mapping() == nil {
+		return nil, nil
+	}
+
+	doc.indent = detectIndent(data, doc.json)
+
+	return doc, nil
+}
+
+// detectIndent returns the indentation of the first indented line. YAML
+// files default to four spaces like yaml.Marshal.
+func detectIndent(data []byte, jsonFile bool) string {
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" || trimmed == line || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		return line[:len(line)-len(trimmed)]
+	}
+
+	if jsonFile {
+		return ""
+	}
+
+	return "    "
+}
+
+// mappingValue returns the value of the key of the mapping node or nil.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// mapping returns the top level mapping of the document.
+func (doc *document) mapping() *yaml.Node {
+	if doc.root.Kind != yaml.DocumentNode || len(doc.root.Content) == 0 ||
+		doc.root.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+
+	return doc.root.Content[0]
+}
+
+// bytes serializes the document in its format.
+func (doc *document) bytes() ([]byte, error) {
+	if doc.json {
+		var buf bytes.Buffer
+		if err := encodeJSONNode(&buf, doc.root); err != nil {
+			return nil, err
+		}
+
+		data := buf.Bytes()
+
+		if doc.indent != "" {
+			var indented bytes.Buffer
+			if err := json.Indent(&indented, data, "", doc.indent); err != nil {
+				return nil, err
+			}
+
+			data = indented.Bytes()
+		}
+
+		if doc.newline {
+			data = append(data, '\n')
+		}
+
+		return data, nil
+	}
+
+	indent := len(strings.ReplaceAll(doc.indent, "\t", " "))
+	if indent < 2 {
+		indent = 2
+	}
+
+	var buf bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(indent)
+
+	if err := encoder.Encode(doc.root); err != nil {
+		return nil, err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encodeSession encodes the session to the mapping node in the format of
+// the document and returns its fields by key in the order of the mapping.
+func (doc *document) encodeSession(ses Session) (*yaml.Node, map[string]*yaml.Node, error) {
+	node := &yaml.Node{}
+
+	if doc.json {
+		data, err := marshalSorted(ses)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+
+		if node, err = decodeJSONNode(decoder); err != nil {
+			return nil, nil, err
+		}
+	} else if err := node.Encode(&ses); err != nil {
+		return nil, nil, err
+	}
+
+	fields := make(map[string]*yaml.Node, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		fields[node.Content[i].Value] = node.Content[i+1]
+	}
+
+	return node, fields, nil
+}
+
+// encodeFields returns the encoded fields of the session for comparison.
+func (doc *document) encodeFields(ses Session) (map[string]string, error) {
+	_, nodes, err := doc.encodeSession(ses)
+	if err != nil {
+		return nil, err
+	}
+
+	return serializeFields(nodes)
+}
+
+// serializeFields serializes the field nodes for comparison.
+func serializeFields(nodes map[string]*yaml.Node) (map[string]string, error) {
+	fields := make(map[string]string, len(nodes))
+
+	for key, node := range nodes {
+		data, err := yaml.Marshal(node)
+		if err != nil {
+			return nil, err
+		}
+
+		fields[key] = string(data)
+	}
+
+	return fields, nil
+}
+
+// snapshot remembers the encoded fields of the environments, so the
+// following patch writes only the changes.
+func (doc *document) snapshot(cfg Config) error {
+	doc.base = make(map[string]map[string]string, len(cfg))
+
+	for env, ses := range cfg {
+		fields, err := doc.encodeFields(ses)
+		if err != nil {
+			return fmt.Errorf("%s environment: %w", env, err)
+		}
+
+		doc.base[env] = fields
+	}
+
+	return nil
+}
+
+// patch updates the node tree to the config. Removed environments are
+// deleted, new ones are appended in the order of Names and changed fields
+// are replaced in place. Other nodes are kept as they were parsed.
+func (doc *document) patch(cfg *File) error {
+	mapping := doc.mapping()
+	content := make([]*yaml.Node, 0, len(mapping.Content))
+	written := make(map[string]bool, len(cfg.Config))
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+
+		switch name := key.Value; {
+		case name == BackupCopiesKey && cfg.backupCopies == nil:
+			continue
+		case name == BackupCopiesKey:
+			value = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(*cfg.backupCopies)}
+			written[name] = true
+		case name == SchemaVersionKey:
+		case !cfg.HasEnv(name) && name != DefaultsKey, name == DefaultsKey && !cfg.hasDefaults():
+			continue
+		default:
+			patched, err := doc.patchSession(name, value, cfg.Config[name])
+			if err != nil {
+				return fmt.Errorf("%s environment: %w", name, err)
+			}
+
+			value = patched
+			written[name] = true
+		}
+
+		content = append(content, key, value)
+	}
+
+	if cfg.backupCopies != nil && !written[BackupCopiesKey] {
+		at := 0
+		if len(content) > 0 && content[0].Value == SchemaVersionKey {
+			at = 2
+		}
+
+		content = append(content[:at], append([]*yaml.Node{
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: BackupCopiesKey},
+			{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(*cfg.backupCopies)},
+		}, content[at:]...)...)
+	}
+
+	names := cfg.Names()
+	if cfg.hasDefaults() {
+		names = append([]string{DefaultsKey}, names...)
+	}
+
+	for _, name := range names {
+		if written[name] {
+			continue
+		}
+
+		value, _, err := doc.encodeSession(cfg.Config[name])
+		if err != nil {
+			return fmt.Errorf("%s environment: %w", name, err)
+		}
+
+		content = append(content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}, value)
+	}
+
+	mapping.Content = content
+
+	return doc.snapshot(cfg.Config)
+}
+
+// patchSession replaces the fields of the environment node which differ
+// from the parsed ones. Fields are matched by the key or its alias.
+func (doc *document) patchSession(env string, node *yaml.Node, ses Session) (*yaml.Node, error) {
+	encoded, fields, err := doc.encodeSession(ses)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := serializeFields(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	base, ok := doc.base[env]
+	if !ok || node.Kind != yaml.MappingNode {
+		return encoded, nil
+	}
+
+	content := make([]*yaml.Node, 0, len(node.Content))
+	seen := make(map[string]bool, len(node.Content)/2)
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		name := resolveKeyAlias(key.Value)
+		seen[name] = true
+
+		if current[name] != base[name] {
+			if fields[name] == nil {
+				continue
+			}
+
+			fields[name].LineComment = value.LineComment
+			value = fields[name]
+		}
+
+		content = append(content, key, value)
+	}
+
+	for i := 0; i+1 < len(encoded.Content); i += 2 {
+		name := encoded.Content[i].Value
+		if !seen[name] && current[name] != base[name] {
+			content = append(content, encoded.Content[i], encoded.Content[i+1])
+		}
+	}
+
+	node.Content = content
+
+	return node, nil
+}
+
+// hasDefaults reports whether the config contains the defaults session.
+func (cfg *File) hasDefaults() bool {
+	_, ok := cfg.Config[DefaultsKey]
+
+	return ok
+}
+
+// Rename renames the environment like Config.Rename and keeps its position
+// and comments in the file.
+func (cfg *File) Rename(from, to string, force bool) error {
+	if err := cfg.Config.Rename(from, to, force); err != nil || from == to || cfg.doc == nil {
+		return err
+	}
+
+	mapping := cfg.doc.mapping()
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == to {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+
+			break
+		}
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == from {
+			mapping.Content[i].Value = to
+			cfg.doc.base[to] = cfg.doc.base[from]
+			delete(cfg.doc.base, from)
+
+			break
+		}
+	}
+
+	return nil
+}
+
+// Sort orders the environments of the file like Names: reserved keys go
+// first, then the defaults, the default environment and other environments
+// in alphabetical order. Keys of the sessions of JSON files are sorted too.
+func (cfg *File) Sort() {
+	if cfg.doc == nil {
+		return
+	}
+
+	mapping := cfg.doc.mapping()
+	sortMapping(mapping, keyLess)
+
+	if !cfg.doc.json {
+		return
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if value := mapping.Content[i+1]; value.Kind == yaml.MappingNode {
+			sortMapping(value, func(a, b string) bool { return a < b })
+		}
+	}
+}
+
+// IsSorted reports whether the file is in the order of Sort. Only the order
+// of keys is compared, formatting and comments are not.
+func (cfg *File) IsSorted() bool {
+	if cfg.doc == nil {
+		return true
+	}
+
+	mapping := cfg.doc.mapping()
+	if !isSortedMapping(mapping, keyLess) {
+		return false
+	}
+
+	if !cfg.doc.json {
+		return true
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		value := mapping.Content[i+1]
+		if value.Kind == yaml.MappingNode && !isSortedMapping(value, func(a, b string) bool { return a < b }) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sortMapping orders the pairs of the mapping node by keys.
+func sortMapping(mapping *yaml.Node, less func(a, b string) bool) {
+	pairs := make([][2]*yaml.Node, 0, len(mapping.Content)/2)
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		pairs = append(pairs, [2]*yaml.Node{mapping.Content[i], mapping.Content[i+1]})
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return less(pairs[i][0].Value, pairs[j][0].Value)
+	})
+
+	mapping.Content = mapping.Content[:0]
+	for _, pair := range pairs {
+		mapping.Content = append(mapping.Content, pair[0], pair[1])
+	}
+}
+
+// isSortedMapping reports whether the pairs of the mapping node are ordered
+// by keys.
+func isSortedMapping(mapping *yaml.Node, less func(a, b string) bool) bool {
+	for i := 2; i+1 < len(mapping.Content); i += 2 {
+		if less(mapping.Content[i].Value, mapping.Content[i-2].Value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// keyLess compares top level keys of the config in the canonical order.
+func keyLess(a, b string) bool {
+	rank := func(key string) int {
+		switch key {
+		case SchemaVersionKey:
+			return 0
+		case BackupCopiesKey:
+			return 1
+		case DefaultsKey:
+			return 2
+		case DefaultConfigEnv:
+			return 3
+		default:
+			return 4
+		}
+	}
+
+	if rank(a) != rank(b) {
+		return rank(a) < rank(b)
+	}
+
+	return a < b
+}
+
+// decodeJSONNode reads the next JSON value of the decoder to the node tree.
+// Keys keep the order of the file.
+func decodeJSONNode(decoder *json.Decoder) (*yaml.Node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch value := token.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if value == '{' {
+			node.Kind, node.Tag = yaml.MappingNode, "!!map"
+		}
+
+		for decoder.More() {
+			if node.Kind == yaml.MappingNode {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)})
+			}
+
+			child, err := decodeJSONNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+
+			node.Content = append(node.Content, child)
+		}
+
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+
+		return node, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}, nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(value.String(), ".eE") {
+			tag = "!!float"
+		}
+
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(value)}, nil
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+}
+
+// encodeJSONNode writes the node tree decoded by decodeJSONNode as compact
+// JSON.
+func encodeJSONNode(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		return encodeJSONNode(buf, node.Content[0])
+	case yaml.MappingNode, yaml.SequenceNode:
+		open, end := byte('['), byte(']')
+		if node.Kind == yaml.MappingNode {
+			open, end = '{', '}'
+		}
+
+		buf.WriteByte(open)
+
+		for i, child := range node.Content {
+			switch {
+			case i == 0:
+			case node.Kind == yaml.MappingNode && i%2 == 1:
+				buf.WriteByte(':')
+			default:
+				buf.WriteByte(',')
+			}
+
+			if err := encodeJSONNode(buf, child); err != nil {
+				return err
+			}
+		}
+
+		buf.WriteByte(end)
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!int", "!!float", "!!bool", "!!null":
+			buf.WriteString(node.Value)
+		default:
+			data, err := json.Marshal(node.Value)
+			if err != nil {
+				return err
+			}
+
+			buf.Write(data)
+		}
+	default:
+		return fmt.Errorf("unsupported node kind %d", node.Kind)
+	}
+
+	return nil
+}
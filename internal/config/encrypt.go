@@ -0,0 +1,254 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// EncryptedFileExt is appended to the name of the encrypted config file, so
+// the format is still chosen by the extension before it, e.g. rcon.yaml.enc.
+const EncryptedFileExt = ".enc"
+
+// encryptedBlockType is the type of the PEM block with the encrypted config.
+const encryptedBlockType = "RCON CONFIG"
+
+// Parameters of the encryption. The key is derived from the passphrase with
+// PBKDF2-HMAC-SHA256 and the config is sealed with AES-256-GCM.
+const (
+	encryptionCipher = "AES-256-GCM"
+	encryptionKDF    = "PBKDF2-SHA256"
+	kdfIterations    = 600000
+	saltSize         = 16
+	keySize          = 32
+
+	// minKDFIterations and maxKDFIterations bound the iterations read from
+	// the file, so a crafted file can neither weaken the key nor hang the
+	// start.
+	minKDFIterations = kdfIterations / 6
+	maxKDFIterations = kdfIterations * 2
+)
+
+// Encryption errors.
+var (
+	// ErrPassphraseRequired is returned when the encrypted config is read or
+	// written but the passphrase is not available.
+	ErrPassphraseRequired = errors.New("passphrase of the encrypted config is required")
+
+	// ErrDecrypt is returned when the encrypted config cannot be decrypted
+	// with the passphrase.
+	ErrDecrypt = errors.New("decrypt config: wrong passphrase or corrupted file")
+
+	// ErrEncrypted is returned when the config file is encrypted or not
+	// encrypted contrary to the expectation.
+	ErrEncrypted = errors.New("config file encryption mismatch")
+)
+
+// Passphrase returns the passphrase of encrypted config files. It is called
+// every time the encrypted file is read or written, so it should cache the
+// passphrase if it is prompted.
+var Passphrase func() ([]byte, error)
+
+// IsEncrypted reports whether the config file name has EncryptedFileExt.
+func IsEncrypted(name string) bool {
+	return path.Ext(name) == EncryptedFileExt
+}
+
+// formatExt returns the extension which tells the format of the file.
+func formatExt(name string) string {
+	return path.Ext(strings.TrimSuffix(name, EncryptedFileExt))
+}
+
+// ReadFile reads the config file. Encrypted files are decrypted with
+// Passphrase.
+func ReadFile(name string) ([]byte, error) {
+	data, err := os.ReadFile(name)
+	if err != nil || !IsEncrypted(name) {
+		return data, err
+	}
+
+	passphrase, err := passphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	return Decrypt(data, passphrase)
+}
+
+// seal encrypts the data of the config file with Passphrase if the file is
+// encrypted.
+func seal(name string, data []byte) ([]byte, error) {
+	if !IsEncrypted(name) {
+		return data, nil
+	}
+
+	passphrase, err := passphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	return Encrypt(data, passphrase)
+}
+
+// passphrase calls Passphrase if it is set.
+func passphrase() ([]byte, error) {
+	if Passphrase == nil {
+		return nil, ErrPassphraseRequired
+	}
+
+	value, err := Passphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(value) == 0 {
+		return nil, ErrPassphraseRequired
+	}
+
+	return value, nil
+}
+
+// EncryptFile writes the encrypted copy of the plain config file src to
+// dst. Comments and order of the source file are kept.
+func EncryptFile(src string, dst string) error {
+	if IsEncrypted(src) || !IsEncrypted(dst) {
+		return fmt.Errorf("%w: encrypt %s to %s: want plain source and %s destination",
+			ErrEncrypted, src, dst, EncryptedFileExt)
+	}
+
+	return convertFile(src, dst)
+}
+
+// DecryptFile writes the plain copy of the encrypted config file src to dst.
+func DecryptFile(src string, dst string) error {
+	if !IsEncrypted(src) || IsEncrypted(dst) {
+		return fmt.Errorf("%w: decrypt %s to %s: want %s source and plain destination",
+			ErrEncrypted, src, dst, EncryptedFileExt)
+	}
+
+	return convertFile(src, dst)
+}
+
+// convertFile reads src and writes its content to dst, the encryption is
+// chosen by the names. The content is checked to be a valid config.
+func convertFile(src string, dst string) error {
+	if formatExt(src) != formatExt(dst) {
+		return fmt.Errorf("%w %s", ErrUnsupportedFileExt, formatExt(dst))
+	}
+
+	data, err := ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("read file %s: %w", src, err)
+	}
+
+	cfg := new(File)
+	if err := cfg.unmarshal(src, data); err != nil {
+		return fmt.Errorf("parse file %s: %w", src, err)
+	}
+
+	if data, err = seal(dst, data); err != nil {
+		return fmt.Errorf("write file %s: %w", dst, err)
+	}
+
+	return writeFile(dst, data, cfg.BackupCopies())
+}
+
+// Encrypt seals the data with the key derived from the passphrase. The
+// result is the PEM block with the parameters of the key derivation in its
+// headers, so it can be committed to the repository as text.
+func Encrypt(data []byte, passphrase []byte) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("encrypt config: %w", err)
+	}
+
+	gcm, err := newGCM(passphrase, salt, kdfIterations)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt config: %w", err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("encrypt config: %w", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type: encryptedBlockType,
+		Headers: map[string]string{
+			"Cipher":     encryptionCipher,
+			"KDF":        encryptionKDF,
+			"Iterations": strconv.Itoa(kdfIterations),
+			"Salt":       base64.StdEncoding.EncodeToString(salt),
+		},
+		Bytes: gcm.Seal(nonce, nonce, data, nil),
+	}), nil
+}
+
+// Decrypt opens the data sealed by Encrypt.
+func Decrypt(data []byte, passphrase []byte) ([]byte, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != encryptedBlockType {
+		return nil, fmt.Errorf("%w: no %s block", ErrDecrypt, encryptedBlockType)
+	}
+
+	if block.Headers["Cipher"] != encryptionCipher || block.Headers["KDF"] != encryptionKDF {
+		return nil, fmt.Errorf("%w: unsupported cipher %s with %s",
+			ErrDecrypt, block.Headers["Cipher"], block.Headers["KDF"])
+	}
+
+	iterations, err := strconv.Atoi(block.Headers["Iterations"])
+	if err != nil || iterations < minKDFIterations || iterations > maxKDFIterations {
+		return nil, fmt.Errorf("%w: iterations must be between %d and %d", ErrDecrypt, minKDFIterations, maxKDFIterations)
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(block.Headers["Salt"])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid salt", ErrDecrypt)
+	}
+
+	gcm, err := newGCM(passphrase, salt, iterations)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecrypt, err)
+	}
+
+	if len(block.Bytes) < gcm.NonceSize() {
+		return nil, ErrDecrypt
+	}
+
+	nonce, sealed := block.Bytes[:gcm.NonceSize()], block.Bytes[gcm.NonceSize():]
+
+	plain, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return plain, nil
+}
+
+// newGCM creates AES-256-GCM cipher with the key derived from the
+// passphrase.
+func newGCM(passphrase []byte, salt []byte, iterations int) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(deriveKey(passphrase, salt, iterations, keySize))
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// deriveKey derives the key of the length from the passphrase with
+// PBKDF2-HMAC-SHA256.
+func deriveKey(passphrase []byte, salt []byte, iterations int, length int) []byte {
+	return pbkdf2.Key(passphrase, salt, iterations, length, sha256.New)
+}
@@ -0,0 +1,14 @@
+package config
+
+import "os"
+
+// SetRenameFile replaces the function which moves the written temporary file
+// over the config file. The returned function restores the original one.
+func SetRenameFile(fn func(oldpath, newpath string) error) (restore func()) {
+	renameFile = fn
+
+	return func() { renameFile = os.Rename }
+}
+
+// DeriveKey exposes the key derivation of encrypted configs.
+var DeriveKey = deriveKey
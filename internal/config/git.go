@@ -0,0 +1,116 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// GitSourcePrefix marks the config name as a file in a Git repository, e.g.
+// `git+https://github.com/org/infra.git//rcon/prod.yaml`. The part after
+// the double slash is the path to the config file in the repository.
+const GitSourcePrefix = "git+"
+
+// GitRef pins configs loaded from Git repositories to the tag, branch or
+// commit. Empty value means the latest commit of the default branch.
+var GitRef string
+
+// ErrInvalidGitSource is returned when the Git config name has no path to
+// the config file or the path leaves the repository.
+var ErrInvalidGitSource = errors.New("invalid git source: expected git+<repository>//<path>")
+
+// IsGitSource reports whether the config name refers to a file in a Git
+// repository.
+func IsGitSource(name string) bool {
+	return strings.HasPrefix(name, GitSourcePrefix)
+}
+
+// parseGit clones the repository to a temporary directory and parses the
+// config file from it. The clone is removed after parsing.
+func (cfg *File) parseGit(name string) error {
+	repo, file, err := splitGitSource(name)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "gorcon-git-")
+	if err != nil {
+		return fmt.Errorf("git: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := gitClone(repo, GitRef, dir); err != nil {
+		return err
+	}
+
+	if err := cfg.parse(filepath.Join(dir, filepath.FromSlash(file))); err != nil {
+		return fmt.Errorf("git %s//%s: %w", repo, file, err)
+	}
+
+	cfg.sources = []string{name}
+
+	return nil
+}
+
+// splitGitSource splits the Git config name into the repository URL and
+// the path to the config file.
+func splitGitSource(name string) (string, string, error) {
+	source := strings.TrimPrefix(name, GitSourcePrefix)
+
+	// Skip the double slash of the URL scheme.
+	start := 0
+	if i := strings.Index(source, "://"); i >= 0 {
+		start = i + len("://")
+	}
+
+	i := strings.Index(source[start:], "//")
+	if i < 0 {
+		return "", "", fmt.Errorf("%w: %s", ErrInvalidGitSource, name)
+	}
+
+	repo, file := source[:start+i], source[start+i+len("//"):]
+	if repo == "" || file == "" || !filepath.IsLocal(filepath.FromSlash(file)) {
+		return "", "", fmt.Errorf("%w: %s", ErrInvalidGitSource, name)
+	}
+
+	return repo, file, nil
+}
+
+// gitClone clones the repository to the directory and checks out the ref.
+// Only the last commit is fetched when no ref is set.
+func gitClone(repo, ref, dir string) error {
+	if ref == "" {
+		return git("", "clone", "--quiet", "--depth", "1", repo, dir)
+	}
+
+	if err := git("", "clone", "--quiet", "--no-checkout", repo, dir); err != nil {
+		return err
+	}
+
+	return git(dir, "checkout", "--quiet", ref)
+}
+
+// git runs the git command in the directory. Credential prompts are
+// disabled, so an inaccessible repository fails instead of hanging.
+func git(dir string, args ...string) error {
+	var stderr bytes.Buffer
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+		}
+
+		return fmt.Errorf("git %s: %w", args[0], err)
+	}
+
+	return nil
+}
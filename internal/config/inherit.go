@@ -0,0 +1,108 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// DefaultsKey is the reserved top level config key that contains the
+// session which unset fields of all environments are taken from.
+const DefaultsKey = "defaults"
+
+var (
+	// ErrInheritanceCycle is returned when environments inherit each other.
+	ErrInheritanceCycle = errors.New("inheritance cycle")
+
+	// ErrEnvInherited is returned when the removed environment is inherited
+	// by other environments.
+	ErrEnvInherited = errors.New("environment is inherited")
+)
+
+// isReserved reports whether the name is a reserved top level key and cannot
+// be used as the environment name.
+func isReserved(name string) bool {
+	return name == "" || name == SchemaVersionKey || name == BackupCopiesKey || name == DefaultsKey
+}
+
+// resolve returns the session of the environment with unset fields taken
+// from the inherited environments in order and then from the defaults.
+func (cfg Config) resolve(name string) (Session, error) {
+	ses := cfg[name]
+	chain := []string{name}
+
+	for parent := ses.Inherits; parent != ""; parent = cfg[parent].Inherits {
+		if slices.Contains(chain, parent) {
+			return ses, fmt.Errorf("%w: %s", ErrInheritanceCycle, strings.Join(append(chain, parent), " -> "))
+		}
+
+		if !cfg.HasEnv(parent) {
+			return ses, fmt.Errorf("inherits unknown environment %q", parent)
+		}
+
+		chain = append(chain, parent)
+		ses = inherit(ses, cfg[parent])
+	}
+
+	if defaults, ok := cfg[DefaultsKey]; ok {
+		ses = inherit(ses, defaults)
+	}
+
+	ses.Inherits = cfg[name].Inherits
+
+	return ses, nil
+}
+
+// flatten returns the session of the environment with the inherited fields
+// and without the reference to the parent, so the session does not depend
+// on other environments. The session is returned as is if it cannot be
+// resolved.
+func (cfg Config) flatten(name string) Session {
+	ses, err := cfg.resolve(name)
+	if err != nil {
+		return cfg[name]
+	}
+
+	ses.Inherits = ""
+
+	return ses
+}
+
+// inherit sets zero fields of the session to the fields of the parent.
+// Nested fields like ssh are inherited as a whole, macros are merged by
+// name.
+func inherit(ses Session, parent Session) Session {
+	macros := ses.Macros
+	child := reflect.ValueOf(&ses).Elem()
+	from := reflect.ValueOf(parent)
+
+	for i := 0; i < child.NumField(); i++ {
+		if field := child.Field(i); field.CanSet() && field.IsZero() {
+			field.Set(from.Field(i))
+		}
+	}
+
+	if len(macros) > 0 && len(parent.Macros) > 0 {
+		ses.Macros = maps.Clone(parent.Macros)
+		maps.Copy(ses.Macros, macros)
+	}
+
+	return ses
+}
+
+// inheritors returns names of the environments which inherit the
+// environment directly.
+func (cfg Config) inheritors(name string) []string {
+	var names []string
+
+	for _, env := range cfg.Names() {
+		if cfg[env].Inherits == name {
+			names = append(names, env)
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/gorcon/rcon-cli/internal/logger"
+	"github.com/zalando/go-keyring"
+)
+
+// Secret errors.
+var (
+	// ErrInvalidKeyringRef is returned when keyring reference does not match
+	// `service/account` format.
+	ErrInvalidKeyringRef = errors.New("invalid keyring reference: expected service/account")
+
+	// ErrPasswordEnvNotSet is returned when the environment variable of
+	// password_env is not set or empty.
+	ErrPasswordEnvNotSet = errors.New("password environment variable is not set")
+)
+
+// DefaultKeyringService is the keyring service of the passwords stored by
+// `secret set` without the reference.
+const DefaultKeyringService = "gorcon"
+
+// StorePassword saves password to the OS keyring under the reference in
+// `service/account` format.
+func StorePassword(ref, plain string) error {
+	service, account, err := parseKeyringRef(ref)
+	if err != nil {
+		return err
+	}
+
+	if err := keyring.Set(service, account, plain); err != nil {
+		return fmt.Errorf("keyring %s: %w", ref, err)
+	}
+
+	return nil
+}
+
+// LoadPassword returns the password stored in the OS keyring under the
+// reference in `service/account` format.
+func LoadPassword(ref string) (string, error) {
+	service, account, err := parseKeyringRef(ref)
+	if err != nil {
+		return "", err
+	}
+
+	plain, err := keyring.Get(service, account)
+	if err != nil {
+		return "", fmt.Errorf("keyring %s: %w", ref, err)
+	}
+
+	return plain, nil
+}
+
+// DeletePassword removes the password stored in the OS keyring under the
+// reference in `service/account` format.
+func DeletePassword(ref string) error {
+	service, account, err := parseKeyringRef(ref)
+	if err != nil {
+		return err
+	}
+
+	if err := keyring.Delete(service, account); err != nil {
+		return fmt.Errorf("keyring %s: %w", ref, err)
+	}
+
+	return nil
+}
+
+// Resolve fills the password of the session from the external source if it
+// is not set explicitly. Sources are checked in order: environment variable,
+// file and OS keyring. Environment name is used in error messages.
+func (s *Session) Resolve(env string) error {
+	if s.Password != "" {
+		return nil
+	}
+
+	switch {
+	case s.PasswordEnv != "":
+		if s.Password = os.Getenv(s.PasswordEnv); s.Password == "" {
+			return fmt.Errorf("%s environment: %w: %s", env, ErrPasswordEnvNotSet, s.PasswordEnv)
+		}
+	case s.PasswordFile != "":
+		data, err := os.ReadFile(s.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("%s environment: password file: %w", env, err)
+		}
+
+		// Editors and `echo` add the trailing newline.
+		s.Password = strings.TrimRight(string(data), "\r\n")
+	case s.PasswordKeyring != "":
+		service, account, err := parseKeyringRef(s.PasswordKeyring)
+		if err != nil {
+			return fmt.Errorf("%s environment: %w", env, err)
+		}
+
+		s.Password, err = keyring.Get(service, account)
+		if err != nil {
+			return fmt.Errorf("%s environment: password keyring %s: %w", env, s.PasswordKeyring, err)
+		}
+	}
+
+	return nil
+}
+
+// passwordSources returns the names of the password fields which are set.
+func (s *Session) passwordSources() []string {
+	var sources []string
+
+	for _, source := range []struct{ name, value string }{
+		{"password", s.Password},
+		{"password_env", s.PasswordEnv},
+		{"password_file", s.PasswordFile},
+		{"password_keyring", s.PasswordKeyring},
+	} {
+		if source.value != "" {
+			sources = append(sources, source.name)
+		}
+	}
+
+	return sources
+}
+
+// ExpandEnv replaces ${VAR} references in the connection fields with the
+// values of the environment variables. Undefined variables are replaced
+// with empty string. Other `$` characters are kept, so passwords may contain
+// them.
+func (s *Session) ExpandEnv() {
+	for _, field := range []*string{
+		&s.Address, &s.Password, &s.PasswordEnv, &s.PasswordFile, &s.PasswordKeyring,
+		&s.ProxyCommand, &s.Proxy, &s.MarksFile, &s.RequestHMACSecret,
+	} {
+		*field = expandEnv(*field)
+	}
+
+	// Placeholders of the log file are replaced by the logger.
+	s.Log = expandEnv(s.Log, logger.PlaceholderEnv, logger.PlaceholderDate)
+
+	for i := range s.Addresses {
+		s.Addresses[i] = expandEnv(s.Addresses[i])
+	}
+
+	if s.SSH != nil {
+		ssh := *s.SSH
+		ssh.Host, ssh.User = expandEnv(ssh.Host), expandEnv(ssh.User)
+		ssh.KeyFile, ssh.KnownHostsFile = expandEnv(ssh.KeyFile), expandEnv(ssh.KnownHostsFile)
+		s.SSH = &ssh
+	}
+
+	if s.TLS != nil {
+		tls := *s.TLS
+		tls.CAFile, tls.CertFile, tls.KeyFile = expandEnv(tls.CAFile), expandEnv(tls.CertFile), expandEnv(tls.KeyFile)
+		s.TLS = &tls
+	}
+}
+
+// envRef matches ${VAR} reference.
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${VAR} references in the text except the kept ones.
+func expandEnv(text string, keep ...string) string {
+	if !strings.Contains(text, "${") {
+		return text
+	}
+
+	return envRef.ReplaceAllStringFunc(text, func(ref string) string {
+		for _, k := range keep {
+			if ref == k {
+				return ref
+			}
+		}
+
+		return os.Getenv(ref[2 : len(ref)-1])
+	})
+}
+
+// parseKeyringRef splits keyring reference into service and account.
+func parseKeyringRef(ref string) (string, string, error) {
+	service, account, ok := strings.Cut(ref, "/")
+	if !ok || service == "" || account == "" {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidKeyringRef, ref)
+	}
+
+	return service, account, nil
+}
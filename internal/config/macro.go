@@ -0,0 +1,227 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MacroPrefix starts the invocation of the macro, e.g. `@restart-warn 60`.
+const MacroPrefix = "@"
+
+// MacroSleep is the directive of the macro step which pauses the execution
+// instead of sending the command. The duration is in seconds or in Go
+// duration format.
+const MacroSleep = "sleep"
+
+// Macro errors.
+var (
+	// ErrMacroNotFound is returned when the invoked macro is not defined.
+	ErrMacroNotFound = errors.New("macro not found")
+
+	// ErrMacroCycle is returned when macros invoke each other.
+	ErrMacroCycle = errors.New("macro cycle")
+
+	// ErrMacroArgument is returned when the step refers to the argument
+	// which is not passed.
+	ErrMacroArgument = errors.New("missing macro argument")
+)
+
+// macroPlaceholder matches $1..$9, $* and $$ placeholders of the steps.
+var macroPlaceholder = regexp.MustCompile(`\$([1-9*$])`)
+
+// MacroStep is the step of the expanded macro: the command to execute or
+// the pause.
+type MacroStep struct {
+	Command string
+	Pause   time.Duration
+}
+
+// IsMacro reports whether the command is the invocation of the macro.
+func IsMacro(command string) bool {
+	return strings.HasPrefix(command, MacroPrefix) && len(command) > len(MacroPrefix)
+}
+
+// ExpandMacro returns the steps of the macro invocation. Arguments of the
+// invocation are separated by spaces and replace the placeholders, nested
+// invocations are expanded in place.
+func (s *Session) ExpandMacro(invocation string) ([]MacroStep, error) {
+	return s.expandMacro(invocation, nil)
+}
+
+// expandMacro expands the invocation. The stack contains the names of the
+// macros which are being expanded.
+func (s *Session) expandMacro(invocation string, stack []string) ([]MacroStep, error) {
+	name, args := parseMacroInvocation(invocation)
+
+	if slices.Contains(stack, name) {
+		return nil, fmt.Errorf("%w: %s", ErrMacroCycle, strings.Join(append(stack, name), " -> "))
+	}
+
+	macro, ok := s.Macros[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMacroNotFound, name)
+	}
+
+	stack = append(stack, name)
+	steps := make([]MacroStep, 0, len(macro))
+
+	for _, line := range macro {
+		command, err := substituteMacroArgs(line, args)
+		if err != nil {
+			return nil, fmt.Errorf("macro %s: %w", name, err)
+		}
+
+		if IsMacro(command) {
+			nested, err := s.expandMacro(command, stack)
+			if err != nil {
+				return nil, err
+			}
+
+			steps = append(steps, nested...)
+
+			continue
+		}
+
+		if value, ok := strings.CutPrefix(command, MacroSleep+" "); ok {
+			pause, err := parseMacroSleep(value)
+			if err != nil {
+				return nil, fmt.Errorf("macro %s: %w", name, err)
+			}
+
+			steps = append(steps, MacroStep{Pause: pause})
+
+			continue
+		}
+
+		steps = append(steps, MacroStep{Command: command})
+	}
+
+	return steps, nil
+}
+
+// parseMacroInvocation returns the name of the invoked macro and its
+// arguments.
+func parseMacroInvocation(invocation string) (string, []string) {
+	fields := strings.Fields(strings.TrimPrefix(invocation, MacroPrefix))
+	if len(fields) == 0 {
+		return "", nil
+	}
+
+	return fields[0], fields[1:]
+}
+
+// substituteMacroArgs replaces the placeholders of the step with the
+// arguments.
+func substituteMacroArgs(line string, args []string) (string, error) {
+	var err error
+
+	command := macroPlaceholder.ReplaceAllStringFunc(line, func(placeholder string) string {
+		switch placeholder[1] {
+		case '$':
+			return "$"
+		case '*':
+			return strings.Join(args, " ")
+		}
+
+		n := int(placeholder[1] - '0')
+		if n > len(args) {
+			err = fmt.Errorf("%w: %s", ErrMacroArgument, placeholder)
+
+			return placeholder
+		}
+
+		return args[n-1]
+	})
+
+	return command, err
+}
+
+// parseMacroSleep parses the duration of the sleep directive: the number of
+// seconds or Go duration.
+func parseMacroSleep(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+
+	pause, err := time.ParseDuration(value)
+	if err != nil || pause < 0 {
+		return 0, fmt.Errorf("invalid %s duration %q", MacroSleep, value)
+	}
+
+	return pause, nil
+}
+
+// validateMacros checks names of the macros, sleep directives and
+// references to other macros. Directives and references with placeholders
+// are checked on invocation.
+func validateMacros(ses Session) error {
+	names := make([]string, 0, len(ses.Macros))
+	for name := range ses.Macros {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if name == "" || strings.ContainsAny(name, " \t") || strings.HasPrefix(name, MacroPrefix) {
+			return fmt.Errorf("invalid macro name %q", name)
+		}
+
+		if len(ses.Macros[name]) == 0 {
+			return fmt.Errorf("macro %s has no steps", name)
+		}
+	}
+
+	for _, name := range names {
+		if err := ses.validateMacro(name, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateMacro checks the steps of the macro and the macros it invokes.
+func (s *Session) validateMacro(name string, stack []string) error {
+	if slices.Contains(stack, name) {
+		return fmt.Errorf("%w: %s", ErrMacroCycle, strings.Join(append(stack, name), " -> "))
+	}
+
+	macro, ok := s.Macros[name]
+	if !ok {
+		return fmt.Errorf("macro %s: %w: %s", stack[len(stack)-1], ErrMacroNotFound, name)
+	}
+
+	stack = append(stack, name)
+
+	for _, line := range macro {
+		if macroPlaceholder.MatchString(line) {
+			continue
+		}
+
+		if IsMacro(line) {
+			nested, _ := parseMacroInvocation(line)
+			if err := s.validateMacro(nested, stack); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if value, ok := strings.CutPrefix(line, MacroSleep+" "); ok {
+			if _, err := parseMacroSleep(value); err != nil {
+				return fmt.Errorf("macro %s: %w", name, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Metric extracts the value of the Prometheus gauge from the response of the
+// command. The first capture group of the regular expression is the value,
+// without groups the value is the number of matching lines, e.g. players
+// listed one per line.
+type Metric struct {
+	Name    string `json:"name" yaml:"name"`
+	Help    string `json:"help,omitempty" yaml:"help,omitempty"`
+	Command string `json:"command" yaml:"command"`
+	Regexp  string `json:"regexp" yaml:"regexp"`
+}
+
+// MetricPresets contains the metrics of the games which are added by the
+// metrics_preset field.
+var MetricPresets = map[string][]Metric{
+	"minecraft": {
+		{Name: "players", Help: "Online players", Command: "list", Regexp: `There are (\d+) of a max(?: of)? \d+ players`},
+		{Name: "max_players", Help: "Player slots", Command: "list", Regexp: `There are \d+ of a max(?: of)? (\d+) players`},
+	},
+	"source": {
+		{Name: "players", Help: "Online players", Command: "status", Regexp: `players\s*:\s*(\d+)`},
+		{Name: "max_players", Help: "Player slots", Command: "status", Regexp: `players\s*:.*?\((\d+)(?:/\d+)? max\)`},
+	},
+	"7dtd": {
+		{Name: "players", Help: "Online players", Command: "lp", Regexp: `Total of (\d+) in the game`},
+	},
+	"factorio": {
+		{Name: "players", Help: "Online players", Command: "/players online count", Regexp: `Online players \((\d+)\)`},
+	},
+}
+
+// metricName matches valid names of Prometheus metrics.
+var metricName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// AllMetrics returns the metrics of the preset followed by the metrics of
+// the session. Metrics of the session replace the preset metrics of the
+// same name.
+func (s *Session) AllMetrics() []Metric {
+	preset := MetricPresets[s.MetricsPreset]
+	metrics := make([]Metric, 0, len(preset)+len(s.Metrics))
+
+	for _, metric := range preset {
+		if !s.hasMetric(metric.Name) {
+			metrics = append(metrics, metric)
+		}
+	}
+
+	return append(metrics, s.Metrics...)
+}
+
+// hasMetric reports whether the session defines the metric.
+func (s *Session) hasMetric(name string) bool {
+	for _, metric := range s.Metrics {
+		if metric.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Extract returns the value of the metric from the response. False is
+// returned if the response has no value.
+func (m Metric) Extract(response string) (float64, bool) {
+	re, err := regexp.Compile(m.Regexp)
+	if err != nil {
+		return 0, false
+	}
+
+	if re.NumSubexp() == 0 {
+		count := 0
+
+		for _, line := range strings.Split(response, "\n") {
+			if re.MatchString(line) {
+				count++
+			}
+		}
+
+		return float64(count), true
+	}
+
+	match := re.FindStringSubmatch(response)
+	if match == nil {
+		return 0, false
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(match[1]), 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return value, true
+}
+
+// validateMetrics checks the preset, names and regular expressions of the
+// metrics.
+func validateMetrics(ses Session) error {
+	if _, ok := MetricPresets[ses.MetricsPreset]; !ok && ses.MetricsPreset != "" {
+		presets := make([]string, 0, len(MetricPresets))
+		for name := range MetricPresets {
+			presets = append(presets, name)
+		}
+
+		sort.Strings(presets)
+
+		return fmt.Errorf("unsupported metrics_preset %q, want one of %s", ses.MetricsPreset, strings.Join(presets, ", "))
+	}
+
+	names := make(map[string]bool, len(ses.Metrics))
+
+	for _, metric := range ses.Metrics {
+		if !metricName.MatchString(metric.Name) {
+			return fmt.Errorf("invalid metric name %q", metric.Name)
+		}
+
+		if names[metric.Name] {
+			return fmt.Errorf("duplicate metric %s", metric.Name)
+		}
+
+		names[metric.Name] = true
+
+		if metric.Command == "" {
+			return fmt.Errorf("metric %s: command is required", metric.Name)
+		}
+
+		if _, err := regexp.Compile(metric.Regexp); err != nil || metric.Regexp == "" {
+			return fmt.Errorf("metric %s: invalid regexp %q", metric.Name, metric.Regexp)
+		}
+	}
+
+	return nil
+}
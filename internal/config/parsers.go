@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ParserPresets contains the response parsers of the games which are added
+// by the response_parsers_preset field.
+var ParserPresets = map[string][]ResponseParser{
+	"minecraft": {
+		{
+			Name:    "players",
+			Pattern: `There are (?P<count>\d+) of a max(?: of)? (?P<max>\d+) players online:?[ \t]*(?P<names>.*)`,
+		},
+	},
+	"source": {
+		{Name: "hostname", Pattern: `(?m)^hostname\s*:\s*(?P<hostname>.*?)\s*$`},
+		{Name: "map", Pattern: `(?m)^map\s*:\s*(?P<map>\S+)`},
+		{Name: "players", Pattern: `(?m)^players\s*:\s*(?P<count>\d+)[^(\n]*\((?P<max>\d+)(?:/\d+)? max\)`},
+		{
+			Name: "player",
+			Pattern: `(?m)^#\s*(?P<userid>\d+)\s+(?:\d+\s+)?"(?P<name>[^"]*)"\s+(?P<uniqueid>\S+)\s+` +
+				`(?P<connected>[\d:]+)\s+(?P<ping>\d+)`,
+		},
+	},
+}
+
+// AllResponseParsers returns the parsers of the preset followed by the
+// parsers of the session. Parsers of the session replace the preset parsers
+// of the same name.
+func (s *Session) AllResponseParsers() []ResponseParser {
+	preset := ParserPresets[s.ResponseParsersPreset]
+	parsers := make([]ResponseParser, 0, len(preset)+len(s.ResponseParsers))
+
+	for _, p := range preset {
+		if !s.hasResponseParser(p.Name) {
+			parsers = append(parsers, p)
+		}
+	}
+
+	return append(parsers, s.ResponseParsers...)
+}
+
+// hasResponseParser reports whether the session defines the parser.
+func (s *Session) hasResponseParser(name string) bool {
+	for _, p := range s.ResponseParsers {
+		if p.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateParsersPreset checks that the preset of the response parsers
+// exists. Empty preset is valid.
+func ValidateParsersPreset(preset string) error {
+	if _, ok := ParserPresets[preset]; ok || preset == "" {
+		return nil
+	}
+
+	presets := make([]string, 0, len(ParserPresets))
+	for name := range ParserPresets {
+		presets = append(presets, name)
+	}
+
+	sort.Strings(presets)
+
+	return fmt.Errorf("unsupported response_parsers_preset %q, want one of %s", preset, strings.Join(presets, ", "))
+}
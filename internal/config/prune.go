@@ -0,0 +1,164 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StateFileExt replaces the extension of the config file in the name of
+// the sidecar file which tracks unreachable environments for Prune.
+const StateFileExt = ".state.yaml"
+
+// DefaultPruneMaxAge is how long the environment must be unreachable to be
+// removed by prune.
+const DefaultPruneMaxAge = 30 * 24 * time.Hour
+
+// ErrNoStateFile is returned when the config was not loaded from a local
+// file, so there is no place for the state file.
+var ErrNoStateFile = errors.New("config is not loaded from a local file")
+
+// envState is the tracked state of the environment.
+type envState struct {
+	UnreachableSince time.Time `yaml:"unreachable_since"`
+}
+
+// StateFile returns the path to the sidecar state file of the config, e.g.
+// `rcon.state.yaml` for `rcon.yaml`.
+func (cfg *File) StateFile() (string, error) {
+	sources := cfg.Sources()
+	if len(sources) == 0 || sources[0] == NoConfigFileSource || IsGitSource(sources[0]) {
+		return "", ErrNoStateFile
+	}
+
+	return strings.TrimSuffix(sources[0], filepath.Ext(sources[0])) + StateFileExt, nil
+}
+
+// Prune checks that the environments accept TCP connections and removes
+// the ones which have been unreachable for longer than maxAge. The time
+// since the first failed check is kept in the state file, so the config is
+// pruned by repeated runs. Environments without address, with proxy
+// command and of UDP protocols are not checked. Returns the removed environment names.
+func (cfg *File) Prune(maxAge time.Duration) ([]string, error) {
+	name, err := cfg.StateFile()
+	if err != nil {
+		return nil, err
+	}
+
+	state := make(map[string]envState)
+
+	data, err := os.ReadFile(name)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read state file %s: %w", name, err)
+	}
+
+	if err := yaml.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("parse state file %s: %w", name, err)
+	}
+
+	now := time.Now()
+	reachable := cfg.probe()
+	removed := make([]string, 0)
+
+	for _, env := range cfg.Names() {
+		if reachable[env] {
+			delete(state, env)
+
+			continue
+		}
+
+		since := state[env].UnreachableSince
+		if since.IsZero() {
+			since = now
+			state[env] = envState{UnreachableSince: since}
+		}
+
+		// The state is kept for the removed environment in case the config
+		// is not written, e.g. in dry run.
+		if now.Sub(since) > maxAge {
+			delete(cfg.Config, env)
+			removed = append(removed, env)
+		}
+	}
+
+	// Environments removed from the config by other means are forgotten.
+	for env := range state {
+		if !cfg.HasEnv(env) && !slices.Contains(removed, env) {
+			delete(state, env)
+		}
+	}
+
+	if data, err = yaml.Marshal(state); err != nil {
+		return nil, fmt.Errorf("serialize state file %s: %w", name, err)
+	}
+
+	if err := writeFile(name, data, 0); err != nil {
+		return nil, err
+	}
+
+	return removed, nil
+}
+
+// probe concurrently checks that the environments accept TCP connections.
+// Environments which cannot be checked are reported as reachable. An
+// environment with several addresses is reachable if any of them is.
+func (cfg *Config) probe() map[string]bool {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	reachable := make(map[string]bool, len(*cfg))
+
+	for _, env := range cfg.Names() {
+		ses := cfg.flatten(env)
+
+		addresses := ses.Addresses
+		if len(addresses) == 0 && ses.Address != "" {
+			addresses = []string{ses.Address}
+		}
+
+		if len(addresses) == 0 || ses.ProxyCommand != "" || ses.SSH != nil || ses.Type == ProtocolBattlEye ||
+			ses.Type == ProtocolPterodactyl {
+			mu.Lock()
+			reachable[env] = true
+			mu.Unlock()
+
+			continue
+		}
+
+		timeout := ses.DialTimeout()
+		if timeout == 0 {
+			timeout = DefaultTimeout
+		}
+
+		for _, address := range addresses {
+			wg.Add(1)
+
+			go func(env, address string) {
+				defer wg.Done()
+
+				conn, err := net.DialTimeout("tcp", address, timeout)
+				if err != nil {
+					return
+				}
+
+				_ = conn.Close()
+
+				mu.Lock()
+				reachable[env] = true
+				mu.Unlock()
+			}(env, AddressWithPort(address, ses.Type))
+		}
+	}
+
+	wg.Wait()
+
+	return reachable
+}
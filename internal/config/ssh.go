@@ -0,0 +1,101 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// Values of StrictHostKeyChecking which are passed to ssh.
+const (
+	SSHHostKeyYes       = "yes"
+	SSHHostKeyNo        = "no"
+	SSHHostKeyAcceptNew = "accept-new"
+)
+
+// SSHCommand is the ssh client which tunnels the connection.
+const SSHCommand = "ssh"
+
+// SSH contains the bastion host which the connection to the server is
+// tunneled through. The system ssh client is started as the proxy command,
+// so ssh config, agent and known_hosts work as in the terminal.
+type SSH struct {
+	// Host is the bastion host with optional port.
+	Host string `json:"host" yaml:"host"`
+	User string `json:"user" yaml:"user,omitempty"`
+	// KeyFile is the private key. Without it the keys of ssh agent and the
+	// default keys are used.
+	KeyFile string `json:"key_file" yaml:"key_file,omitempty"`
+	// KnownHostsFile replaces ~/.ssh/known_hosts. StrictHostKeyChecking is
+	// yes, no or accept-new, empty value keeps the ssh default.
+	KnownHostsFile        string `json:"known_hosts_file" yaml:"known_hosts_file,omitempty"`
+	StrictHostKeyChecking string `json:"strict_host_key_checking" yaml:"strict_host_key_checking,omitempty"`
+}
+
+// safeArg matches arguments which do not need quoting.
+var safeArg = regexp.MustCompile(`^[A-Za-z0-9_./:@%+=,-]+$`)
+
+// ProxyCommand returns the ssh command which forwards stdin and stdout to
+// the %h:%p placeholders of the proxy command. BatchMode is set because
+// stdin of the command is the transport to the server and cannot be used
+// for password prompts.
+func (s *SSH) ProxyCommand() string {
+	args := []string{SSHCommand, "-W", "%h:%p", "-o", "BatchMode=yes"}
+
+	host := s.Host
+	if h, port, err := net.SplitHostPort(s.Host); err == nil {
+		host = h
+		args = append(args, "-p", port)
+	}
+
+	if s.KeyFile != "" {
+		args = append(args, "-i", quoteArg(s.KeyFile), "-o", "IdentitiesOnly=yes")
+	}
+
+	if s.KnownHostsFile != "" {
+		args = append(args, "-o", quoteArg("UserKnownHostsFile="+s.KnownHostsFile))
+	}
+
+	if s.StrictHostKeyChecking != "" {
+		args = append(args, "-o", "StrictHostKeyChecking="+s.StrictHostKeyChecking)
+	}
+
+	if s.User != "" {
+		host = s.User + "@" + host
+	}
+
+	return strings.Join(append(args, quoteArg(host)), " ")
+}
+
+// validate checks the fields of the tunnel.
+func (s *SSH) validate() error {
+	if s.Host == "" {
+		return errors.New("ssh host is not set")
+	}
+
+	switch s.StrictHostKeyChecking {
+	case "", SSHHostKeyYes, SSHHostKeyNo, SSHHostKeyAcceptNew:
+	default:
+		return fmt.Errorf("unsupported ssh strict_host_key_checking %q", s.StrictHostKeyChecking)
+	}
+
+	return nil
+}
+
+// quoteArg quotes the argument of the proxy command for the shell which
+// runs it. Percent signs are escaped from the placeholder replacement.
+func quoteArg(arg string) string {
+	arg = strings.ReplaceAll(arg, "%", "%%")
+	if safeArg.MatchString(arg) {
+		return arg
+	}
+
+	if runtime.GOOS == "windows" {
+		return `"` + arg + `"`
+	}
+
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Line endings of TELNET commands.
+const (
+	LineEndingCRLF = "crlf"
+	LineEndingLF   = "lf"
+)
+
+// DialectGeneric is the dialect of TELNET consoles which prompt for the
+// password and end the responses by silence.
+const DialectGeneric = "generic"
+
+// TELNETDialect describes the login handshake and the responses of the
+// TELNET console of the game. Empty fields are taken from the preset of
+// Dialect.
+type TELNETDialect struct {
+	// Dialect is the preset the other fields default to: 7dtd or generic.
+	Dialect string `json:"dialect" yaml:"dialect,omitempty"`
+	// Username is sent after LoginPrompt.
+	Username string `json:"username" yaml:"username,omitempty"`
+	// LoginPrompt and PasswordPrompt are regular expressions of the prompts
+	// for the username and the password. The username is not sent if
+	// LoginPrompt is empty.
+	LoginPrompt    string `json:"login_prompt" yaml:"login_prompt,omitempty"`
+	PasswordPrompt string `json:"password_prompt" yaml:"password_prompt,omitempty"`
+	// AuthFailed is the regular expression of the response to the wrong
+	// password.
+	AuthFailed string `json:"auth_failed" yaml:"auth_failed,omitempty"`
+	// PromptRegex is the regular expression of the command prompt which
+	// ends the response. Without it the response ends when the server is
+	// quiet.
+	PromptRegex string `json:"prompt_regex" yaml:"prompt_regex,omitempty"`
+	// LineEnding terminates the commands: crlf or lf.
+	LineEnding string `json:"line_ending" yaml:"line_ending,omitempty"`
+	// SkipBannerLines is the number of lines the server sends after the
+	// login. Without it the banner ends with the prompt or when the server
+	// is quiet.
+	SkipBannerLines int `json:"skip_banner_lines" yaml:"skip_banner_lines,omitempty"`
+}
+
+// TELNETDialects contains the presets of the dialects.
+var TELNETDialects = map[string]TELNETDialect{
+	GameSevenDaysToDie: {
+		PasswordPrompt: `Please enter password:?`,
+		AuthFailed:     `Password incorrect|Too many failed login attempts`,
+		LineEnding:     LineEndingCRLF,
+	},
+	DialectGeneric: {
+		PasswordPrompt: `(?i)password:`,
+		AuthFailed:     `(?i)incorrect|invalid|denied|failed`,
+		LineEnding:     LineEndingCRLF,
+	},
+}
+
+// Resolve returns the dialect with empty fields taken from the preset.
+// Empty Dialect means DialectGeneric.
+func (d TELNETDialect) Resolve() TELNETDialect {
+	if d.Dialect == "" {
+		d.Dialect = DialectGeneric
+	}
+
+	preset := TELNETDialects[d.Dialect]
+
+	for _, field := range []struct{ value, preset *string }{
+		{&d.LoginPrompt, &preset.LoginPrompt},
+		{&d.PasswordPrompt, &preset.PasswordPrompt},
+		{&d.AuthFailed, &preset.AuthFailed},
+		{&d.PromptRegex, &preset.PromptRegex},
+		{&d.LineEnding, &preset.LineEnding},
+	} {
+		if *field.value == "" {
+			*field.value = *field.preset
+		}
+	}
+
+	if d.SkipBannerLines == 0 {
+		d.SkipBannerLines = preset.SkipBannerLines
+	}
+
+	return d
+}
+
+// Terminator returns the bytes which terminate the commands.
+func (d TELNETDialect) Terminator() string {
+	if d.LineEnding == LineEndingLF {
+		return "\n"
+	}
+
+	return "\r\n"
+}
+
+// validate checks the dialect and the regular expressions.
+func (d TELNETDialect) validate() error {
+	if _, ok := TELNETDialects[d.Dialect]; !ok && d.Dialect != "" {
+		dialects := make([]string, 0, len(TELNETDialects))
+		for name := range TELNETDialects {
+			dialects = append(dialects, name)
+		}
+
+		sort.Strings(dialects)
+
+		return fmt.Errorf("unsupported telnet dialect %q, want one of %s", d.Dialect, strings.Join(dialects, ", "))
+	}
+
+	for _, field := range []struct{ key, pattern string }{
+		{"login_prompt", d.LoginPrompt}, {"password_prompt", d.PasswordPrompt},
+		{"auth_failed", d.AuthFailed}, {"prompt_regex", d.PromptRegex},
+	} {
+		if _, err := regexp.Compile(field.pattern); err != nil {
+			return fmt.Errorf("telnet %s: %w", field.key, err)
+		}
+	}
+
+	switch d.LineEnding {
+	case "", LineEndingCRLF, LineEndingLF:
+	default:
+		return fmt.Errorf("unsupported telnet line_ending %q, want %s or %s", d.LineEnding, LineEndingCRLF, LineEndingLF)
+	}
+
+	if d.SkipBannerLines < 0 {
+		return errors.New("telnet skip_banner_lines must not be negative")
+	}
+
+	if d.LoginPrompt != "" && d.Username == "" {
+		return errors.New("telnet username is required by login_prompt")
+	}
+
+	return nil
+}
+
+// validateTELNET checks the dialect of the session.
+func validateTELNET(ses Session) error {
+	if ses.TELNET == nil {
+		return nil
+	}
+
+	if ses.Type != ProtocolTELNET {
+		return fmt.Errorf("telnet dialect is not supported by %q type", ses.Type)
+	}
+
+	return ses.TELNET.validate()
+}
@@ -0,0 +1,284 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrInvalidDuration is returned when duration value cannot be parsed.
+	ErrInvalidDuration = errors.New("invalid duration")
+
+	// ErrInvalidSize is returned when size value cannot be parsed.
+	ErrInvalidSize = errors.New("invalid size")
+)
+
+// DeprecationOutput receives warnings about deprecated config values.
+var DeprecationOutput io.Writer = os.Stderr
+
+// sizeUnits contains multipliers of allowed size units. Decimal units are
+// powers of 1000 and binary units are powers of 1024.
+var sizeUnits = map[string]int64{
+	"":    1,
+	"B":   1,
+	"KB":  1000,
+	"MB":  1000 * 1000,
+	"GB":  1000 * 1000 * 1000,
+	"KIB": 1 << 10,
+	"MIB": 1 << 20,
+	"GIB": 1 << 30,
+}
+
+// maxDurationSeconds is the largest bare integer of seconds which fits in
+// time.Duration.
+const maxDurationSeconds = math.MaxInt64 / int64(time.Second)
+
+// ParseDuration parses duration in Go syntax, e.g. `1m30s`. Bare integers
+// are interpreted as seconds, they are deprecated. Negative durations are
+// rejected.
+func ParseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 || seconds > maxDurationSeconds {
+			return 0, fmt.Errorf("%w %q: seconds must be between 0 and %d", ErrInvalidDuration, value, maxDurationSeconds)
+		}
+
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%w %q: expected value like 30s or 5m", ErrInvalidDuration, value)
+	}
+
+	if d < 0 {
+		return 0, fmt.Errorf("%w %q: duration must not be negative", ErrInvalidDuration, value)
+	}
+
+	return d, nil
+}
+
+// parseNanoseconds parses the JSON number of the legacy config format, which
+// stored durations as integer nanoseconds.
+func parseNanoseconds(value string) (time.Duration, error) {
+	nanoseconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || nanoseconds < 0 {
+		return 0, fmt.Errorf("%w %s: expected nanoseconds or value like \"30s\"", ErrInvalidDuration, value)
+	}
+
+	return time.Duration(nanoseconds), nil
+}
+
+// ParseSize parses size in bytes as an integer with optional unit, e.g.
+// `10MB`, `512KiB` or `4096`.
+func ParseSize(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+
+	i := strings.IndexFunc(value, func(r rune) bool { return r < '0' || r > '9' })
+	if i < 0 {
+		i = len(value)
+	}
+
+	multiplier, ok := sizeUnits[strings.ToUpper(strings.TrimSpace(value[i:]))]
+	if !ok || i == 0 {
+		return 0, fmt.Errorf("%w %q: expected value like 10MB, 512KiB or 4096", ErrInvalidSize, value)
+	}
+
+	number, err := strconv.ParseInt(value[:i], 10, 64)
+	if err != nil || number > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("%w %q: size must be between 0 and %d bytes", ErrInvalidSize, value, int64(math.MaxInt64))
+	}
+
+	return number * multiplier, nil
+}
+
+// isBareInteger reports whether the value is an integer without unit.
+func isBareInteger(value string) bool {
+	_, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+
+	return err == nil
+}
+
+// Duration is time.Duration which is parsed with ParseDuration from config
+// files and flags and is serialized in Go syntax.
+type Duration time.Duration
+
+// String implements flag.Value.
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// Set implements flag.Value. Bare integers produce the deprecation warning,
+// config values are checked by checkUnitField instead.
+func (d *Duration) Set(value string) error {
+	if err := d.set(value); err != nil {
+		return err
+	}
+
+	if isBareInteger(value) {
+		_, _ = fmt.Fprintf(DeprecationOutput, "warning: bare integer %s is deprecated, use %ss\n", value, strings.TrimSpace(value))
+	}
+
+	return nil
+}
+
+// set parses the value with ParseDuration.
+func (d *Duration) set(value string) error {
+	parsed, err := ParseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Numbers are the legacy
+// encoding of time.Duration in nanoseconds.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		nanoseconds, err := parseNanoseconds(string(data))
+		if err != nil {
+			return err
+		}
+
+		*d = Duration(nanoseconds)
+
+		return nil
+	}
+
+	return d.set(value)
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(d).String(), nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	return d.set(value.Value)
+}
+
+// Size is a number of bytes which is parsed with ParseSize from config files
+// and flags.
+type Size int64
+
+// String implements flag.Value.
+func (s Size) String() string {
+	return strconv.FormatInt(int64(s), 10)
+}
+
+// Set implements flag.Value.
+func (s *Size) Set(value string) error {
+	parsed, err := ParseSize(value)
+	if err != nil {
+		return err
+	}
+
+	*s = Size(parsed)
+
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *Size) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	return s.Set(strings.Trim(string(data), `"`))
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (s *Size) UnmarshalYAML(value *yaml.Node) error {
+	return s.Set(value.Value)
+}
+
+// unitFields contains config keys of Session fields with Duration and Size
+// types.
+var unitFields = func() map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+
+	t := reflect.TypeOf(Session{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Type != reflect.TypeOf(Duration(0)) && field.Type != reflect.TypeOf(Size(0)) {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		fields[name] = field.Type
+	}
+
+	return fields
+}()
+
+// checkUnitField validates raw value of the Duration or Size field and warns
+// about deprecated values. Other fields are ignored.
+func checkUnitField(env, key, value string) error {
+	fieldType, ok := unitFields[resolveKeyAlias(key)]
+	if !ok {
+		return nil
+	}
+
+	var err error
+
+	switch fieldType {
+	case reflect.TypeOf(Duration(0)):
+		if _, err = ParseDuration(value); err == nil && isBareInteger(value) {
+			_, _ = fmt.Fprintf(DeprecationOutput,
+				"warning: %s environment: %s: bare integer %s is deprecated, use %ss\n", env, key, value, value)
+		}
+	default:
+		_, err = ParseSize(value)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %s environment: %s: %w", ErrConfigValidation, env, key, err)
+	}
+
+	return nil
+}
+
+// checkJSONUnitField is checkUnitField for raw JSON values. Numbers of
+// Duration fields are the legacy encoding in nanoseconds, they are
+// deprecated.
+func checkJSONUnitField(env, key string, raw json.RawMessage) error {
+	value := strings.TrimSpace(string(raw))
+	if unitFields[resolveKeyAlias(key)] != reflect.TypeOf(Duration(0)) || strings.HasPrefix(value, `"`) || value == "null" {
+		return checkUnitField(env, key, strings.Trim(value, `"`))
+	}
+
+	d, err := parseNanoseconds(value)
+	if err != nil {
+		return fmt.Errorf("%w: %s environment: %s: %w", ErrConfigValidation, env, key, err)
+	}
+
+	_, _ = fmt.Fprintf(DeprecationOutput,
+		"warning: %s environment: %s: number %s in nanoseconds is deprecated, use %q\n", env, key, value, d.String())
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package config_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		err   bool
+	}{
+		{value: "5m", want: 5 * time.Minute},
+		{value: "30s", want: 30 * time.Second},
+		{value: "1m30s", want: 90 * time.Second},
+		{value: "250ms", want: 250 * time.Millisecond},
+		{value: "1h", want: time.Hour},
+		{value: "30", want: 30 * time.Second},
+		{value: " 10 ", want: 10 * time.Second},
+		{value: "0", want: 0},
+		{value: "9223372036", want: 9223372036 * time.Second},
+		{value: "-5", err: true},
+		{value: "9223372037", err: true},
+		{value: "10000000000", err: true},
+		{value: "", err: true},
+		{value: "5x", err: true},
+		{value: "five minutes", err: true},
+		{value: "1.5", err: true},
+		{value: "-5s", err: true},
+		{value: "-1m30s", err: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := config.ParseDuration(tt.value)
+			if tt.err {
+				assert.ErrorIs(t, err, config.ErrInvalidDuration)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+		err   bool
+	}{
+		{value: "4096", want: 4096},
+		{value: "10B", want: 10},
+		{value: "1KB", want: 1000},
+		{value: "10MB", want: 10 * 1000 * 1000},
+		{value: "2GB", want: 2 * 1000 * 1000 * 1000},
+		{value: "512KiB", want: 512 * 1024},
+		{value: "1MiB", want: 1024 * 1024},
+		{value: "1GiB", want: 1024 * 1024 * 1024},
+		{value: "10 mb", want: 10 * 1000 * 1000},
+		{value: "9223372036854775807", want: 9223372036854775807},
+		{value: "8589934591GiB", want: 8589934591 * 1024 * 1024 * 1024},
+		{value: "1.5MiB", err: true},
+		{value: "0.5KB", err: true},
+		{value: "-1KB", err: true},
+		{value: "8589934592GiB", err: true},
+		{value: "9223372036854775808", err: true},
+		{value: "", err: true},
+		{value: "MB", err: true},
+		{value: "10XB", err: true},
+		{value: "1.2.3KB", err: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := config.ParseSize(tt.value)
+			if tt.err {
+				assert.ErrorIs(t, err, config.ErrInvalidSize)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDuration_Set(t *testing.T) {
+	warnings := bytes.Buffer{}
+	config.DeprecationOutput = &warnings
+
+	defer func() { config.DeprecationOutput = os.Stderr }()
+
+	var d config.Duration
+
+	assert.NoError(t, d.Set("1m"))
+	assert.Equal(t, config.Duration(time.Minute), d)
+	assert.Empty(t, warnings.String())
+
+	assert.NoError(t, d.Set("10"))
+	assert.Equal(t, config.Duration(10*time.Second), d)
+	assert.Equal(t, "warning: bare integer 10 is deprecated, use 10s\n", warnings.String())
+
+	assert.ErrorIs(t, d.Set("-5s"), config.ErrInvalidDuration)
+}
+
+func TestConfig_DurationFields(t *testing.T) {
+	warnings := bytes.Buffer{}
+	config.DeprecationOutput = &warnings
+
+	defer func() { config.DeprecationOutput = os.Stderr }()
+
+	tests := []struct {
+		name    string
+		file    string
+		body    string
+		want    time.Duration
+		err     string
+		warning string
+	}{
+		{
+			name: "yaml go syntax",
+			file: "rcon-test-local.yaml",
+			body: "prod:\n  timeout: 5m\n",
+			want: 5 * time.Minute,
+		},
+		{
+			name:    "yaml bare integer",
+			file:    "rcon-test-local.yaml",
+			body:    "prod:\n  timeout: 30\n",
+			want:    30 * time.Second,
+			warning: "warning: prod environment: timeout: bare integer 30 is deprecated, use 30s\n",
+		},
+		{
+			name: "json go syntax",
+			file: "rcon-test-local.json",
+			body: `{"prod": {"timeout": "5m"}}`,
+			want: 5 * time.Minute,
+		},
+		{
+			name:    "json bare integer",
+			file:    "rcon-test-local.json",
+			body:    `{"prod": {"timeout": "30"}}`,
+			want:    30 * time.Second,
+			warning: "warning: prod environment: timeout: bare integer 30 is deprecated, use 30s\n",
+		},
+		{
+			name:    "json legacy nanoseconds",
+			file:    "rcon-test-local.json",
+			body:    `{"prod": {"timeout": 10000000000}}`,
+			want:    10 * time.Second,
+			warning: "warning: prod environment: timeout: number 10000000000 in nanoseconds is deprecated, use \"10s\"\n",
+		},
+		{
+			name: "json negative nanoseconds",
+			file: "rcon-test-local.json",
+			body: `{"prod": {"timeout": -1}}`,
+			err:  `parse file rcon-test-local.json: config validation error: prod environment: timeout: invalid duration -1: expected nanoseconds or value like "30s"`,
+		},
+		{
+			name: "yaml overflow",
+			file: "rcon-test-local.yaml",
+			body: "prod:\n  timeout: 10000000000\n",
+			err:  `parse file rcon-test-local.yaml: config validation error: prod environment: timeout: invalid duration "10000000000": seconds must be between 0 and 9223372036`,
+		},
+		{
+			name: "yaml invalid",
+			file: "rcon-test-local.yaml",
+			body: "prod:\n  kill_timeout: 5x\n",
+			err:  `parse file rcon-test-local.yaml: config validation error: prod environment: kill_timeout: invalid duration "5x": expected value like 30s or 5m`,
+		},
+		{
+			name: "json invalid",
+			file: "rcon-test-local.json",
+			body: `{"prod": {"warn_timeout": "soon"}}`,
+			err:  `parse file rcon-test-local.json: config validation error: prod environment: warn_timeout: invalid duration "soon": expected value like 30s or 5m`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			warnings.Reset()
+
+			createFile(tt.file, tt.body)
+			defer os.Remove(tt.file)
+
+			cfg, err := config.NewConfig(tt.file)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, config.Duration(tt.want), cfg.Config["prod"].Timeout)
+			assert.Equal(t, tt.warning, warnings.String())
+		})
+	}
+}
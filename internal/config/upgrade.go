@@ -0,0 +1,243 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnsupportedSchemaVersion is returned when config file has schema version
+// newer than supported by the application.
+var ErrUnsupportedSchemaVersion = errors.New("unsupported schema version")
+
+// migration upgrades the node tree of the config from the previous schema
+// version in place and returns the list of changes.
+type migration func(doc *document) []string
+
+// migrations contains config migrations. Migration with index i upgrades
+// config from schema version i to i+1.
+var migrations = []migration{
+	migrateToV1,
+}
+
+// Upgrade applies migrations to the config file, writes the upgraded config
+// back and returns the list of changes. Only the migrated values are
+// rewritten, comments and the order of keys are kept. The previous version
+// of the file is kept as a backup. If the file is up to date nothing is
+// written.
+func Upgrade(name string) ([]string, error) {
+	file, err := ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("read file %s: %w", name, err)
+	}
+
+	ext := formatExt(name)
+
+	var doc *document
+
+	switch ext {
+	case ".yml", ".yaml", ".json":
+		doc, err = parseDocument(ext, file)
+	default:
+		err = fmt.Errorf("%w %s", ErrUnsupportedFileExt, ext)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("parse file %s: %w", name, err)
+	}
+
+	if doc == nil {
+		if len(bytes.TrimSpace(file)) > 0 {
+			return nil, fmt.Errorf("parse file %s: config must be a mapping of environments", name)
+		}
+
+		doc = &document{
+			root:   &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}},
+			json:   ext == ".json",
+			indent: detectIndent(nil, ext == ".json"),
+		}
+	}
+
+	version, err := schemaVersion(doc.mapping())
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []string
+
+	for ; version < SchemaVersion; version++ {
+		changes = append(changes, migrations[version](doc)...)
+		changes = append(changes, fmt.Sprintf("%s: %d -> %d", SchemaVersionKey, version, version+1))
+	}
+
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	setSchemaVersion(doc.mapping())
+
+	data, err := doc.bytes()
+	if err != nil {
+		return nil, fmt.Errorf("serialize file %s: %w", name, err)
+	}
+
+	// Make sure the upgraded config is readable and valid before
+	// overwriting the original file.
+	cfg := new(File)
+	if err := cfg.unmarshal(name, data); err != nil {
+		return nil, fmt.Errorf("parse upgraded config: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if data, err = seal(name, data); err != nil {
+		return nil, fmt.Errorf("write file %s: %w", name, err)
+	}
+
+	if err := writeFile(name, data, cfg.BackupCopies()); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// setSchemaVersion sets the schema version of the config to SchemaVersion.
+// The missing key is inserted first and takes over the comment of the file
+// header.
+func setSchemaVersion(mapping *yaml.Node) {
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(SchemaVersion)}
+
+	if node := mappingValue(mapping, SchemaVersionKey); node != nil {
+		*node = *value
+
+		return
+	}
+
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: SchemaVersionKey}
+	if len(mapping.Content) > 0 {
+		key.HeadComment, mapping.Content[0].HeadComment = mapping.Content[0].HeadComment, ""
+	}
+
+	mapping.Content = append([]*yaml.Node{key, value}, mapping.Content...)
+}
+
+// schemaVersion returns schema version from the top level mapping of the
+// config. Config files without the version have version 0.
+func schemaVersion(mapping *yaml.Node) (int, error) {
+	node := mappingValue(mapping, SchemaVersionKey)
+	if node == nil {
+		return 0, nil
+	}
+
+	var version int
+
+	switch node.ShortTag() {
+	case "!!int":
+		v, err := strconv.Atoi(node.Value)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %s", ErrUnsupportedSchemaVersion, node.Value)
+		}
+
+		version = v
+	case "!!float":
+		v, err := strconv.ParseFloat(node.Value, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %s", ErrUnsupportedSchemaVersion, node.Value)
+		}
+
+		version = int(v)
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrUnsupportedSchemaVersion, node.Value)
+	}
+
+	if version < 0 || version > SchemaVersion {
+		return 0, fmt.Errorf("%w: %d", ErrUnsupportedSchemaVersion, version)
+	}
+
+	return version, nil
+}
+
+// migrateToV1 standardizes protocol types and converts deprecated integer
+// timeouts to durations: seconds of YAML files and legacy nanoseconds of
+// JSON files.
+func migrateToV1(doc *document) []string {
+	var changes []string
+
+	mapping := doc.mapping()
+	envs := make([]int, 0, len(mapping.Content)/2)
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		envs = append(envs, i)
+	}
+
+	sort.Slice(envs, func(i, j int) bool {
+		return mapping.Content[envs[i]].Value < mapping.Content[envs[j]].Value
+	})
+
+	for _, i := range envs {
+		env, ses := mapping.Content[i].Value, mapping.Content[i+1]
+		if ses.Kind != yaml.MappingNode {
+			continue
+		}
+
+		if node := mappingValue(ses, "type"); node != nil && node.ShortTag() == "!!str" {
+			value := node.Value
+
+			standard := strings.ToLower(strings.TrimSpace(value))
+			if standard == "webrcon" {
+				standard = ProtocolWebRCON
+			}
+
+			if standard != value {
+				node.Value = standard
+				changes = append(changes, fmt.Sprintf("%s: type %q -> %q", env, value, standard))
+			}
+		}
+
+		for _, field := range []string{"timeout", "warn_timeout", "kill_timeout"} {
+			node := mappingValue(ses, field)
+			if node == nil || node.Kind != yaml.ScalarNode {
+				continue
+			}
+
+			var duration time.Duration
+			var from string
+
+			switch tag := node.ShortTag(); {
+			case !doc.json && tag == "!!int":
+				// Bare integers of YAML files are seconds.
+				value, err := strconv.ParseInt(node.Value, 0, 64)
+				if err != nil || value < 0 || value > maxDurationSeconds {
+					continue
+				}
+
+				duration, from = time.Duration(value)*time.Second, strconv.FormatInt(value, 10)
+			case doc.json && (tag == "!!int" || tag == "!!float"):
+				// JSON numbers are the legacy encoding of time.Duration in
+				// nanoseconds.
+				value, err := strconv.ParseFloat(node.Value, 64)
+				if err != nil || value < 0 || value >= math.MaxInt64 || value != math.Trunc(value) {
+					continue
+				}
+
+				duration, from = time.Duration(value), fmt.Sprintf("%d nanoseconds", int64(value))
+			default:
+				continue
+			}
+
+			node.Value, node.Tag, node.Style = duration.String(), "!!str", 0
+			changes = append(changes, fmt.Sprintf("%s: %s %s -> %q", env, field, from, duration.String()))
+		}
+	}
+
+	return changes
+}
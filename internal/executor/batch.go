@@ -0,0 +1,100 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// StdinScript is the --command-file value which reads commands from stdin.
+const StdinScript = "-"
+
+// Variable errors.
+var (
+	// ErrInvalidVariable is returned when --var value does not match
+	// name=value format.
+	ErrInvalidVariable = errors.New("invalid variable: expected name=value")
+
+	// ErrUndefinedVariable is returned when the command file references the
+	// variable which is not set with --var.
+	ErrUndefinedVariable = errors.New("undefined variable")
+)
+
+// varName matches the name of the variable, varRef matches ${name}
+// reference in the command file.
+var (
+	varName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	varRef  = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+)
+
+// readCommands reads commands from the reader. Empty lines and lines
+// starting with # are skipped.
+func readCommands(r io.Reader) ([]string, error) {
+	if r == nil {
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("command file: %w", err)
+	}
+
+	return scriptLines(string(data)), nil
+}
+
+// scriptLines returns the commands of the command file text.
+func scriptLines(text string) []string {
+	var commands []string
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			commands = append(commands, line)
+		}
+	}
+
+	return commands
+}
+
+// parseVars parses --var values in name=value format.
+func parseVars(values []string) (map[string]string, error) {
+	vars := make(map[string]string, len(values))
+
+	for _, value := range values {
+		name, v, ok := strings.Cut(value, "=")
+		if !ok || !varName.MatchString(name) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidVariable, value)
+		}
+
+		vars[name] = v
+	}
+
+	return vars, nil
+}
+
+// substituteVars replaces ${name} references in the commands with the
+// values of the variables. Every reference must be defined.
+func substituteVars(commands []string, vars map[string]string) ([]string, error) {
+	var undefined []string
+
+	for i, command := range commands {
+		commands[i] = varRef.ReplaceAllStringFunc(command, func(ref string) string {
+			name := ref[2 : len(ref)-1]
+
+			value, ok := vars[name]
+			if !ok {
+				undefined = append(undefined, name)
+			}
+
+			return value
+		})
+
+		if len(undefined) > 0 {
+			return nil, fmt.Errorf("command file: %w: %s", ErrUndefinedVariable, strings.Join(undefined, ", "))
+		}
+	}
+
+	return commands, nil
+}
@@ -0,0 +1,112 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/changes"
+	"github.com/gorcon/rcon-cli/internal/config"
+	"github.com/urfave/cli/v2"
+)
+
+// recordChange appends the command to the changes file of the environment
+// if it matches record_changes patterns of the session.
+func (executor *Executor) recordChange(ses *config.Session, seq int, command string, response string) error {
+	matched := false
+
+	for _, re := range executor.record {
+		if re.MatchString(command) {
+			matched = true
+
+			break
+		}
+	}
+
+	if !matched {
+		return nil
+	}
+
+	env := ses.Env
+	if env == "" {
+		env = config.DefaultConfigEnv
+	}
+
+	name, err := changes.DefaultFile(env)
+	if err != nil {
+		return err
+	}
+
+	keep := time.Duration(ses.ChangesKeep)
+	if keep == 0 {
+		keep = config.DefaultChangesKeep
+	}
+
+	return changes.Append(name, changes.Entry{
+		Time:     time.Now(),
+		User:     currentUser(),
+		Env:      env,
+		RunID:    executor.run.id,
+		Seq:      seq,
+		Command:  executor.redactor.Redact(command),
+		Response: changes.Excerpt(response),
+	}, keep)
+}
+
+// changes prints recorded state-changing commands of the environment with
+// undo hints from the config.
+func (executor *Executor) changes(c *cli.Context) error {
+	env := c.String("env")
+
+	var hints map[string]string
+
+	if cfg, err := config.NewConfig(c.String("config")); err == nil {
+		ses, _ := cfg.GetEnv(env)
+		hints = ses.UndoHint
+	}
+
+	name, err := changes.DefaultFile(env)
+	if err != nil {
+		return fmt.Errorf("changes: %w", err)
+	}
+
+	var since time.Time
+	if d := durationFlag(c, "since"); d > 0 {
+		since = time.Now().Add(-time.Duration(d))
+	}
+
+	entries, err := changes.Read(name, since)
+	if err != nil {
+		return fmt.Errorf("changes: %w", err)
+	}
+
+	if len(entries) == 0 {
+		_, _ = fmt.Fprintf(executor.w, "No changes recorded for %s environment\n", env)
+
+		return nil
+	}
+
+	for _, e := range entries {
+		_, _ = fmt.Fprintf(executor.w, "[%s] %s: %s\n", e.Time.Local().Format(time.DateTime), e.User, e.Command)
+
+		if e.Response != "" {
+			_, _ = fmt.Fprintf(executor.w, "  response: %s\n", e.Response)
+		}
+
+		if undo := changes.UndoHint(hints, e.Command); undo != "" {
+			_, _ = fmt.Fprintf(executor.w, "  undo: %s\n", undo)
+		}
+	}
+
+	return nil
+}
+
+// currentUser returns name of the user who runs the CLI.
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+
+	return os.Getenv("USER")
+}
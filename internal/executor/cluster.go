@@ -0,0 +1,75 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+)
+
+// Dial sends auth request for remote server. Returns en error if
+// address or password is incorrect. Addresses of the cluster are tried in
+// order and the session address is set to the connected one.
+func (executor *Executor) Dial(ses *config.Session) error {
+	if executor.client != nil || len(ses.Addresses) == 0 {
+		return executor.dial(ses)
+	}
+
+	addresses := ses.Addresses
+	if executor.primary != "" {
+		addresses = []string{executor.primary}
+	}
+
+	errs := make([]error, 0, len(addresses))
+
+	for _, address := range addresses {
+		ses.Address = address
+
+		err := executor.dial(ses)
+		if err == nil {
+			if ses.ClusterMode == config.ClusterModePrimary {
+				executor.primary = address
+			}
+
+			return nil
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", address, err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// broadcast executes commands on every address of the cluster over its own
+// connection. Responses of every address follow the address in brackets.
+// Failed addresses do not stop the broadcast, their errors are returned
+// together.
+func (executor *Executor) broadcast(w io.Writer, ses *config.Session, commands ...string) error {
+	var errs []error
+
+	for i, address := range ses.Addresses {
+		if i > 0 {
+			_, _ = fmt.Fprintln(w, CommandsResponseSeparator)
+		}
+
+		_, _ = fmt.Fprintf(w, "[%s]\n", address)
+
+		addrSes := *ses
+		addrSes.Address, addrSes.Addresses = address, nil
+
+		exec := NewExecutor(nil, w, executor.version)
+		exec.ew = executor.ew
+		// Commands of all addresses are numbered in the same run.
+		exec.run = executor.run
+		exec.expect = executor.expect
+
+		if err := exec.Execute(w, &addrSes, commands...); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", address, err))
+		}
+
+		_ = exec.Close()
+	}
+
+	return errors.Join(errs...)
+}
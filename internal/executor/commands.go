@@ -0,0 +1,1182 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+	"github.com/gorcon/rcon-cli/internal/redact"
+	"github.com/gorcon/rcon-cli/internal/suite"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Command errors.
+var (
+	// ErrNoConfigFile is returned when the command modifies config file, but
+	// no config file was found.
+	ErrNoConfigFile = errors.New("config file is not found: to set config file add -c path")
+
+	// ErrSuiteFailed is returned when at least one suite case failed.
+	ErrSuiteFailed = errors.New("test suite failed")
+
+	// ErrUnsupportedOutput is returned when output format is not supported.
+	ErrUnsupportedOutput = errors.New("unsupported output format")
+
+	// ErrConfigNotSorted is returned by config sort --check when the config
+	// file is not in canonical order.
+	ErrConfigNotSorted = errors.New("config file is not sorted: run config sort to fix it")
+
+	// ErrReadOnlyConfig is returned when the command modifies config file
+	// loaded from a Git repository.
+	ErrReadOnlyConfig = errors.New("config from git repository is read-only: commit the changes to the repository")
+)
+
+// Output formats. Executed commands are printed as JSON lines with
+// OutputJSON.
+const (
+	OutputText      = "text"
+	OutputJSON      = "json"
+	OutputJSONArray = "json-array"
+	OutputYAML      = "yaml"
+)
+
+// envInfo is the environment in --list-env output. Credentials are never
+// listed.
+type envInfo struct {
+	Name        string `json:"name"`
+	Address     string `json:"address"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Owner       string `json:"owner"`
+}
+
+// getCommands returns CLI subcommands.
+func (executor *Executor) getCommands() []*cli.Command {
+	return []*cli.Command{
+		{
+			Name:      "test",
+			Usage:     "Execute response assertion suite and report results",
+			ArgsUsage: "suite.yaml",
+			Action:    executor.test,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "output",
+					Aliases: []string{"o"},
+					Usage:   "Results format: tap or json",
+					Value:   "tap",
+				},
+			},
+		},
+		{
+			Name:   "run",
+			Usage:  "Execute the script of RCON commands",
+			Action: executor.runScript,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "script",
+					Usage:    "Path to the script file",
+					Required: true,
+				},
+			},
+		},
+		{
+			Name:   "changes",
+			Usage:  "Print recorded state-changing commands of the environment",
+			Action: executor.changes,
+			Flags: []cli.Flag{
+				&cli.GenericFlag{
+					Name:  "since",
+					Value: durationValue(0),
+					Usage: "Print only changes recorded within the duration. Example 24h",
+				},
+			},
+		},
+		{
+			Name: "healthcheck",
+			Usage: "Authenticate and execute the ping command, ready_command by default. Exit code tells " +
+				"connect failure (2), auth failure (3), timeout (4) and expectation mismatch (5) apart",
+			Action: executor.healthcheck,
+			Flags: []cli.Flag{
+				&cli.StringFlag{Name: "command", Usage: "Ping command, the check is auth only without it"},
+			},
+		},
+		{
+			Name:    "listen",
+			Aliases: []string{"tail"},
+			Usage: "Keep the connection open and print the server output until ^C or :q, commands read from stdin " +
+				"are sent to the server. Supported by telnet, web and pterodactyl types",
+			Action: executor.listen,
+			Flags: []cli.Flag{
+				&cli.StringFlag{Name: "filter", Usage: "Print only the lines which match the regular expression"},
+				&cli.BoolFlag{Name: "filter-invert", Usage: "Print only the lines which do not match --filter"},
+			},
+		},
+		{
+			Name: "export",
+			Usage: "Collect metrics of the environments selected by --env pattern or of all environments with " +
+				"metrics or metrics_preset and serve them as Prometheus gauges at /metrics",
+			Action: executor.export,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "listen",
+					Usage: "Address of the metrics endpoint",
+					Value: DefaultExportListen,
+				},
+				&cli.GenericFlag{
+					Name:  "interval",
+					Usage: "Interval between collections. Example 30s",
+					Value: durationValue(DefaultExportInterval),
+				},
+				&cli.BoolFlag{Name: "once", Usage: "Collect the metrics once and print them instead of serving"},
+			},
+		},
+		{
+			Name:  "sessions",
+			Usage: "Inspect and close the connections kept by the broker of --pool",
+			Subcommands: []*cli.Command{
+				{
+					Name:   "list",
+					Usage:  "Print the pooled sessions. Supports --output text and json",
+					Action: executor.sessionsList,
+				},
+				{
+					Name:      "close",
+					Usage:     "Close the sessions of the environments, all sessions and the broker without arguments",
+					ArgsUsage: "[env...]",
+					Action:    executor.sessionsClose,
+				},
+				{
+					Name:   "broker",
+					Usage:  "Serve the pooled connections on the unix socket, started by --pool",
+					Hidden: true,
+					Action: executor.poolBroker,
+					Flags: []cli.Flag{
+						&cli.GenericFlag{
+							Name:  "idle",
+							Usage: "Close the connections unused for the duration and stop without connections",
+							Value: durationValue(DefaultPoolIdle),
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "completion",
+			Usage: "Print the shell completion script of commands, flags and environment names of --env",
+			Subcommands: []*cli.Command{
+				{Name: "bash", Usage: "Print bash completion script", Action: executor.completion("bash")},
+				{Name: "zsh", Usage: "Print zsh completion script", Action: executor.completion("zsh")},
+				{Name: "fish", Usage: "Print fish completion script", Action: executor.completion("fish")},
+				{Name: "powershell", Usage: "Print PowerShell completion script", Action: executor.completion("powershell")},
+				{
+					Name:   "envs",
+					Usage:  "Print environment names of the config file for the completion scripts",
+					Hidden: true,
+					Action: executor.completionEnvs,
+				},
+			},
+		},
+		{
+			Name: "query",
+			Usage: "Print server info, players and rules over Source query protocol (A2S) without the password. " +
+				"Address defaults to query_address or address of the environment",
+			ArgsUsage: "[host:port]",
+			Action:    executor.query,
+			Flags: []cli.Flag{
+				&cli.BoolFlag{Name: "info", Usage: "Print server info, the default without other flags"},
+				&cli.BoolFlag{Name: "players", Usage: "Print players"},
+				&cli.BoolFlag{Name: "rules", Usage: "Print server rules"},
+			},
+		},
+		{
+			Name: "serve",
+			Usage: "Serve HTTP API which executes commands on the config environments over persistent connections: " +
+				"GET /env lists environments, POST /env/{name}/exec with {\"commands\": [...]} executes commands",
+			Action: executor.serve,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "listen",
+					Usage: "Address of the HTTP API",
+					Value: DefaultServeListen,
+				},
+				&cli.StringFlag{
+					Name:    "token",
+					Usage:   "Require the token in Authorization: Bearer header of requests",
+					EnvVars: []string{"RCON_SERVE_TOKEN"},
+				},
+			},
+		},
+		{
+			Name: "secret",
+			Usage: "Manage passwords in the OS keyring. Without the reference password_keyring of --env environment " +
+				"or " + config.DefaultKeyringService + "/<env> is used",
+			Subcommands: []*cli.Command{
+				{
+					Name:      "set",
+					Usage:     "Read the password from stdin and store it in the keyring",
+					ArgsUsage: "[service/account]",
+					Action:    executor.secretSet,
+				},
+				{
+					Name:      "get",
+					Usage:     "Print the password stored in the keyring",
+					ArgsUsage: "[service/account]",
+					Action:    executor.secretGet,
+				},
+				{
+					Name:      "delete",
+					Usage:     "Remove the password from the keyring",
+					ArgsUsage: "[service/account]",
+					Action:    executor.secretDelete,
+				},
+			},
+		},
+		{
+			Name:  "config",
+			Usage: "Inspect and manage the configuration file",
+			Subcommands: []*cli.Command{
+				{
+					Name:   "which",
+					Usage:  "Print which config file was loaded",
+					Action: executor.configWhich,
+				},
+				{
+					Name:   "list",
+					Usage:  "Print environments of the config file in --output format",
+					Action: executor.configList,
+				},
+				{
+					Name:      "show",
+					Usage:     "Print fields of the environment as they are stored in the config file",
+					ArgsUsage: "env",
+					Action:    executor.configShow,
+					Flags: []cli.Flag{
+						&cli.BoolFlag{
+							Name:  "reveal",
+							Usage: "Print password and secrets instead of the mask",
+						},
+					},
+				},
+				{
+					Name:      "add",
+					Usage:     "Add the environment to the config file. The file is created if it does not exist",
+					ArgsUsage: "env",
+					Action:    executor.configAdd,
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:     "address",
+							Usage:    "Remote host and port of the environment",
+							Required: true,
+						},
+						&cli.StringFlag{Name: "password", Usage: "Password of the environment"},
+						&cli.StringFlag{Name: "type", Usage: "Protocol type of the environment"},
+						&cli.StringFlag{Name: "log", Usage: "Log file of the environment"},
+						&cli.StringFlag{Name: "description", Usage: "Description of the environment"},
+						&cli.StringFlag{Name: "owner", Usage: "Owner of the environment"},
+						&cli.BoolFlag{
+							Name:  "test",
+							Usage: "Connect to the server and save the environment only if the connection succeeds",
+						},
+						&cli.BoolFlag{
+							Name:  "force",
+							Usage: "Replace the environment if it exists",
+						},
+					},
+				},
+				{
+					Name:      "remove",
+					Usage:     "Remove the environment from the config file",
+					ArgsUsage: "env",
+					Action:    executor.configRemove,
+					Flags: []cli.Flag{
+						&cli.BoolFlag{
+							Name:  "force",
+							Usage: "Remove the environment without asking",
+						},
+					},
+				},
+				{
+					Name:   "upgrade",
+					Usage:  "Migrate the config file to the current schema version",
+					Action: executor.configUpgrade,
+				},
+				{
+					Name:   "encrypt",
+					Usage:  "Write the encrypted copy of the config file, which can be committed without leaking credentials",
+					Action: executor.configEncrypt,
+					Flags: []cli.Flag{
+						&cli.StringFlag{Name: "out", Usage: "Encrypted file. Defaults to the config file with .enc extension"},
+					},
+				},
+				{
+					Name:   "decrypt",
+					Usage:  "Write the plain copy of the encrypted config file",
+					Action: executor.configDecrypt,
+					Flags: []cli.Flag{
+						&cli.StringFlag{Name: "out", Usage: "Plain file. Defaults to the config file without .enc extension"},
+					},
+				},
+				{
+					Name:   "copy",
+					Usage:  "Copy the environment with optional overrides in the config file",
+					Action: executor.configCopy,
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:     "from",
+							Usage:    "Environment to copy",
+							Required: true,
+						},
+						&cli.StringFlag{
+							Name:     "to",
+							Usage:    "New environment name",
+							Required: true,
+						},
+						&cli.StringFlag{Name: "address", Usage: "Override host and port of the new environment"},
+						&cli.StringFlag{Name: "password", Usage: "Override password of the new environment"},
+						&cli.StringFlag{Name: "type", Usage: "Override protocol type of the new environment"},
+						&cli.StringFlag{Name: "log", Usage: "Override log file of the new environment"},
+						&cli.BoolFlag{
+							Name:  "force",
+							Usage: "Replace the environment with the new name without asking if it exists",
+						},
+					},
+				},
+				{
+					Name:   "rename",
+					Usage:  "Rename the environment in the config file",
+					Action: executor.configRename,
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:     "from",
+							Usage:    "Current environment name",
+							Required: true,
+						},
+						&cli.StringFlag{
+							Name:     "to",
+							Usage:    "New environment name",
+							Required: true,
+						},
+						&cli.BoolFlag{
+							Name:  "force",
+							Usage: "Replace the environment with the new name if it exists",
+						},
+					},
+				},
+				{
+					Name:   "import",
+					Usage:  "Merge environments of another config file with the prefix prepended to their names",
+					Action: executor.configImport,
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:     "from",
+							Usage:    "Config file to import",
+							Required: true,
+						},
+						&cli.StringFlag{
+							Name:  "prefix",
+							Usage: "Prefix of the imported environment names",
+						},
+						&cli.BoolFlag{
+							Name:  "force",
+							Usage: "Replace existing environments without asking",
+						},
+					},
+				},
+				{
+					Name:   "export",
+					Usage:  "Write environments with the prefix to another config file with the prefix removed",
+					Action: executor.configExport,
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:     "to",
+							Usage:    "Config file to write",
+							Required: true,
+						},
+						&cli.StringFlag{
+							Name:     "prefix",
+							Usage:    "Prefix of the exported environment names",
+							Required: true,
+						},
+					},
+				},
+				{
+					Name:   "prune",
+					Usage:  "Remove environments which have been unreachable for longer than --max-age",
+					Action: executor.configPrune,
+					Flags: []cli.Flag{
+						&cli.GenericFlag{
+							Name:  "max-age",
+							Value: durationValue(config.DefaultPruneMaxAge),
+							Usage: "How long the environment must be unreachable to be removed",
+						},
+						&cli.BoolFlag{
+							Name:  "dry-run",
+							Usage: "Print the environments to remove without changing the config file",
+						},
+					},
+				},
+				{
+					Name:   "env",
+					Usage:  "Print resolved fields of the environment set in --env as shell exports",
+					Action: executor.configEnv,
+					Flags: []cli.Flag{
+						&cli.BoolFlag{
+							Name:  "reveal",
+							Usage: "Print password and secrets instead of the mask",
+						},
+					},
+				},
+				{
+					Name:   "stats",
+					Usage:  "Print aggregate statistics about the config environments",
+					Action: executor.configStats,
+				},
+				{
+					Name:   "sort",
+					Usage:  "Rewrite the config file in canonical order: default environment first, then alphabetically",
+					Action: executor.configSort,
+					Flags: []cli.Flag{
+						&cli.BoolFlag{
+							Name:  "check",
+							Usage: "Fail if the config file is not sorted instead of rewriting it",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// configWhich prints the paths to the files the config was loaded from.
+func (executor *Executor) configWhich(c *cli.Context) error {
+	cfg, err := config.NewConfig(c.String("config"))
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	for _, source := range cfg.Sources() {
+		_, _ = fmt.Fprintln(executor.w, source)
+	}
+
+	return nil
+}
+
+// configStats prints aggregate statistics about the config environments.
+func (executor *Executor) configStats(c *cli.Context) error {
+	cfg, err := config.NewConfig(c.String("config"))
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	stats := cfg.Stats()
+
+	_, _ = fmt.Fprintf(executor.w, "Environments: %d\n", stats.TotalEnvs)
+
+	protocols := make([]string, 0, len(stats.EnvsByType))
+	for protocol := range stats.EnvsByType {
+		protocols = append(protocols, protocol)
+	}
+
+	sort.Strings(protocols)
+
+	for _, protocol := range protocols {
+		_, _ = fmt.Fprintf(executor.w, "  %s: %d\n", protocol, stats.EnvsByType[protocol])
+	}
+
+	_, _ = fmt.Fprintf(executor.w, "With tags: %d\n", stats.EnvsWithTags)
+	_, _ = fmt.Fprintf(executor.w, "With timeout: %d\n", stats.EnvsWithTimeout)
+	_, _ = fmt.Fprintf(executor.w, "With TLS: %d\n", stats.EnvsWithTLS)
+
+	return nil
+}
+
+// configUpgrade applies schema migrations to the config file and prints the
+// list of changes.
+func (executor *Executor) configUpgrade(c *cli.Context) error {
+	name, err := configFile(c)
+	if err != nil {
+		return err
+	}
+
+	changes, err := config.Upgrade(name)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	if len(changes) == 0 {
+		_, _ = fmt.Fprintf(executor.w, "Config %s is up to date\n", name)
+
+		return nil
+	}
+
+	_, _ = fmt.Fprintf(executor.w, "Upgraded config %s (previous version saved to %s):\n", name, name+config.BackupFileExt)
+
+	for _, change := range changes {
+		_, _ = fmt.Fprintf(executor.w, "  %s\n", change)
+	}
+
+	return nil
+}
+
+// configEncrypt writes the encrypted copy of the config file. The plain
+// file is kept, so the result can be checked before removing it.
+func (executor *Executor) configEncrypt(c *cli.Context) error {
+	name, err := configFile(c)
+	if err != nil {
+		return err
+	}
+
+	out := c.String("out")
+	if out == "" {
+		out = name + config.EncryptedFileExt
+	}
+
+	if err := config.EncryptFile(name, out); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(executor.w, "Encrypted config %s to %s, remove the plain file after checking the result\n", name, out)
+
+	return nil
+}
+
+// configDecrypt writes the plain copy of the encrypted config file.
+func (executor *Executor) configDecrypt(c *cli.Context) error {
+	name, err := configFile(c)
+	if err != nil {
+		return err
+	}
+
+	out := c.String("out")
+	if out == "" {
+		out = strings.TrimSuffix(name, config.EncryptedFileExt)
+	}
+
+	if err := config.DecryptFile(name, out); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(executor.w, "Decrypted config %s to %s\n", name, out)
+
+	return nil
+}
+
+// configEnv prints every field of the session the environment resolves to,
+// the same way as it is used for connection, as shell exports.
+func (executor *Executor) configEnv(c *cli.Context) error {
+	ses, err := executor.NewSession(c)
+	if err != nil {
+		return err
+	}
+
+	if !c.Bool("reveal") {
+		maskSecrets(ses)
+	}
+
+	t := reflect.TypeOf(*ses)
+	v := reflect.ValueOf(*ses)
+
+	for i := 0; i < t.NumField(); i++ {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if key == "-" || key == "" {
+			continue
+		}
+
+		data, err := json.Marshal(v.Field(i).Interface())
+		if err != nil {
+			return fmt.Errorf("config: %s: %w", key, err)
+		}
+
+		// Strings and units are printed as is, other values as JSON.
+		value := string(data)
+
+		switch field := v.Field(i).Interface().(type) {
+		case string:
+			value = field
+		case fmt.Stringer:
+			value = field.String()
+		}
+
+		_, _ = fmt.Fprintf(executor.w, "export RCON_%s=%s\n", strings.ToUpper(key), shellQuote(value))
+	}
+
+	return nil
+}
+
+// maskSecrets replaces the password, request_hmac_secret and the proxy
+// credentials of the printed session with redact.Mask.
+func maskSecrets(ses *config.Session) {
+	for _, secret := range []*string{&ses.Password, &ses.RequestHMACSecret} {
+		if *secret != "" {
+			*secret = redact.Mask
+		}
+	}
+
+	ses.Proxy = config.RedactProxy(ses.Proxy, redact.Mask)
+}
+
+// shellQuote quotes the value for POSIX shell.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// configSort reorders environments of the config file. The file is not
+// touched if it is already sorted, formatting and comments are not
+// compared.
+func (executor *Executor) configSort(c *cli.Context) error {
+	name, err := configFile(c)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.NewConfig(name)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	if cfg.IsSorted() {
+		_, _ = fmt.Fprintf(executor.w, "Config %s is sorted\n", name)
+
+		return nil
+	}
+
+	if c.Bool("check") {
+		return fmt.Errorf("%w: %s", ErrConfigNotSorted, name)
+	}
+
+	cfg.Sort()
+
+	if err := cfg.WriteToFile(name); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(executor.w, "Sorted config %s\n", name)
+
+	return nil
+}
+
+// configRename renames the environment and saves the config file. The
+// previous version of the file is kept as a backup.
+func (executor *Executor) configRename(c *cli.Context) error {
+	name, err := configFile(c)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.NewConfig(name)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	from, to := c.String("from"), c.String("to")
+	if err := cfg.Rename(from, to, c.Bool("force")); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	if err := cfg.WriteToFile(name); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(executor.w, "Renamed %s environment to %s in %s\n", from, to, name)
+
+	// Rename is recorded in the log of the environment for the audit.
+	if ses, err := cfg.GetEnv(to); err == nil {
+		ses.Env = to
+		if err := executor.log(&ses, RenameLogRequest, fmt.Sprintf("%s -> %s in %s", from, to, name)); err != nil {
+			_, _ = fmt.Fprintln(executor.ew, fmt.Errorf("log: %w", err))
+		}
+	}
+
+	return nil
+}
+
+// configImport merges environments of another config file into the config
+// file with the prefix prepended to their names. Existing environments are
+// replaced after confirmation in terminal or with --force.
+func (executor *Executor) configImport(c *cli.Context) error {
+	name, err := configFile(c)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.NewConfig(name)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	other, err := config.NewConfig(c.String("from"))
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	var replace func(string) bool
+
+	switch {
+	case c.Bool("force"):
+		replace = func(string) bool { return true }
+	case isTerminal(executor.r):
+		r := bufio.NewReader(executor.r)
+		replace = func(env string) bool {
+			return confirm(r, executor.w, fmt.Sprintf("Environment %s exists. Replace it?", env), false)
+		}
+	}
+
+	imported, err := cfg.Import(other.Config, c.String("prefix"), replace)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	if err := cfg.WriteToFile(name); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(executor.w, "Imported %d environments from %s to %s\n", len(imported), c.String("from"), name)
+
+	for _, env := range imported {
+		_, _ = fmt.Fprintln(executor.w, "  "+env)
+	}
+
+	return nil
+}
+
+// configExport writes environments with the prefix to another config file
+// with the prefix removed from their names.
+func (executor *Executor) configExport(c *cli.Context) error {
+	cfg, err := config.NewConfig(c.String("config"))
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	to, prefix := c.String("to"), c.String("prefix")
+
+	exported := config.File{Config: cfg.Export(prefix)}
+	if len(exported.Config) == 0 {
+		return fmt.Errorf("config: %w: no environments with prefix %s", config.ErrSessionNotFound, prefix)
+	}
+
+	if err := exported.WriteToFile(to); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(executor.w, "Exported %d environments with prefix %s to %s\n", len(exported.Config), prefix, to)
+
+	return nil
+}
+
+// configPrune removes environments which have been unreachable for longer
+// than --max-age from the config file.
+func (executor *Executor) configPrune(c *cli.Context) error {
+	name, err := configFile(c)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.NewConfig(name)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	removed, err := cfg.Prune(time.Duration(durationFlag(c, "max-age")))
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	if len(removed) == 0 {
+		_, _ = fmt.Fprintln(executor.w, "No environments to prune")
+
+		return nil
+	}
+
+	verb := "Would remove"
+	if !c.Bool("dry-run") {
+		if err := cfg.WriteToFile(name); err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+
+		verb = "Removed"
+	}
+
+	for _, env := range removed {
+		_, _ = fmt.Fprintf(executor.w, "%s %s environment\n", verb, env)
+	}
+
+	return nil
+}
+
+// listEnv prints environments of the config file with their notes.
+func (executor *Executor) listEnv(c *cli.Context) error {
+	cfg, err := config.NewConfig(c.String("config"))
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	envs := make([]envInfo, 0, len(cfg.Config))
+	for _, name := range cfg.Names() {
+		// The config is validated, so inheritance is resolved.
+		ses, _ := cfg.GetEnv(name)
+		envs = append(envs, envInfo{
+			Name: name, Address: ses.Address, Type: ses.Type, Description: ses.Description, Owner: ses.Owner,
+		})
+	}
+
+	switch output := c.String("output"); output {
+	case OutputText:
+		tw := tabwriter.NewWriter(executor.w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "ENV\tADDRESS\tTYPE\tOWNER\tDESCRIPTION")
+
+		for _, env := range envs {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", env.Name, env.Address, env.Type, env.Owner, env.Description)
+		}
+
+		return tw.Flush()
+	case OutputJSON:
+		encoder := json.NewEncoder(executor.w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(envs)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedOutput, output)
+	}
+}
+
+// configCopy copies the environment, applies overrides from flags and saves
+// the config file. Existing environment is replaced only after confirmation
+// in terminal or with --force flag.
+func (executor *Executor) configCopy(c *cli.Context) error {
+	name, err := configFile(c)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.NewConfig(name)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	from, to, force := c.String("from"), c.String("to"), c.Bool("force")
+	if cfg.HasEnv(from) && cfg.HasEnv(to) && !force && isTerminal(executor.r) {
+		force = confirm(bufio.NewReader(executor.r), executor.w, fmt.Sprintf("Environment %s exists. Replace it?", to), false)
+	}
+
+	if err := cfg.Copy(from, to, force); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	ses := cfg.Config[to]
+	for flag, field := range map[string]*string{
+		"address": &ses.Address, "password": &ses.Password, "type": &ses.Type, "log": &ses.Log,
+	} {
+		if c.IsSet(flag) {
+			*field = c.String(flag)
+		}
+	}
+
+	// Password from flag replaces the other password sources.
+	if c.IsSet("password") {
+		ses.PasswordEnv, ses.PasswordFile, ses.PasswordKeyring = "", "", ""
+	}
+
+	cfg.Config[to] = ses
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	if err := cfg.WriteToFile(name); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(executor.w, "Copied %s environment to %s in %s\n", from, to, name)
+
+	return nil
+}
+
+// configList prints environments of the config file. The path to the file
+// is printed to stderr, so the output can be parsed.
+func (executor *Executor) configList(c *cli.Context) error {
+	cfg, err := config.NewConfig(c.String("config"))
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(executor.ew, "Config: %s\n", strings.Join(cfg.Sources(), ", "))
+
+	return executor.listEnv(c)
+}
+
+// configShow prints fields of the environment as YAML. The password and
+// secrets are masked unless --reveal is set.
+func (executor *Executor) configShow(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("%w: expected environment name", ErrCommandEmpty)
+	}
+
+	cfg, err := config.NewConfig(c.String("config"))
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	ses, err := cfg.GetEnv(c.Args().First())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	if !c.Bool("reveal") {
+		maskSecrets(&ses)
+	}
+
+	data, err := yaml.Marshal(ses)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(executor.w, "# %s\n%s", strings.Join(cfg.Sources(), ", "), data)
+
+	return nil
+}
+
+// configAdd adds the environment from flags to the config file. The
+// connection is tested before saving with --test flag.
+func (executor *Executor) configAdd(c *cli.Context) error {
+	env := c.Args().First()
+	if c.NArg() != 1 {
+		return fmt.Errorf("%w: expected environment name", ErrCommandEmpty)
+	}
+
+	name, err := configFile(c)
+	if errors.Is(err, ErrNoConfigFile) {
+		name, err = config.DefaultFile()
+	}
+
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	cfg, err := config.NewConfig(name)
+	if errors.Is(err, os.ErrNotExist) {
+		cfg, err = &config.File{Config: config.Config{}}, nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	ses := config.Session{
+		Address: c.String("address"), Password: c.String("password"), Type: c.String("type"), Log: c.String("log"),
+		Description: c.String("description"), Owner: c.String("owner"),
+	}
+
+	if err := cfg.Add(env, ses, c.Bool("force")); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	if c.Bool("test") {
+		_, _ = fmt.Fprintf(executor.ew, "Testing connection to %s... ", ses.Address)
+
+		if err := executor.Dial(&ses); err != nil {
+			_, _ = fmt.Fprintln(executor.ew, "failed")
+
+			return err
+		}
+
+		_, _ = fmt.Fprintln(executor.ew, "ok")
+	}
+
+	if err := cfg.WriteToFile(name); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(executor.w, "Added %s environment to %s\n", env, name)
+
+	return nil
+}
+
+// configRemove removes the environment from the config file. In terminal
+// the removal is confirmed unless --force flag is set.
+func (executor *Executor) configRemove(c *cli.Context) error {
+	env := c.Args().First()
+	if c.NArg() != 1 {
+		return fmt.Errorf("%w: expected environment name", ErrCommandEmpty)
+	}
+
+	name, err := configFile(c)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.NewConfig(name)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	if cfg.HasEnv(env) && !c.Bool("force") && isTerminal(executor.r) &&
+		!confirm(bufio.NewReader(executor.r), executor.w, fmt.Sprintf("Remove %s environment?", env), false) {
+		return nil
+	}
+
+	if err := cfg.Remove(env); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	if err := cfg.WriteToFile(name); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(executor.w, "Removed %s environment from %s\n", env, name)
+
+	return nil
+}
+
+// printRunID prints the run ID if the suite cases are executed on several
+// environments, so their logs can be found by it.
+func printRunID(w io.Writer, env string, cases []suite.Case, id string) {
+	envs := make(map[string]bool)
+
+	for _, tc := range cases {
+		if tc.Env != "" {
+			envs[tc.Env] = true
+		} else {
+			envs[env] = true
+		}
+	}
+
+	if len(envs) > 1 {
+		_, _ = fmt.Fprintf(w, "Run ID: %s\n", id)
+	}
+}
+
+// configFile returns path to the config file passed in flags or found in
+// default locations.
+func configFile(c *cli.Context) (string, error) {
+	if name := c.String("config"); name != "" {
+		if config.IsGitSource(name) {
+			return "", ErrReadOnlyConfig
+		}
+
+		return name, nil
+	}
+
+	cfg, err := config.NewConfig("")
+	if err != nil {
+		return "", fmt.Errorf("config: %w", err)
+	}
+
+	sources := cfg.Sources()
+	if len(sources) == 0 || sources[0] == config.NoConfigFileSource {
+		return "", ErrNoConfigFile
+	}
+
+	return sources[0], nil
+}
+
+// test executes response assertion suite and prints the results.
+func (executor *Executor) test(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("%w: expected path to the suite file", ErrCommandEmpty)
+	}
+
+	s, err := suite.Load(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	printRunID(executor.ew, c.String("env"), s.Cases, executor.run.id)
+
+	// Cases of every environment are executed over its own connection.
+	executors := make(map[string]*Executor)
+	sessions := make(map[string]*config.Session)
+
+	defer func() {
+		for _, exec := range executors {
+			_ = exec.Close()
+		}
+	}()
+
+	results := make([]suite.Result, 0, len(s.Cases))
+
+	for i := range s.Cases {
+		tc := &s.Cases[i]
+
+		env := tc.Env
+		if env == "" {
+			env = c.String("env")
+		}
+
+		result := suite.Result{Name: tc.Name, Env: env, Command: tc.Command, Status: suite.StatusSkip}
+		if tc.Skip {
+			results = append(results, result)
+
+			continue
+		}
+
+		ses, ok := sessions[env]
+		if !ok {
+			if ses, err = executor.newSession(c, env); err != nil {
+				return err
+			}
+
+			sessions[env] = ses
+			executors[env] = NewExecutor(nil, io.Discard, executor.version)
+			// Commands of all environments are numbered in the same run.
+			executors[env].run = executor.run
+		}
+
+		caseSes := *ses
+		caseSes.SkipErrors = false
+
+		if tc.Timeout > 0 {
+			caseSes.KillTimeout = tc.Timeout
+		}
+
+		w := bytes.Buffer{}
+		start := time.Now()
+		err := executors[env].Execute(&w, &caseSes, tc.Command)
+
+		result.Duration = time.Since(start)
+		result.Seq = executor.run.seq
+		result.Response = strings.TrimSuffix(w.String(), "\n")
+
+		if err == nil {
+			err = tc.Check(result.Response)
+		}
+
+		if err != nil {
+			result.Status = suite.StatusFail
+			result.Error = err.Error()
+		} else {
+			result.Status = suite.StatusPass
+		}
+
+		results = append(results, result)
+	}
+
+	switch c.String("output") {
+	case "json":
+		if err := suite.WriteJSON(executor.w, executor.run.id, results); err != nil {
+			return err
+		}
+	default:
+		suite.WriteTAP(executor.w, results)
+	}
+
+	if suite.Summarize(results).Failed > 0 {
+		return ErrSuiteFailed
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+	"github.com/urfave/cli/v2"
+)
+
+// Completion scripts of the shells. Commands and flags are completed by the
+// binary with --generate-bash-completion, values of --env by `completion
+// envs` with --config of the command line. %[1]s is the name of the binary.
+const (
+	completionBash = `# bash completion for %[1]s, add to ~/.bashrc:
+#   source <(%[1]s completion bash)
+_%[1]s_complete() {
+  local cur prev config i
+  COMPREPLY=()
+  cur="${COMP_WORDS[COMP_CWORD]}"
+  prev="${COMP_WORDS[COMP_CWORD-1]}"
+  config=()
+  for ((i = 1; i < COMP_CWORD - 1; i++)); do
+    case "${COMP_WORDS[i]}" in
+      -c|--config) config=(--config "${COMP_WORDS[i+1]}") ;;
+    esac
+  done
+
+  case "$prev" in
+    -e|--env)
+      COMPREPLY=($(compgen -W "$("${COMP_WORDS[0]}" "${config[@]}" completion envs 2>/dev/null)" -- "$cur"))
+      return 0
+      ;;
+  esac
+
+  if [[ "$cur" == -* ]]; then
+    COMPREPLY=($(compgen -W "$("${COMP_WORDS[@]:0:COMP_CWORD}" "$cur" --generate-bash-completion 2>/dev/null)" -- "$cur"))
+  else
+    COMPREPLY=($(compgen -W "$("${COMP_WORDS[@]:0:COMP_CWORD}" --generate-bash-completion 2>/dev/null)" -- "$cur"))
+  fi
+}
+
+complete -o bashdefault -o default -F _%[1]s_complete %[1]s
+`
+
+	completionZsh = `#compdef %[1]s
+# zsh completion for %[1]s, add to ~/.zshrc:
+#   source <(%[1]s completion zsh)
+_%[1]s_complete() {
+  local -a opts config
+  local cur prev i
+  cur=${words[CURRENT]}
+  prev=${words[CURRENT-1]}
+  config=()
+  for ((i = 2; i < CURRENT - 1; i++)); do
+    case "${words[i]}" in
+      -c|--config) config=(--config "${words[i+1]}") ;;
+    esac
+  done
+
+  case "$prev" in
+    -e|--env)
+      opts=("${(@f)$(${words[1]} "${config[@]}" completion envs 2>/dev/null)}")
+      ;;
+    *)
+      if [[ "$cur" == -* ]]; then
+        opts=("${(@f)$(${words[@]:0:CURRENT-1} "$cur" --generate-bash-completion 2>/dev/null)}")
+      else
+        opts=("${(@f)$(${words[@]:0:CURRENT-1} --generate-bash-completion 2>/dev/null)}")
+      fi
+      ;;
+  esac
+
+  if [[ "${opts[1]}" != "" ]]; then
+    compadd -a opts
+  else
+    _files
+  fi
+}
+
+if [[ "$funcstack[1]" = "_%[1]s_complete" ]]; then
+  _%[1]s_complete "$@"
+else
+  compdef _%[1]s_complete %[1]s
+fi
+`
+
+	completionFishEnv = `
+# Environment names of the config file for --env.
+function __fish_%[1]s_envs
+    set -l args (commandline -opc)
+    set -l config
+    for i in (seq 2 (math (count $args) - 1))
+        if contains -- $args[$i] -c --config
+            set config --config $args[(math $i + 1)]
+        end
+    end
+    %[1]s $config completion envs 2>/dev/null
+end
+
+complete -c %[1]s -s e -l env -x -a '(__fish_%[1]s_envs)'
+`
+
+	completionPowerShell = `# PowerShell completion for %[1]s, add to $PROFILE:
+#   %[1]s completion powershell | Out-String | Invoke-Expression
+Register-ArgumentCompleter -Native -CommandName '%[1]s' -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+
+    $words = @($commandAst.CommandElements | ForEach-Object { $_.ToString() })
+    if ($wordToComplete -ne '') {
+        $words = $words[0..($words.Count - 2)]
+    }
+
+    $config = @()
+    for ($i = 1; $i -lt $words.Count - 1; $i++) {
+        if ($words[$i] -in '-c', '--config') {
+            $config = '--config', $words[$i + 1]
+        }
+    }
+
+    $arguments = @($words | Select-Object -Skip 1)
+    if ($words[-1] -in '-e', '--env') {
+        $candidates = & $words[0] @config completion envs 2>$null
+    } elseif ($wordToComplete -like '-*') {
+        $candidates = & $words[0] @arguments $wordToComplete --generate-bash-completion 2>$null
+    } else {
+        $candidates = & $words[0] @arguments --generate-bash-completion 2>$null
+    }
+
+    $candidates | Where-Object { $_ -like "$wordToComplete*" } | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`
+)
+
+// completion prints the completion script of the shell.
+func (executor *Executor) completion(shell string) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		name := c.App.Name
+
+		switch shell {
+		case "bash":
+			_, _ = fmt.Fprintf(executor.w, completionBash, name)
+		case "zsh":
+			_, _ = fmt.Fprintf(executor.w, completionZsh, name)
+		case "fish":
+			script, err := c.App.ToFishCompletion()
+			if err != nil {
+				return fmt.Errorf("completion: %w", err)
+			}
+
+			_, _ = fmt.Fprint(executor.w, strings.TrimRight(script, "\n")+"\n")
+			_, _ = fmt.Fprintf(executor.w, completionFishEnv, name)
+		case "powershell":
+			_, _ = fmt.Fprintf(executor.w, completionPowerShell, name)
+		}
+
+		return nil
+	}
+}
+
+// completionEnvs prints names of the config environments one per line for
+// the completion of --env. Errors are not printed, the shell completes
+// nothing instead.
+func (executor *Executor) completionEnvs(c *cli.Context) error {
+	// Completion never prompts for the passphrase of the encrypted config.
+	executor.r = nil
+
+	cfg, err := config.NewConfig(c.String("config"))
+	if err != nil {
+		return nil
+	}
+
+	for _, name := range cfg.Names() {
+		_, _ = fmt.Fprintln(executor.w, name)
+	}
+
+	return nil
+}
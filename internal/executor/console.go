@@ -0,0 +1,63 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+	"github.com/gorcon/rcon-cli/internal/redact"
+)
+
+// consoleClient is the client which receives the server console output
+// between commands, e.g. the console of Pterodactyl.
+type consoleClient interface {
+	SetConsole(handler func(line string))
+}
+
+// lockedWriter serializes writes of the console output and of the command
+// responses.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Write implements io.Writer.
+func (lw *lockedWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	return lw.w.Write(p)
+}
+
+// streamConsole prints the console output received between commands to w
+// in interactive mode if the client supports it. The returned writer must
+// be used for the rest of the output.
+func (executor *Executor) streamConsole(w io.Writer, ses *config.Session) (io.Writer, error) {
+	if _, ok := executor.client.(consoleClient); !ok {
+		return w, nil
+	}
+
+	// The redactor of the executor is replaced by every command.
+	redactor, err := redact.New(ses.RedactPatterns)
+	if err != nil {
+		return w, err
+	}
+
+	locked := &lockedWriter{w: w}
+	executor.console = func(line string) {
+		_, _ = fmt.Fprintln(locked, redactor.Redact(line))
+	}
+
+	executor.setConsole()
+
+	return locked, nil
+}
+
+// setConsole passes the console handler to the client. The client is dialed
+// again after connection errors, so the handler is set after every dial.
+func (executor *Executor) setConsole() {
+	if client, ok := executor.client.(consoleClient); ok && executor.console != nil {
+		client.SetConsole(executor.console)
+	}
+}
@@ -0,0 +1,111 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+	"golang.org/x/term"
+)
+
+// credentials sets the address and the password of the session which are
+// not set in the config and flags. They are prompted if stdin is a terminal,
+// otherwise ErrEmptyAddress or ErrEmptyPassword is returned. With
+// --ask-password the password is always read by readPassword and replaces
+// the configured one.
+func (executor *Executor) credentials(ses *config.Session, askPassword bool) error {
+	if ses.Address == "" && isTerminal(executor.r) {
+		_, _ = fmt.Fprint(executor.ew, "Address [host:port]: ")
+
+		address, err := readLine(executor.r)
+		if err != nil {
+			return fmt.Errorf("address: %w", err)
+		}
+
+		ses.Address = address
+	}
+
+	if ses.Address == "" {
+		return ErrEmptyAddress
+	}
+
+	if askPassword || (ses.Password == "" && isTerminal(executor.r)) {
+		password, err := executor.readPassword()
+		if err != nil {
+			return err
+		}
+
+		ses.Password = password
+	}
+
+	if ses.Password == "" {
+		return ErrEmptyPassword
+	}
+
+	return nil
+}
+
+// readPassword reads the password from the terminal without echo or the
+// first line of stdin if it is piped. The rest of stdin is left for the
+// commands.
+func (executor *Executor) readPassword() (string, error) {
+	if isTerminal(executor.r) {
+		_, _ = fmt.Fprint(executor.ew, "Password: ")
+	}
+
+	password, err := scanPassword(executor.r, executor.ew)
+	if err != nil {
+		return "", fmt.Errorf("password: %w", err)
+	}
+
+	return password, nil
+}
+
+// scanPassword reads the line without echo if the reader is a terminal. The
+// line break which is not echoed is written to w.
+func scanPassword(r io.Reader, w io.Writer) (string, error) {
+	file, ok := r.(*os.File)
+	if !ok || !term.IsTerminal(int(file.Fd())) {
+		return readLine(r)
+	}
+
+	password, err := term.ReadPassword(int(file.Fd()))
+	_, _ = fmt.Fprintln(w)
+
+	return string(password), err
+}
+
+// readLine reads the line byte by byte, so nothing after the line break is
+// consumed from the reader. The line break is removed.
+func readLine(r io.Reader) (string, error) {
+	if r == nil {
+		return "", io.EOF
+	}
+
+	var line strings.Builder
+
+	b := make([]byte, 1)
+
+	for {
+		n, err := r.Read(b)
+		if n > 0 {
+			if b[0] == '\n' {
+				break
+			}
+
+			line.WriteByte(b[0])
+		}
+
+		if err == io.EOF && line.Len() > 0 {
+			break
+		}
+
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return strings.TrimSuffix(line.String(), "\r"), nil
+}
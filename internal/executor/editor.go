@@ -0,0 +1,67 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+	"github.com/gorcon/rcon-cli/internal/readline"
+)
+
+// newEditor creates the line editor of interactive mode. Tab completes the
+// local commands and the session completions, the history is kept per
+// environment in XDG data directory. Returns nil if the terminal is not
+// supported, the input is read line by line then.
+func (executor *Executor) newEditor(r io.Reader, w io.Writer, ses *config.Session) *readline.Editor {
+	completions := append([]string{CommandQuit, CommandMark, CommandMarks, CommandStatus}, ses.Completions...)
+
+	env := ses.Env
+	if env == "" {
+		env = config.DefaultConfigEnv
+	}
+
+	name, err := readline.DefaultHistoryFile(env)
+	if err != nil {
+		_, _ = fmt.Fprintln(executor.ew, fmt.Errorf("history: %w", err))
+	}
+
+	editor, err := readline.New(r, w, completions, name)
+	if err != nil && name != "" {
+		_, _ = fmt.Fprintln(executor.ew, fmt.Errorf("history: %w", err))
+
+		// The editor works without the history file.
+		editor, err = readline.New(r, w, completions, "")
+	}
+
+	if err != nil {
+		_, _ = fmt.Fprintln(executor.ew, fmt.Errorf("line editor: %w", err))
+
+		return nil
+	}
+
+	return editor
+}
+
+// prompt prints the prompt before the next line. The line editor redraws
+// the whole line, so the prompt is passed to it if it is used.
+func (executor *Executor) prompt(w io.Writer, text string) {
+	if executor.editor != nil {
+		executor.editor.SetPrompt(text)
+
+		return
+	}
+
+	_, _ = fmt.Fprint(w, text)
+}
+
+// remember adds the command to the history of the line editor. Secrets are
+// masked with the session redact patterns.
+func (executor *Executor) remember(command string) {
+	if executor.editor == nil {
+		return
+	}
+
+	if err := executor.editor.AddHistory(executor.redactor.Redact(command)); err != nil {
+		_, _ = fmt.Fprintln(executor.ew, fmt.Errorf("history: %w", err))
+	}
+}
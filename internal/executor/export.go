@@ -0,0 +1,324 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+	"github.com/urfave/cli/v2"
+)
+
+// DefaultExportListen is the default address of the metrics endpoint of
+// export command. The metrics are scraped by Prometheus from another host,
+// so it listens on all interfaces.
+const DefaultExportListen = ":9137"
+
+// DefaultExportInterval is the default interval between metric collections.
+const DefaultExportInterval = 30 * time.Second
+
+// MetricPrefix is prepended to the names of the exported metrics.
+const MetricPrefix = "rcon_"
+
+// ErrNoMetrics is returned by export when the selected environments have no
+// metrics.
+var ErrNoMetrics = errors.New("no metrics to export: add metrics or metrics_preset to the environment")
+
+// exportEnv keeps the connection of the environment between collections.
+type exportEnv struct {
+	ses     *config.Session
+	exec    *Executor
+	metrics []config.Metric
+}
+
+// sample is the value of the metric collected from the environment.
+type sample struct {
+	env   string
+	value float64
+}
+
+// exporter collects the metrics of the environments and renders them in
+// Prometheus text format.
+type exporter struct {
+	envs []*exportEnv
+
+	mu   sync.RWMutex
+	body []byte
+}
+
+// export collects the metrics of the environments selected by --env pattern
+// or of all environments with metrics every interval and serves the last
+// values at /metrics until ^C. With --once the metrics are collected once
+// and printed.
+func (executor *Executor) export(c *cli.Context) error {
+	interval := time.Duration(durationFlag(c, "interval"))
+	if interval <= 0 {
+		return fmt.Errorf("%w: interval must be positive", config.ErrInvalidDuration)
+	}
+
+	e, err := executor.newExporter(c)
+	if err != nil {
+		return err
+	}
+	defer e.close()
+
+	if c.Bool("once") {
+		e.collect()
+
+		_, err := executor.w.Write(e.metrics())
+
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	listener, err := net.Listen("tcp", c.String("listen"))
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = w.Write(e.metrics())
+	})
+
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: config.DefaultTimeout}
+
+	// Connections are closed after the running collection finished.
+	collecting := make(chan struct{})
+	defer func() {
+		stop()
+		<-collecting
+	}()
+
+	go func() {
+		defer close(collecting)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			e.collect()
+
+			select {
+			case <-ctx.Done():
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+				defer cancel()
+
+				_ = server.Shutdown(shutdownCtx)
+
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	_, _ = fmt.Fprintf(executor.w, "Serving metrics of %d environments on http://%s/metrics\n", len(e.envs), listener.Addr())
+
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("export: %w", err)
+	}
+
+	return nil
+}
+
+// newExporter creates the sessions of the environments with metrics.
+func (executor *Executor) newExporter(c *cli.Context) (*exporter, error) {
+	cfg, err := config.NewConfig(c.String("config"))
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	names := cfg.Names()
+	if selector := c.String("env"); c.IsSet("env") {
+		if names, err = cfg.Match(selector); err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+	}
+
+	e := &exporter{}
+
+	for _, name := range names {
+		ses, err := executor.newSession(c, name)
+		if err != nil {
+			e.close()
+
+			return nil, err
+		}
+
+		metrics := ses.AllMetrics()
+		if len(metrics) == 0 {
+			continue
+		}
+
+		// Collections are not written to the log file of the environment.
+		ses.Log = ""
+
+		exec := NewExecutor(nil, io.Discard, executor.version)
+		exec.ew = executor.ew
+		exec.run = newRun(executor.run.id)
+
+		e.envs = append(e.envs, &exportEnv{ses: ses, exec: exec, metrics: metrics})
+	}
+
+	if len(e.envs) == 0 {
+		return nil, ErrNoMetrics
+	}
+
+	return e, nil
+}
+
+// collect executes the commands of the metrics on every environment
+// concurrently and renders the collected values. Every command is executed
+// once per collection even if several metrics use it.
+func (e *exporter) collect() {
+	up := make([]sample, len(e.envs))
+	durations := make([]sample, len(e.envs))
+	values := make([]map[string]float64, len(e.envs))
+
+	var wg sync.WaitGroup
+
+	for i, env := range e.envs {
+		wg.Add(1)
+
+		go func(i int, env *exportEnv) {
+			defer wg.Done()
+
+			start := time.Now()
+			responses, err := env.collect()
+
+			up[i] = sample{env: env.ses.Env, value: 1}
+			durations[i] = sample{env: env.ses.Env, value: time.Since(start).Seconds()}
+
+			if err != nil {
+				up[i].value = 0
+
+				_, _ = fmt.Fprintf(env.exec.ew, "[%s] error: %s\n", env.ses.Env, err)
+
+				return
+			}
+
+			values[i] = make(map[string]float64, len(env.metrics))
+
+			for _, metric := range env.metrics {
+				if value, ok := metric.Extract(responses[metric.Command]); ok {
+					values[i][metric.Name] = value
+				}
+			}
+		}(i, env)
+	}
+
+	wg.Wait()
+
+	var buf bytes.Buffer
+
+	writeMetric(&buf, "up", "Whether the last collection of the environment succeeded", up)
+	writeMetric(&buf, "collect_duration_seconds", "Duration of the last collection of the environment", durations)
+
+	// Samples of the metric are grouped across the environments.
+	helps := make(map[string]string)
+	samples := make(map[string][]sample)
+
+	for i, env := range e.envs {
+		for _, metric := range env.metrics {
+			if helps[metric.Name] == "" {
+				helps[metric.Name] = metric.Help
+			}
+
+			if value, ok := values[i][metric.Name]; ok {
+				samples[metric.Name] = append(samples[metric.Name], sample{env: env.ses.Env, value: value})
+			}
+		}
+	}
+
+	names := make([]string, 0, len(samples))
+	for name := range samples {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		writeMetric(&buf, name, helps[name], samples[name])
+	}
+
+	e.mu.Lock()
+	e.body = buf.Bytes()
+	e.mu.Unlock()
+}
+
+// collect executes the commands of the metrics and returns their responses.
+// The connection is dialed again by the next collection after an error.
+func (env *exportEnv) collect() (map[string]string, error) {
+	responses := make(map[string]string)
+
+	var commands []string
+
+	for _, metric := range env.metrics {
+		if _, ok := responses[metric.Command]; !ok {
+			responses[metric.Command] = ""
+			commands = append(commands, metric.Command)
+		}
+	}
+
+	// Commands of the records are redacted, so the responses are matched
+	// by the order.
+	n := 0
+
+	err := env.exec.executeRecords(env.ses, commands, func(record Record) error {
+		responses[commands[n]] = record.Response
+		n++
+
+		return nil
+	})
+	if err != nil {
+		_ = env.exec.disconnect()
+	}
+
+	return responses, err
+}
+
+// metrics returns the metrics of the last collection.
+func (e *exporter) metrics() []byte {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.body
+}
+
+// close closes connections of all environments.
+func (e *exporter) close() {
+	for _, env := range e.envs {
+		_ = env.exec.Close()
+	}
+}
+
+// writeMetric writes the gauge in Prometheus text format. Samples are
+// labeled with the environment.
+func writeMetric(w io.Writer, name string, help string, samples []sample) {
+	name = MetricPrefix + name
+
+	if help != "" {
+		_, _ = fmt.Fprintf(w, "# HELP %s %s\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help))
+	}
+
+	_, _ = fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+
+	for _, s := range samples {
+		env := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s.env)
+		_, _ = fmt.Fprintf(w, "%s{env=\"%s\"} %s\n", name, env, strconv.FormatFloat(s.value, 'g', -1, 64))
+	}
+}
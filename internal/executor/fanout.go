@@ -0,0 +1,150 @@
+package executor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+	"github.com/urfave/cli/v2"
+)
+
+// Fan-out errors.
+var (
+	// ErrFanOutFailed is returned when the commands failed on some of the
+	// environments selected by --env pattern.
+	ErrFanOutFailed = errors.New("commands failed")
+
+	// ErrFanOutInteractive is returned when --env pattern selects several
+	// environments without commands to execute.
+	ErrFanOutInteractive = errors.New("interactive mode is not supported for several environments")
+
+	// ErrFanOutEnvCreate is returned when --env-create is set together with
+	// --env pattern.
+	ErrFanOutEnvCreate = errors.New("--env-create is not supported for several environments")
+)
+
+// fanOutResult contains the output of the commands on the environment.
+type fanOutResult struct {
+	output  bytes.Buffer
+	records []Record
+	err     error
+}
+
+// fanOut executes commands concurrently on every environment selected by
+// --env pattern over its own connection. Output lines are prefixed with the
+// environment name and printed in config order after all environments
+// finished, followed by the summary. Structured output contains the records
+// of all environments in config order without the summary.
+func (executor *Executor) fanOut(c *cli.Context, commands []string) error {
+	var rw *recordWriter
+	if output := c.String("output"); output != OutputText {
+		var err error
+		if rw, err = newRecordWriter(executor.w, output); err != nil {
+			return err
+		}
+	}
+
+	cfg, err := config.NewConfig(c.String("config"))
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	envs, err := cfg.Match(c.String("env"))
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	results := make([]fanOutResult, len(envs))
+
+	var wg sync.WaitGroup
+
+	for i, env := range envs {
+		ses, err := executor.newSession(c, env)
+		if err != nil {
+			return err
+		}
+
+		exec := NewExecutor(nil, &results[i].output, executor.version)
+		exec.ew = executor.ew
+		// Environments are executed concurrently, so every environment
+		// numbers its commands in the run.
+		exec.run = newRun(executor.run.id)
+		exec.expect = executor.expect
+
+		wg.Add(1)
+
+		go func(result *fanOutResult) {
+			defer wg.Done()
+
+			if rw != nil {
+				result.err = exec.executeRecords(ses, commands, func(record Record) error {
+					result.records = append(result.records, record)
+
+					return nil
+				})
+			} else {
+				result.err = exec.Execute(&result.output, ses, commands...)
+			}
+			_ = exec.Close()
+		}(&results[i])
+	}
+
+	wg.Wait()
+
+	if rw != nil {
+		return fanOutRecords(rw, envs, results)
+	}
+
+	var failed []string
+
+	for i, env := range envs {
+		for _, line := range strings.Split(strings.TrimSuffix(results[i].output.String(), "\n"), "\n") {
+			if line != "" {
+				_, _ = fmt.Fprintf(executor.w, "[%s] %s\n", env, line)
+			}
+		}
+
+		if results[i].err != nil {
+			_, _ = fmt.Fprintf(executor.w, "[%s] error: %s\n", env, results[i].err)
+			failed = append(failed, env)
+		}
+	}
+
+	_, _ = fmt.Fprintf(executor.w, "%d of %d environments succeeded\n", len(envs)-len(failed), len(envs))
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%w on %s", ErrFanOutFailed, strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// fanOutRecords prints the records of the environments in structured output.
+func fanOutRecords(rw *recordWriter, envs []string, results []fanOutResult) error {
+	var failed []string
+
+	for i, env := range envs {
+		for _, record := range results[i].records {
+			if err := rw.write(record); err != nil {
+				return fmt.Errorf("output: %w", err)
+			}
+		}
+
+		if results[i].err != nil {
+			failed = append(failed, env)
+		}
+	}
+
+	if err := rw.flush(); err != nil {
+		return fmt.Errorf("output: %w", err)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%w on %s", ErrFanOutFailed, strings.Join(failed, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package executor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+	"github.com/gorcon/telnet"
+)
+
+// ErrFingerprintMismatch is returned when the greeting of the TELNET server
+// does not match the fingerprint of the session. The password is not sent
+// to such server.
+var ErrFingerprintMismatch = errors.New("telnet fingerprint mismatch")
+
+// greeting collects the bytes the TELNET server sends before the password
+// prompt and checks their fingerprint.
+type greeting struct {
+	expected string
+	timeout  time.Duration
+
+	mu       sync.Mutex
+	data     []byte
+	complete bool
+	done     chan struct{}
+	err      error
+}
+
+// newGreeting creates the greeting check with the expected fingerprint.
+// The server must send the password prompt within the timeout.
+func newGreeting(expected string, timeout time.Duration) *greeting {
+	if timeout <= 0 {
+		timeout = config.DefaultTimeout
+	}
+
+	return &greeting{expected: expected, timeout: timeout, done: make(chan struct{})}
+}
+
+// Write records bytes received from the server until the password prompt.
+func (g *greeting) Write(p []byte) (int, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.complete {
+		return len(p), nil
+	}
+
+	g.data = append(g.data, p...)
+	if i := bytes.Index(g.data, []byte(telnet.ResponseEnterPassword)); i >= 0 {
+		g.data = g.data[:i+len(telnet.ResponseEnterPassword)]
+		g.complete = true
+		close(g.done)
+	}
+
+	return len(p), nil
+}
+
+// verify waits for the password prompt and compares the fingerprint of the
+// greeting with the expected one.
+func (g *greeting) verify() error {
+	timer := time.NewTimer(g.timeout)
+	defer timer.Stop()
+
+	var err error
+
+	select {
+	case <-g.done:
+		g.mu.Lock()
+		actual := fingerprint(g.data)
+		g.mu.Unlock()
+
+		if !strings.EqualFold(actual, g.expected) {
+			err = fmt.Errorf("%w: expected %s, got %s", ErrFingerprintMismatch, g.expected, actual)
+		}
+	case <-timer.C:
+		err = fmt.Errorf("%w: no password prompt for %s", ErrFingerprintMismatch, g.timeout)
+	}
+
+	g.mu.Lock()
+	g.err = err
+	g.mu.Unlock()
+
+	return err
+}
+
+// failure returns the error of the check.
+func (g *greeting) failure() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.err
+}
+
+// fingerprint returns hex encoded SHA-256 of the data.
+func fingerprint(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
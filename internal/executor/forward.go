@@ -0,0 +1,281 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+)
+
+// readRateWindow is the interval of the response throughput checks.
+const readRateWindow = time.Second
+
+// forwarder forwards the protocol client connection to the remote server and
+// counts bytes received from the server. The client gets one end of the
+// in-memory pipe, so no local port is opened which other processes could
+// connect to.
+type forwarder struct {
+	conn     net.Conn
+	local    net.Conn
+	remote   io.ReadWriteCloser
+	received atomic.Int64
+	// greeting verifies the TELNET server identity before the client sends
+	// the password. It is nil if the check is disabled.
+	greeting *greeting
+
+	// checked and started are used by slow and are accessed only by the
+	// goroutine which executes the command.
+	checked int64
+	started bool
+}
+
+// needsForwarder reports whether the session uses features which require
+// the local forwarder.
+func needsForwarder(ses *config.Session) bool {
+	return ses.ProxyCommand != "" || ses.Proxy != "" || ses.MinReadRate > 0 || ses.ReadBufferSize > 0 || ses.WriteBufferSize > 0 ||
+		ses.KeepaliveInterval > 0 || (ses.Type == config.ProtocolTELNET && ses.TELNETFingerprint != "")
+}
+
+// dialForwarder connects to the remote server directly or through the proxy
+// command of the session and starts forwarding the client end of the pipe.
+func dialForwarder(ses *config.Session, stderr io.Writer) (*forwarder, error) {
+	var remote io.ReadWriteCloser
+	var err error
+
+	if ses.ProxyCommand != "" {
+		remote, err = startProxy(ses.ProxyCommand, ses.Address, stderr)
+	} else {
+		remote, err = dialTCP(ses)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	conn, local := net.Pipe()
+
+	f := forwarder{conn: conn, local: local, remote: remote}
+	if ses.Type == config.ProtocolTELNET && ses.TELNETFingerprint != "" {
+		f.greeting = newGreeting(ses.TELNETFingerprint, ses.DialTimeout())
+	}
+
+	go f.serve()
+
+	return &f, nil
+}
+
+// dialTCP connects to the remote server directly or through the proxy of
+// the session and sets buffer sizes and keep-alive period of the connection.
+func dialTCP(ses *config.Session) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+
+	if ses.Proxy != "" {
+		conn, err = dialProxy(ses.Proxy, ses.Address, ses.DialTimeout())
+	} else {
+		conn, err = net.DialTimeout("tcp", ses.Address, ses.DialTimeout())
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	raw := conn
+	if bc, ok := conn.(*bufferedConn); ok {
+		raw = bc.Conn
+	}
+
+	tcp, ok := raw.(*net.TCPConn)
+	if !ok {
+		return conn, nil
+	}
+
+	if ses.ReadBufferSize > 0 {
+		err = tcp.SetReadBuffer(int(ses.ReadBufferSize))
+	}
+
+	if err == nil && ses.WriteBufferSize > 0 {
+		err = tcp.SetWriteBuffer(int(ses.WriteBufferSize))
+	}
+
+	if err != nil {
+		_ = conn.Close()
+
+		return nil, fmt.Errorf("set buffer size: %w", err)
+	}
+
+	if ses.KeepaliveInterval > 0 {
+		if err = tcp.SetKeepAlive(true); err == nil {
+			err = tcp.SetKeepAlivePeriod(time.Duration(ses.KeepaliveInterval))
+		}
+	}
+
+	if err != nil {
+		_ = conn.Close()
+
+		return nil, fmt.Errorf("set keepalive: %w", err)
+	}
+
+	return conn, nil
+}
+
+// Conn returns the connection the client must use instead of dialing the
+// server.
+func (f *forwarder) Conn() net.Conn {
+	return f.conn
+}
+
+// Close stops the forwarding and closes the server connection.
+func (f *forwarder) Close() error {
+	_ = f.local.Close()
+
+	return f.remote.Close()
+}
+
+// failure returns the error of the server identity check if it failed.
+func (f *forwarder) failure() error {
+	if f.greeting == nil {
+		return nil
+	}
+
+	return f.greeting.failure()
+}
+
+// reset starts the throughput measurement of the next response.
+func (f *forwarder) reset() {
+	f.checked = f.received.Load()
+	f.started = false
+}
+
+// slow reports whether the response has been received slower than rate bytes
+// per second since the previous call. It must be called every readRateWindow.
+// The window in which the first bytes of the response arrived is not checked
+// because the server could start to respond at the end of it.
+func (f *forwarder) slow(rate config.Size) bool {
+	received := f.received.Load()
+	n := received - f.checked
+	f.checked = received
+
+	if !f.started {
+		f.started = n > 0
+
+		return false
+	}
+
+	return float64(n) < float64(rate)*readRateWindow.Seconds()
+}
+
+// serve forwards data between the local end of the pipe and the server in
+// both directions until one of the sides closes the connection.
+func (f *forwarder) serve() {
+	defer f.remote.Close()
+
+	conn := f.local
+	defer conn.Close()
+
+	var remote io.Reader = &countingReader{r: f.remote, n: &f.received}
+	if f.greeting != nil {
+		remote = io.TeeReader(remote, f.greeting)
+	}
+
+	go func() {
+		// Nothing is sent to the server which is not verified.
+		if f.greeting != nil && f.greeting.verify() != nil {
+			_ = conn.Close()
+			_ = f.remote.Close()
+
+			return
+		}
+
+		_, _ = io.Copy(f.remote, conn)
+		_ = f.remote.Close()
+	}()
+
+	_, _ = io.Copy(conn, remote)
+}
+
+// countingReader counts bytes read from the underlying reader.
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n.Add(int64(n))
+
+	return n, err
+}
+
+// proxyConn is the connection to the remote server over stdin and stdout of
+// the proxy command.
+type proxyConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	once   sync.Once
+}
+
+// startProxy starts the proxy command in the system shell. Placeholders %h
+// and %p in the command are replaced with host and port of the address like
+// in ssh ProxyCommand. Stderr of the command is passed to stderr.
+func startProxy(command string, address string, stderr io.Writer) (*proxyConn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("proxy command: %w", err)
+	}
+
+	command = strings.NewReplacer("%h", host, "%p", port, "%%", "%").Replace(command)
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("/bin/sh", "-c", command)
+	}
+
+	cmd.Stderr = stderr
+
+	conn := &proxyConn{cmd: cmd}
+
+	if conn.stdin, err = cmd.StdinPipe(); err != nil {
+		return nil, fmt.Errorf("proxy command: %w", err)
+	}
+
+	if conn.stdout, err = cmd.StdoutPipe(); err != nil {
+		return nil, fmt.Errorf("proxy command: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("proxy command: %w", err)
+	}
+
+	return conn, nil
+}
+
+func (pc *proxyConn) Read(p []byte) (int, error) {
+	return pc.stdout.Read(p)
+}
+
+func (pc *proxyConn) Write(p []byte) (int, error) {
+	return pc.stdin.Write(p)
+}
+
+// Close closes stdin of the proxy command and kills it. It is safe to call
+// Close several times.
+func (pc *proxyConn) Close() error {
+	pc.once.Do(func() {
+		_ = pc.stdin.Close()
+		_ = pc.cmd.Process.Kill()
+		_ = pc.cmd.Wait()
+	})
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"syscall"
+	"time"
+
+	"github.com/gorcon/rcon"
+	"github.com/gorcon/rcon-cli/internal/battleye"
+	"github.com/gorcon/rcon-cli/internal/pterodactyl"
+	"github.com/gorcon/telnet"
+	"github.com/gorcon/websocket"
+	"github.com/urfave/cli/v2"
+)
+
+// Exit codes of the process. Scripts and liveness probes can tell the
+// failures apart without parsing stderr.
+const (
+	ExitCodeSuccess        = 0
+	ExitCodeFailure        = 1
+	ExitCodeConnectFailure = 2
+	ExitCodeAuthFailure    = 3
+	ExitCodeTimeout        = 4
+	ExitCodeExpectMismatch = 5
+	ExitCodeCanceled       = 130
+)
+
+// Expectation errors.
+var (
+	// ErrExpectMismatch is returned when the response does not match
+	// --expect or matches --expect-not.
+	ErrExpectMismatch = errors.New("response does not match expectation")
+
+	// ErrExpectInteractive is returned when response expectations are used
+	// without commands.
+	ErrExpectInteractive = errors.New("response expectations require commands to execute")
+
+	// ErrExpectWithoutCommand is returned when healthcheck has response
+	// expectations but no ping command.
+	ErrExpectWithoutCommand = errors.New("response expectations require the ping command: add --command or ready_command")
+)
+
+// expectation contains the patterns which the responses are checked with.
+type expectation struct {
+	match  *regexp.Regexp
+	reject *regexp.Regexp
+}
+
+// newExpectation compiles --expect and --expect-not flags. Nil expectation
+// is returned if both are not set.
+func newExpectation(c *cli.Context) (*expectation, error) {
+	if c.String("expect") == "" && c.String("expect-not") == "" {
+		return nil, nil
+	}
+
+	var e expectation
+	var err error
+
+	if pattern := c.String("expect"); pattern != "" {
+		if e.match, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("expect: %w", err)
+		}
+	}
+
+	if pattern := c.String("expect-not"); pattern != "" {
+		if e.reject, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("expect not: %w", err)
+		}
+	}
+
+	return &e, nil
+}
+
+// check returns ErrExpectMismatch if the response does not satisfy the
+// expectation.
+func (e *expectation) check(response string) error {
+	if e.match != nil && !e.match.MatchString(response) {
+		return fmt.Errorf("%w: want match of %q", ErrExpectMismatch, e.match)
+	}
+
+	if e.reject != nil && e.reject.MatchString(response) {
+		return fmt.Errorf("%w: want no match of %q", ErrExpectMismatch, e.reject)
+	}
+
+	return nil
+}
+
+// ExitCode returns the exit code of the process for the error returned by
+// Run.
+func ExitCode(err error) int {
+	var netErr net.Error
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	var poolErr *poolError
+
+	switch {
+	case err == nil:
+		return ExitCodeSuccess
+	case errors.As(err, &poolErr):
+		return poolErr.ExitCode
+	case errors.Is(err, ErrScheduleCanceled):
+		return ExitCodeCanceled
+	case errors.Is(err, ErrExpectMismatch):
+		return ExitCodeExpectMismatch
+	case errors.Is(err, rcon.ErrAuthFailed), errors.Is(err, telnet.ErrAuthFailed),
+		errors.Is(err, websocket.ErrAuthFailed), errors.Is(err, battleye.ErrAuthFailed),
+		errors.Is(err, pterodactyl.ErrAuthFailed):
+		return ExitCodeAuthFailure
+	case errors.Is(err, ErrKillTimeout), errors.Is(err, ErrResponseTimeout),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ExitCodeTimeout
+	case errors.As(err, &opErr) && opErr.Op == "dial", errors.As(err, &dnsErr),
+		errors.Is(err, ErrProxy), errors.Is(err, syscall.ECONNREFUSED):
+		return ExitCodeConnectFailure
+	default:
+		return ExitCodeFailure
+	}
+}
+
+// healthcheck authenticates on the server and executes the ping command if
+// it is set. The ping command defaults to ready_command of the environment,
+// its response is checked with --expect and --expect-not flags or with
+// ready_expect.
+func (executor *Executor) healthcheck(c *cli.Context) error {
+	ses, err := executor.NewSession(c)
+	if err != nil {
+		return err
+	}
+
+	if err := executor.credentials(ses, c.Bool("ask-password")); err != nil {
+		return err
+	}
+
+	command, expect := c.String("command"), executor.expect
+	if command == "" {
+		command = ses.ReadyCommand
+
+		if expect == nil && ses.ReadyExpect != "" {
+			re, err := regexp.Compile(ses.ReadyExpect)
+			if err != nil {
+				return fmt.Errorf("healthcheck: ready expect: %w", err)
+			}
+
+			expect = &expectation{match: re}
+		}
+	}
+
+	if command == "" && expect != nil {
+		return ErrExpectWithoutCommand
+	}
+
+	start := time.Now()
+
+	if err := executor.Dial(ses); err != nil {
+		return fmt.Errorf("healthcheck: %w", err)
+	}
+
+	defer func() {
+		_ = executor.disconnect()
+	}()
+
+	if command != "" {
+		response, err := executor.client.Execute(ses.SignCommand(command))
+		if err != nil {
+			return fmt.Errorf("healthcheck: %w", err)
+		}
+
+		if expect != nil {
+			if err := expect.check(response); err != nil {
+				return fmt.Errorf("healthcheck: %w", err)
+			}
+		}
+	}
+
+	_, _ = fmt.Fprintf(executor.w, "OK %s in %s\n", ses.Address, time.Since(start).Round(time.Millisecond))
+
+	return nil
+}
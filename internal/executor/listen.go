@@ -0,0 +1,203 @@
+package executor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+	"github.com/gorcon/rcon-cli/internal/pterodactyl"
+	"github.com/gorcon/rcon-cli/internal/redact"
+	"github.com/gorcon/rcon-cli/internal/telnetdialect"
+	"github.com/gorcon/rcon-cli/internal/telnetstream"
+	"github.com/gorcon/rcon-cli/internal/webrcon"
+	"github.com/urfave/cli/v2"
+)
+
+// ErrListenUnsupported is returned when listen mode is used with the
+// protocol which does not push the server output to the clients.
+var ErrListenUnsupported = errors.New("listen mode is supported by telnet, web and pterodactyl types only")
+
+// streamClient is the client of listen mode which passes every line of the
+// server output to the handler.
+type streamClient interface {
+	Send(command string) error
+	Stream(handler func(line string)) error
+	Close() error
+}
+
+// streamLine is the line of the server output in JSON output of listen
+// mode.
+type streamLine struct {
+	Time    time.Time `json:"time"`
+	Env     string    `json:"env"`
+	Address string    `json:"address"`
+	Line    string    `json:"line"`
+}
+
+// listen keeps the connection open and prints the server output until ^C,
+// :q or the server closes the connection. Commands read from stdin are
+// sent to the server, their responses are printed as part of the output.
+func (executor *Executor) listen(c *cli.Context) error {
+	output := c.String("output")
+	if output != OutputText && output != OutputJSON {
+		return fmt.Errorf("%w: %s", ErrUnsupportedOutput, output)
+	}
+
+	var filter *regexp.Regexp
+	if pattern := c.String("filter"); pattern != "" {
+		var err error
+		if filter, err = regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("listen: filter: %w", err)
+		}
+	}
+
+	invert := c.Bool("filter-invert")
+
+	ses, err := executor.NewSession(c)
+	if err != nil {
+		return err
+	}
+
+	if err := executor.credentials(ses, c.Bool("ask-password")); err != nil {
+		return err
+	}
+
+	redactor, err := redact.New(ses.RedactPatterns)
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+
+	defer executor.closeForwarder()
+
+	client, err := executor.dialStream(ses)
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+
+	var stopped atomic.Bool
+	stop := func() {
+		if stopped.CompareAndSwap(false, true) {
+			_ = client.Close()
+		}
+	}
+	defer stop()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if executor.r != nil {
+		go executor.sendInput(client, ses, stop)
+	}
+
+	w := &lockedWriter{w: executor.w}
+	encoder := json.NewEncoder(w)
+
+	err = client.Stream(func(line string) {
+		if filter != nil && filter.MatchString(line) == invert {
+			return
+		}
+
+		line = redactor.Redact(line)
+
+		if output == OutputJSON {
+			_ = encoder.Encode(streamLine{Time: time.Now(), Env: ses.Env, Address: ses.Address, Line: line})
+
+			return
+		}
+
+		_, _ = fmt.Fprintln(w, line)
+	})
+
+	if stopped.Load() {
+		return nil
+	}
+
+	return fmt.Errorf("listen: %w", err)
+}
+
+// sendInput sends the commands read from stdin to the server. :q stops
+// listening, the end of stdin does not.
+func (executor *Executor) sendInput(client streamClient, ses *config.Session, stop func()) {
+	scanner := bufio.NewScanner(executor.r)
+
+	for scanner.Scan() {
+		command := strings.TrimSpace(scanner.Text())
+
+		switch command {
+		case "":
+			continue
+		case CommandQuit:
+			stop()
+
+			return
+		}
+
+		if err := client.Send(ses.SignCommand(command)); err != nil {
+			_, _ = fmt.Fprintf(executor.ew, "listen: %s\n", err)
+		}
+	}
+}
+
+// dialStream connects to the server with the client of listen mode. RCON
+// and BattlEye servers answer the commands only, so they are not supported.
+func (executor *Executor) dialStream(ses *config.Session) (streamClient, error) {
+	ses.Address = config.AddressWithPort(ses.Address, ses.Type)
+
+	switch ses.Type {
+	case config.ProtocolTELNET, config.ProtocolWebRCON:
+	case config.ProtocolPterodactyl:
+		if needsForwarder(ses) {
+			return nil, ErrForwardingPterodactyl
+		}
+
+		return pterodactyl.Dial(ses.Address, ses.Password, ses.ServerID, pterodactyl.SetDialTimeout(ses.DialTimeout()))
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrListenUnsupported, ses.Type)
+	}
+
+	var conn net.Conn
+
+	if needsForwarder(ses) {
+		var err error
+		if executor.forwarder, err = dialForwarder(ses, executor.ew); err != nil {
+			return nil, err
+		}
+
+		conn = executor.forwarder.Conn()
+	}
+
+	switch {
+	case ses.Type == config.ProtocolTELNET && ses.TELNET != nil:
+		if conn != nil {
+			return dialTELNET(ses, telnetdialect.SetConn(conn))
+		}
+
+		return dialTELNET(ses)
+	case ses.Type == config.ProtocolTELNET:
+		options := []telnetstream.Option{telnetstream.SetDialTimeout(ses.DialTimeout())}
+		if conn != nil {
+			options = append(options, telnetstream.SetConn(conn))
+		}
+
+		return telnetstream.Dial(ses.Address, ses.Password, options...)
+	case conn != nil:
+		return dialWebRCON(ses, webrcon.SetConn(conn))
+	default:
+		return dialWebRCON(ses)
+	}
+}
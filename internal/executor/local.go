@@ -0,0 +1,119 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/bookmark"
+	"github.com/gorcon/rcon-cli/internal/config"
+	"github.com/gorcon/rcon-cli/internal/redact"
+)
+
+// Local commands of interactive mode. They are handled by the CLI and are
+// not sent to the remote server.
+const (
+	// CommandMark saves the previous command and its response to the
+	// bookmarks file. Usage: `:mark [label]`.
+	CommandMark = ":mark"
+
+	// CommandMarks lists bookmarks saved in the current session.
+	CommandMarks = ":marks"
+
+	// CommandStatus prints the environment of the session with its notes.
+	CommandStatus = ":status"
+)
+
+// ErrNothingToMark is returned when :mark is called before any command was
+// executed.
+var ErrNothingToMark = errors.New("nothing to mark: execute a command first")
+
+// local executes local command of interactive mode. Returns false if the
+// command is not a local command.
+func (executor *Executor) local(w io.Writer, ses *config.Session, command string) (bool, error) {
+	name, args, _ := strings.Cut(command, " ")
+
+	switch name {
+	case CommandMark:
+		return true, executor.mark(w, ses, strings.TrimSpace(args))
+	case CommandMarks:
+		executor.printMarks(w)
+
+		return true, nil
+	case CommandStatus:
+		printStatus(w, ses)
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// mark appends the previous command and its response to the bookmarks file.
+func (executor *Executor) mark(w io.Writer, ses *config.Session, label string) error {
+	if executor.last.Command == "" {
+		return ErrNothingToMark
+	}
+
+	name := ses.MarksFile
+	if name == "" {
+		var err error
+		if name, err = bookmark.DefaultFile(); err != nil {
+			return fmt.Errorf("mark: %w", err)
+		}
+	}
+
+	b := executor.last
+	b.Time = time.Now()
+	b.Address = ses.Address
+	b.Label = label
+
+	// Password must never get to the bookmarks even if it was echoed.
+	if ses.Password != "" {
+		b.Command = strings.ReplaceAll(b.Command, ses.Password, redact.Mask)
+		b.Response = strings.ReplaceAll(b.Response, ses.Password, redact.Mask)
+	}
+
+	if err := bookmark.Append(name, b); err != nil {
+		return fmt.Errorf("mark: %w", err)
+	}
+
+	executor.marks = append(executor.marks, b)
+
+	_, _ = fmt.Fprintf(w, "Marked %q in %s\n", b.Command, name)
+
+	return nil
+}
+
+// printStatus prints the environment of the session with its notes.
+func printStatus(w io.Writer, ses *config.Session) {
+	_, _ = fmt.Fprintf(w, "env: %s\naddress: %s\ntype: %s\n", ses.Env, ses.Address, ses.Type)
+
+	if ses.Description != "" {
+		_, _ = fmt.Fprintf(w, "description: %s\n", ses.Description)
+	}
+
+	if ses.Owner != "" {
+		_, _ = fmt.Fprintf(w, "owner: %s\n", ses.Owner)
+	}
+}
+
+// printMarks prints bookmarks saved in the current session.
+func (executor *Executor) printMarks(w io.Writer) {
+	if len(executor.marks) == 0 {
+		_, _ = fmt.Fprintln(w, "No marks in this session")
+
+		return
+	}
+
+	for i, b := range executor.marks {
+		_, _ = fmt.Fprintf(w, "%d. [%s] %s", i+1, b.Time.Format(time.DateTime), b.Command)
+		if b.Label != "" {
+			_, _ = fmt.Fprintf(w, " (%s)", b.Label)
+		}
+
+		_, _ = fmt.Fprintln(w)
+	}
+}
@@ -0,0 +1,161 @@
+package executor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Record is the result of the executed command in structured output.
+type Record struct {
+	Env      string        `json:"env" yaml:"env"`
+	Address  string        `json:"address" yaml:"address"`
+	RunID    string        `json:"run_id" yaml:"run_id"`
+	Seq      int           `json:"seq" yaml:"seq"`
+	Command  string        `json:"command" yaml:"command"`
+	Response string        `json:"response" yaml:"response"`
+	Duration time.Duration `json:"duration" yaml:"duration"`
+	Error    string        `json:"error,omitempty" yaml:"error,omitempty"`
+	// Parsed contains matches of the response parsers by parser name.
+	Parsed map[string][]map[string]string `json:"parsed,omitempty" yaml:"parsed,omitempty"`
+}
+
+// recordWriter prints records in the output format. JSON lines are printed
+// as soon as the command is executed, JSON array and YAML are printed by
+// flush.
+type recordWriter struct {
+	w       io.Writer
+	format  string
+	records []Record
+}
+
+// newRecordWriter creates the writer of the structured output format.
+func newRecordWriter(w io.Writer, format string) (*recordWriter, error) {
+	switch format {
+	case OutputJSON, OutputJSONArray, OutputYAML:
+		return &recordWriter{w: w, format: format, records: []Record{}}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedOutput, format)
+	}
+}
+
+// write prints or collects the record.
+func (rw *recordWriter) write(record Record) error {
+	if rw.format != OutputJSON {
+		rw.records = append(rw.records, record)
+
+		return nil
+	}
+
+	return json.NewEncoder(rw.w).Encode(record)
+}
+
+// flush prints the collected records.
+func (rw *recordWriter) flush() error {
+	switch rw.format {
+	case OutputJSONArray:
+		encoder := json.NewEncoder(rw.w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(rw.records)
+	case OutputYAML:
+		encoder := yaml.NewEncoder(rw.w)
+		encoder.SetIndent(2)
+
+		if err := encoder.Encode(rw.records); err != nil {
+			return err
+		}
+
+		return encoder.Close()
+	}
+
+	return nil
+}
+
+// executeRecords executes commands one by one and passes their records to
+// emit. The response of the record is the text which would be printed in
+// text output. The first failed command stops the execution unless the
+// session skips errors.
+func (executor *Executor) executeRecords(ses *config.Session, commands []string, emit func(Record) error) error {
+	for _, command := range commands {
+		cmdSes := *ses
+		cmdSes.SkipErrors = false
+
+		var buf bytes.Buffer
+
+		executor.parsed = nil
+
+		start := time.Now()
+		err := executor.Execute(&buf, &cmdSes, command)
+
+		record := Record{
+			Env:      ses.Env,
+			Address:  cmdSes.Address,
+			RunID:    executor.run.id,
+			Seq:      executor.run.seq,
+			Command:  executor.redactor.Redact(command),
+			Response: strings.TrimSuffix(buf.String(), "\n"),
+			Duration: time.Since(start),
+			Parsed:   executor.parsed,
+		}
+
+		if err != nil {
+			record.Error = err.Error()
+		}
+
+		if err := emit(record); err != nil {
+			return fmt.Errorf("output: %w", err)
+		}
+
+		if err != nil && !ses.SkipErrors {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// structured executes commands and prints their records in the output
+// format. Commands of the broadcast cluster are executed on every address.
+func (executor *Executor) structured(ses *config.Session, commands []string, format string) error {
+	rw, err := newRecordWriter(executor.w, format)
+	if err != nil {
+		return err
+	}
+
+	if ses.ClusterMode == config.ClusterModeBroadcast && len(ses.Addresses) > 0 {
+		var errs []error
+
+		for _, address := range ses.Addresses {
+			addrSes := *ses
+			addrSes.Address, addrSes.Addresses = address, nil
+
+			exec := NewExecutor(nil, io.Discard, executor.version)
+			exec.ew = executor.ew
+			exec.run = executor.run
+
+			if err := exec.executeRecords(&addrSes, commands, rw.write); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", address, err))
+			}
+
+			_ = exec.Close()
+		}
+
+		err = errors.Join(errs...)
+	} else {
+		err = executor.executeRecords(ses, commands, rw.write)
+	}
+
+	if flushErr := rw.flush(); flushErr != nil {
+		return fmt.Errorf("output: %w", flushErr)
+	}
+
+	return err
+}
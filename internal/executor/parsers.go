@@ -0,0 +1,140 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+	"github.com/gorcon/rcon-cli/internal/mccolor"
+)
+
+// responseParser is the compiled response parser of the session.
+type responseParser struct {
+	name   string
+	re     *regexp.Regexp
+	fields []string
+}
+
+// Response is the data of the response template.
+type Response struct {
+	Env      string
+	Command  string
+	Response string
+	// Parsed contains matches of the response parsers by parser name. Every
+	// match maps field names to the captured values.
+	Parsed map[string][]map[string]string
+}
+
+// compileParsers compiles response parsers of the session. Fields default
+// to all named groups of the pattern.
+func compileParsers(parsers []config.ResponseParser) ([]responseParser, error) {
+	compiled := make([]responseParser, 0, len(parsers))
+
+	for _, p := range parsers {
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("response parser %s: %w", p.Name, err)
+		}
+
+		fields := p.Fields
+		if len(fields) == 0 {
+			for _, name := range re.SubexpNames() {
+				if name != "" {
+					fields = append(fields, name)
+				}
+			}
+		}
+
+		compiled = append(compiled, responseParser{name: p.Name, re: re, fields: fields})
+	}
+
+	return compiled, nil
+}
+
+// parseResponse runs the parsers in order and extracts fields of every
+// match. Parsers without matches are omitted.
+func parseResponse(parsers []responseParser, response string) map[string][]map[string]string {
+	parsed := make(map[string][]map[string]string)
+
+	for _, p := range parsers {
+		for _, match := range p.re.FindAllStringSubmatch(response, -1) {
+			fields := make(map[string]string, len(p.fields))
+			for _, field := range p.fields {
+				if i := p.re.SubexpIndex(field); i >= 0 {
+					fields[field] = match[i]
+				}
+			}
+
+			parsed[p.name] = append(parsed[p.name], fields)
+		}
+	}
+
+	return parsed
+}
+
+// print writes the response to w. If the response template is set the
+// response is rendered with the parsed fields. Minecraft formatting codes
+// are rendered before parsing. Parsed fields are kept for the record of the
+// structured output.
+func (executor *Executor) print(w io.Writer, ses *config.Session, command string, result string) error {
+	result = executor.colorize(ses, result)
+
+	executor.parsed = nil
+	if len(executor.parsers) > 0 {
+		executor.parsed = parseResponse(executor.parsers, result)
+	}
+
+	if executor.template == nil {
+		_, _ = fmt.Fprintln(w, result)
+
+		return nil
+	}
+
+	var buf strings.Builder
+
+	err := executor.template.Execute(&buf, Response{
+		Env:      ses.Env,
+		Command:  command,
+		Response: result,
+		Parsed:   executor.parsed,
+	})
+	if err != nil {
+		return fmt.Errorf("response template: %w", err)
+	}
+
+	_, _ = fmt.Fprintln(w, strings.TrimSuffix(buf.String(), "\n"))
+
+	return nil
+}
+
+// colorize renders Minecraft formatting codes of the response in the color
+// mode of the session. Auto mode checks the executor output, because the
+// response can be buffered before it is printed.
+func (executor *Executor) colorize(ses *config.Session, result string) string {
+	if ses.Color == config.ColorNever {
+		return result
+	}
+
+	if ses.ChatComponents {
+		if text, ok := mccolor.FromJSON(result); ok {
+			result = text
+		}
+	}
+
+	mode := ses.Color
+	if mode == "" || mode == config.ColorAuto {
+		mode = config.ColorStrip
+		if _, ok := os.LookupEnv("NO_COLOR"); !ok && isTerminal(executor.w) {
+			mode = config.ColorAlways
+		}
+	}
+
+	if mode == config.ColorAlways {
+		return mccolor.ToANSI(result)
+	}
+
+	return mccolor.Strip(result)
+}
@@ -0,0 +1,46 @@
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+	"golang.org/x/term"
+)
+
+// PassphraseEnv is the environment variable with the passphrase of the
+// encrypted config file.
+const PassphraseEnv = "RCON_CONFIG_PASSPHRASE"
+
+// passphrase returns the source of the passphrase of encrypted config files:
+// the key file, PassphraseEnv or the prompt if stdin is a terminal. The
+// passphrase is read once and reused by every config read and write.
+func (executor *Executor) passphrase(keyFile string) func() ([]byte, error) {
+	return sync.OnceValues(func() ([]byte, error) {
+		if keyFile != "" {
+			data, err := os.ReadFile(keyFile)
+			if err != nil {
+				return nil, fmt.Errorf("key file: %w", err)
+			}
+
+			return bytes.TrimRight(data, "\r\n"), nil
+		}
+
+		if value := os.Getenv(PassphraseEnv); value != "" {
+			return []byte(value), nil
+		}
+
+		file, ok := executor.r.(*os.File)
+		if !ok || !term.IsTerminal(int(file.Fd())) {
+			return nil, fmt.Errorf("%w: add --key-file or set %s", config.ErrPassphraseRequired, PassphraseEnv)
+		}
+
+		_, _ = fmt.Fprint(executor.ew, "Config passphrase: ")
+		value, err := term.ReadPassword(int(file.Fd()))
+		_, _ = fmt.Fprintln(executor.ew)
+
+		return value, err
+	})
+}
@@ -0,0 +1,88 @@
+package executor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+)
+
+// Terminal control sequences of bracketed paste mode. When the mode is on,
+// the terminal wraps pasted text in pasteStart and pasteEnd.
+const (
+	bracketedPasteOn  = "\x1b[?2004h"
+	bracketedPasteOff = "\x1b[?2004l"
+	pasteStart        = "\x1b[200~"
+	pasteEnd          = "\x1b[201~"
+)
+
+// pasted returns commands of the scanned line. If the line starts the
+// bracketed paste, the following lines are read until the end of the paste
+// and multi-line paste is handled according to the session paste mode.
+// Without bracketed paste every line is a separate command.
+func (executor *Executor) pasted(scanner *bufio.Scanner, w io.Writer, ses *config.Session) []string {
+	line := scanner.Text()
+	if !strings.HasPrefix(line, pasteStart) {
+		return []string{line}
+	}
+
+	lines := []string{strings.TrimPrefix(line, pasteStart)}
+	for !strings.Contains(lines[len(lines)-1], pasteEnd) && scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	// Text typed after the paste on the same line is kept.
+	last := len(lines) - 1
+	lines[last] = strings.Replace(lines[last], pasteEnd, "", 1)
+
+	if lines[last] == "" && last > 0 {
+		lines = lines[:last]
+	}
+
+	if len(lines) == 1 {
+		return lines
+	}
+
+	mode := ses.PasteMode
+	if mode == config.PasteModeAsk || mode == "" {
+		mode = executor.askPasteMode(scanner, w, lines)
+	}
+
+	switch mode {
+	case config.PasteModeSeparate:
+		return lines
+	case config.PasteModeJoin:
+		return []string{strings.Join(lines, " ")}
+	default:
+		_, _ = fmt.Fprintln(w, "Paste canceled")
+
+		return nil
+	}
+}
+
+// askPasteMode shows the pasted lines and asks how to send them. Returns
+// empty string if the paste is canceled.
+func (executor *Executor) askPasteMode(scanner *bufio.Scanner, w io.Writer, lines []string) string {
+	_, _ = fmt.Fprintf(w, "Pasted %d lines:\n", len(lines))
+
+	for i, line := range lines {
+		_, _ = fmt.Fprintf(w, "%4d | %s\n", i+1, line)
+	}
+
+	executor.prompt(w, fmt.Sprintf("Send as %d [s]eparate commands, [j]oin into one command or [c]ancel? [s/j/C]: ", len(lines)))
+
+	if !scanner.Scan() {
+		return ""
+	}
+
+	switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+	case "s", config.PasteModeSeparate:
+		return config.PasteModeSeparate
+	case "j", config.PasteModeJoin:
+		return config.PasteModeJoin
+	default:
+		return ""
+	}
+}
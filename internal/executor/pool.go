@@ -0,0 +1,641 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/gorcon/rcon-cli/internal/config"
+	"github.com/urfave/cli/v2"
+)
+
+// DefaultPoolIdle is how long the broker keeps unused connections. The
+// broker exits when it has no connections left.
+const DefaultPoolIdle = 10 * time.Minute
+
+// poolMaxConns is the number of connections the broker opens per session,
+// parallel invocations over more connections wait for a free one.
+const poolMaxConns = 4
+
+// poolSpawnTimeout is how long the CLI waits for the spawned broker.
+const poolSpawnTimeout = 5 * time.Second
+
+// ErrPoolRunning is returned when the broker is started on the socket of
+// the running broker.
+var ErrPoolRunning = errors.New("pool broker is already running")
+
+// poolRequest is the body of /dial and /exec requests of the broker. The
+// session is resolved by the CLI, so the broker does not read the config.
+type poolRequest struct {
+	Env     string          `json:"env"`
+	Session *config.Session `json:"session"`
+	Command string          `json:"command,omitempty"`
+}
+
+// poolResponse is the body of successful /exec response.
+type poolResponse struct {
+	Response string `json:"response"`
+}
+
+// poolCloseRequest is the body of /sessions/close request. Empty Envs close
+// all sessions and stop the broker.
+type poolCloseRequest struct {
+	Envs []string `json:"envs"`
+}
+
+// poolError is the error of the pooled connection. The exit code is taken
+// from the original error in the broker.
+type poolError struct {
+	Message  string `json:"error"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// Error implements error.
+func (e *poolError) Error() string {
+	return e.Message
+}
+
+// poolSession is the session in the output of `sessions list`.
+type poolSession struct {
+	Env         string    `json:"env"`
+	Address     string    `json:"address"`
+	Type        string    `json:"type"`
+	Connections int       `json:"connections"`
+	Commands    int       `json:"commands"`
+	LastUsed    time.Time `json:"last_used"`
+}
+
+// poolEnv keeps the authenticated connections of the session.
+type poolEnv struct {
+	key string
+	env string
+	ses *config.Session
+	// slots limits the connections in use.
+	slots chan struct{}
+
+	mu       sync.Mutex
+	idle     []*Executor
+	commands int
+	lastUsed time.Time
+	// closed is set when the session is removed from the broker, the
+	// connections in use are closed when they are released.
+	closed bool
+}
+
+// broker keeps connections of the sessions between invocations of the CLI.
+type broker struct {
+	version string
+	stop    context.CancelFunc
+
+	mu   sync.Mutex
+	envs map[string]*poolEnv
+}
+
+// poolSocket returns the socket of the broker: --pool-socket or pool.sock
+// in XDG runtime directory.
+func poolSocket(c *cli.Context) (string, error) {
+	if socket := c.String("pool-socket"); socket != "" {
+		return socket, nil
+	}
+
+	return xdg.RuntimeFile(filepath.Join("gorcon", "pool.sock"))
+}
+
+// poolBroker serves the pooled connections on the unix socket until all
+// connections are idle for --idle, `sessions close` or ^C.
+func (executor *Executor) poolBroker(c *cli.Context) error {
+	socket, err := poolSocket(c)
+	if err != nil {
+		return fmt.Errorf("pool: %w", err)
+	}
+
+	if conn, err := net.Dial("unix", socket); err == nil {
+		_ = conn.Close()
+
+		return fmt.Errorf("%w: %s", ErrPoolRunning, socket)
+	}
+
+	// The socket of the stopped broker is left behind.
+	_ = os.Remove(socket)
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return fmt.Errorf("pool: %w", err)
+	}
+
+	if err := os.Chmod(socket, 0o600); err != nil {
+		_ = listener.Close()
+
+		return fmt.Errorf("pool: %w", err)
+	}
+
+	// The spawned broker outlives the terminal of the CLI.
+	signal.Ignore(syscall.SIGHUP)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	b := &broker{version: executor.version, stop: stop, envs: make(map[string]*poolEnv)}
+	defer b.close(nil)
+
+	server := &http.Server{Handler: b.handler(), ReadHeaderTimeout: config.DefaultTimeout}
+
+	go b.expire(ctx, time.Duration(durationFlag(c, "idle")))
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+		defer cancel()
+
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	_, _ = fmt.Fprintf(executor.w, "Listening on unix://%s\n", socket)
+
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("pool: %w", err)
+	}
+
+	return nil
+}
+
+// handler returns the routes of the broker:
+//
+//	POST /dial           authenticate the connection of the session
+//	POST /exec           execute the command over the connection of the session
+//	GET  /sessions       pooled sessions
+//	POST /sessions/close close the sessions of the environments
+func (b *broker) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	for _, path := range []string{"/dial", "/exec"} {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			var req poolRequest
+			if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil || req.Session == nil {
+				writeJSON(w, http.StatusBadRequest, poolError{Message: "invalid request body", ExitCode: ExitCodeFailure})
+
+				return
+			}
+
+			response, err := b.execute(req, r.URL.Path == "/exec")
+			if err != nil {
+				writeJSON(w, http.StatusBadGateway, poolError{Message: err.Error(), ExitCode: ExitCode(err)})
+
+				return
+			}
+
+			writeJSON(w, http.StatusOK, poolResponse{Response: response})
+		})
+	}
+
+	mux.HandleFunc("/sessions", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, b.sessions())
+	})
+
+	mux.HandleFunc("/sessions/close", func(w http.ResponseWriter, r *http.Request) {
+		var req poolCloseRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, poolError{Message: "invalid request body", ExitCode: ExitCodeFailure})
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, b.close(req.Envs))
+
+		if len(req.Envs) == 0 {
+			b.stop()
+		}
+	})
+
+	return mux
+}
+
+// execute dials the free connection of the session and executes the
+// command if it is set. The failed connection is closed, the next request
+// dials the server again.
+func (b *broker) execute(req poolRequest, execute bool) (string, error) {
+	env := b.env(req)
+
+	env.slots <- struct{}{}
+	defer func() { <-env.slots }()
+
+	env.mu.Lock()
+	var conn *Executor
+	if n := len(env.idle); n > 0 {
+		conn, env.idle = env.idle[n-1], env.idle[:n-1]
+	} else {
+		conn = NewExecutor(nil, io.Discard, b.version)
+		conn.ew = io.Discard
+		conn.run = newRun("")
+	}
+	env.mu.Unlock()
+
+	// Dial changes the address of the session.
+	ses := *env.ses
+
+	var response string
+
+	err := conn.dial(&ses)
+	if err == nil && execute {
+		response, err = executeClient(conn.client, &ses, req.Command)
+	}
+
+	env.mu.Lock()
+
+	if err != nil || env.closed {
+		_ = conn.disconnect()
+	} else {
+		env.idle = append(env.idle, conn)
+	}
+
+	if execute {
+		env.commands++
+	}
+
+	env.lastUsed = time.Now()
+
+	// Sessions which never connected, e.g. with the wrong password, are
+	// not kept.
+	unused := err != nil && env.commands == 0 && len(env.idle) == 0
+	env.mu.Unlock()
+
+	if unused {
+		b.mu.Lock()
+		if b.envs[env.key] == env {
+			delete(b.envs, env.key)
+		}
+		b.mu.Unlock()
+	}
+
+	return response, err
+}
+
+// env returns the pooled session of the request. Sessions differ in the
+// environment and in any setting, e.g. the password changed in the config.
+func (b *broker) env(req poolRequest) *poolEnv {
+	data, _ := json.Marshal(req.Session)
+	sum := sha256.Sum256(append([]byte(req.Env+"\x00"), data...))
+	key := hex.EncodeToString(sum[:])
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	env, ok := b.envs[key]
+	if !ok {
+		req.Session.Env = req.Env
+		env = &poolEnv{key: key, env: req.Env, ses: req.Session, slots: make(chan struct{}, poolMaxConns), lastUsed: time.Now()}
+		b.envs[key] = env
+	}
+
+	return env
+}
+
+// sessions returns the pooled sessions sorted by environment.
+func (b *broker) sessions() []poolSession {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sessions := make([]poolSession, 0, len(b.envs))
+
+	for _, env := range b.envs {
+		env.mu.Lock()
+		sessions = append(sessions, poolSession{
+			Env: env.env, Address: config.AddressWithPort(env.ses.Address, env.ses.Type), Type: env.ses.Type,
+			Connections: len(env.idle) + len(env.slots), Commands: env.commands, LastUsed: env.lastUsed,
+		})
+		env.mu.Unlock()
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].Env != sessions[j].Env {
+			return sessions[i].Env < sessions[j].Env
+		}
+
+		return sessions[i].Address < sessions[j].Address
+	})
+
+	return sessions
+}
+
+// close closes idle connections of the environments, all environments if
+// envs is empty, and returns the closed sessions. Connections in use are
+// closed when they are released.
+func (b *broker) close(envs []string) []poolSession {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	closed := []poolSession{}
+
+	for key, env := range b.envs {
+		if len(envs) > 0 && !slices.Contains(envs, env.env) {
+			continue
+		}
+
+		env.mu.Lock()
+		for _, conn := range env.idle {
+			_ = conn.Close()
+		}
+
+		closed = append(closed, poolSession{
+			Env: env.env, Address: config.AddressWithPort(env.ses.Address, env.ses.Type), Type: env.ses.Type,
+			Connections: len(env.idle), Commands: env.commands, LastUsed: env.lastUsed,
+		})
+		env.idle, env.closed = nil, true
+		env.mu.Unlock()
+
+		delete(b.envs, key)
+	}
+
+	return closed
+}
+
+// expire closes the sessions which are unused for the idle timeout and
+// stops the broker when no sessions are left.
+func (b *broker) expire(ctx context.Context, idle time.Duration) {
+	if idle <= 0 {
+		idle = DefaultPoolIdle
+	}
+
+	ticker := time.NewTicker(idle / 4)
+	defer ticker.Stop()
+
+	started := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		b.mu.Lock()
+		for key, env := range b.envs {
+			env.mu.Lock()
+			if len(env.slots) == 0 && time.Since(env.lastUsed) > idle {
+				for _, conn := range env.idle {
+					_ = conn.Close()
+				}
+
+				env.idle, env.closed = nil, true
+				delete(b.envs, key)
+			}
+			env.mu.Unlock()
+		}
+
+		empty := len(b.envs) == 0
+		b.mu.Unlock()
+
+		if empty && time.Since(started) > idle {
+			b.stop()
+
+			return
+		}
+	}
+}
+
+// poolClient executes the commands over the connection kept by the broker.
+type poolClient struct {
+	http *http.Client
+	env  string
+	ses  *config.Session
+}
+
+// newPoolClient authenticates the connection of the session in the broker.
+// The broker is spawned if it is not running.
+func newPoolClient(socket string, ses *config.Session) (*poolClient, error) {
+	client := &poolClient{http: poolHTTPClient(socket), env: ses.Env, ses: ses}
+
+	var poolErr *poolError
+
+	err := poolPost(client.http, "/dial", poolRequest{Env: client.env, Session: ses}, nil)
+	if err != nil && !errors.As(err, &poolErr) {
+		if err := spawnBroker(socket); err != nil {
+			return nil, err
+		}
+
+		err = poolPost(client.http, "/dial", poolRequest{Env: client.env, Session: ses}, nil)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// Execute sends the command to the broker and returns the response.
+func (p *poolClient) Execute(command string) (string, error) {
+	var response poolResponse
+	if err := poolPost(p.http, "/exec", poolRequest{Env: p.env, Session: p.ses, Command: command}, &response); err != nil {
+		return "", err
+	}
+
+	return response.Response, nil
+}
+
+// Close keeps the connection in the broker for the next invocation.
+func (p *poolClient) Close() error {
+	p.http.CloseIdleConnections()
+
+	return nil
+}
+
+// poolHTTPClient returns the HTTP client of the broker on the socket.
+func poolHTTPClient(socket string) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+
+			return dialer.DialContext(ctx, "unix", socket)
+		},
+	}}
+}
+
+// poolPost sends the request to the broker and decodes the response.
+// Errors of the pooled connection are returned as *poolError.
+func poolPost(client *http.Client, path string, body any, result any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("pool: %w", err)
+	}
+
+	resp, err := client.Post("http://pool"+path, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("pool: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		poolErr := &poolError{ExitCode: ExitCodeFailure}
+		if err := json.NewDecoder(resp.Body).Decode(poolErr); err != nil || poolErr.Message == "" {
+			poolErr.Message = "pool: " + resp.Status
+		}
+
+		return poolErr
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("pool: %w", err)
+	}
+
+	return nil
+}
+
+// spawnBroker starts the broker in the background and waits until it
+// listens on the socket.
+func spawnBroker(socket string) error {
+	name, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("pool: %w", err)
+	}
+
+	cmd := exec.Command(name, "--pool-socket="+socket, "sessions", "broker")
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("pool: %w", err)
+	}
+
+	_ = cmd.Process.Release()
+
+	deadline := time.Now().Add(poolSpawnTimeout)
+
+	for {
+		conn, err := net.Dial("unix", socket)
+		if err == nil {
+			return conn.Close()
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("pool: broker is not started: %w", err)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// dialPool authenticates the connection of the session in the broker. The
+// server is dialed directly if the broker can not be started.
+func (executor *Executor) dialPool(ses *config.Session) error {
+	client, err := newPoolClient(executor.pool, ses)
+
+	var poolErr *poolError
+	if err != nil && !errors.As(err, &poolErr) {
+		_, _ = fmt.Fprintf(executor.ew, "%s, connecting directly\n", err)
+
+		executor.pool = ""
+
+		return executor.dial(ses)
+	}
+
+	// Errors of the broker are prefixed by dial.
+	if err != nil {
+		return err
+	}
+
+	executor.client = client
+	executor.fresh = true
+
+	return nil
+}
+
+// sessionsList prints the sessions kept by the broker.
+func (executor *Executor) sessionsList(c *cli.Context) error {
+	sessions := []poolSession{}
+
+	if err := executor.poolCall(c, http.MethodGet, "/sessions", nil, &sessions); err != nil {
+		return err
+	}
+
+	switch output := c.String("output"); output {
+	case OutputText:
+		tw := tabwriter.NewWriter(executor.w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "ENV\tADDRESS\tTYPE\tCONNECTIONS\tCOMMANDS\tIDLE")
+
+		for _, s := range sessions {
+			idle := time.Since(s.LastUsed).Truncate(time.Second)
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%s\n", s.Env, s.Address, s.Type, s.Connections, s.Commands, idle)
+		}
+
+		return tw.Flush()
+	case OutputJSON:
+		encoder := json.NewEncoder(executor.w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(sessions)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedOutput, output)
+	}
+}
+
+// sessionsClose closes the sessions of the environments in the arguments.
+// Without arguments all sessions are closed and the broker stops.
+func (executor *Executor) sessionsClose(c *cli.Context) error {
+	closed := []poolSession{}
+
+	if err := executor.poolCall(c, http.MethodPost, "/sessions/close", poolCloseRequest{Envs: c.Args().Slice()}, &closed); err != nil {
+		return err
+	}
+
+	for _, s := range closed {
+		_, _ = fmt.Fprintf(executor.w, "Closed %s session %s\n", s.Env, s.Address)
+	}
+
+	return nil
+}
+
+// poolCall sends the request to the broker of sessions commands. The
+// stopped broker has no sessions, so its requests are not sent.
+func (executor *Executor) poolCall(c *cli.Context, method string, path string, body any, result any) error {
+	socket, err := poolSocket(c)
+	if err != nil {
+		return fmt.Errorf("pool: %w", err)
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil
+	}
+
+	_ = conn.Close()
+
+	client := poolHTTPClient(socket)
+	defer client.CloseIdleConnections()
+
+	if method == http.MethodGet {
+		resp, err := client.Get("http://pool" + path)
+		if err != nil {
+			return fmt.Errorf("pool: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return fmt.Errorf("pool: %w", err)
+		}
+
+		return nil
+	}
+
+	return poolPost(client, path, body, result)
+}
@@ -0,0 +1,210 @@
+package executor
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+)
+
+// ErrProxy is returned when the proxy refuses to connect to the server.
+var ErrProxy = errors.New("proxy refused the connection")
+
+// SOCKS5 protocol values of RFC 1928 and RFC 1929.
+const (
+	socksVersion      = 5
+	socksAuthNone     = 0
+	socksAuthPassword = 2
+	socksConnect      = 1
+	socksDomain       = 3
+	socksIPv4         = 1
+	socksIPv6         = 4
+)
+
+// dialProxy connects to the address through SOCKS5 or HTTP CONNECT proxy.
+// The handshake must complete within the timeout unless it is zero.
+func dialProxy(proxy string, address string, timeout time.Duration) (net.Conn, error) {
+	u, err := config.ParseProxy(proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialTimeout("tcp", u.Host, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("proxy: %w", err)
+	}
+
+	if timeout > 0 {
+		if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+			_ = conn.Close()
+
+			return nil, fmt.Errorf("proxy: %w", err)
+		}
+	}
+
+	if u.Scheme == config.ProxySOCKS5 {
+		err = socksConnectTo(conn, u.User, address)
+	} else {
+		conn, err = httpConnectTo(conn, u.User, address)
+	}
+
+	if err == nil {
+		err = conn.SetDeadline(time.Time{})
+	}
+
+	if err != nil {
+		_ = conn.Close()
+
+		return nil, fmt.Errorf("proxy: %w", err)
+	}
+
+	return conn, nil
+}
+
+// socksConnectTo authorizes on SOCKS5 proxy and asks it to connect to the
+// address. The host name is resolved by the proxy.
+func socksConnectTo(conn net.Conn, user *url.Userinfo, address string) error {
+	host, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port %q", portString)
+	}
+
+	method := byte(socksAuthNone)
+	if user != nil {
+		method = socksAuthPassword
+	}
+
+	if _, err := conn.Write([]byte{socksVersion, 1, method}); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+
+	if reply[0] != socksVersion || reply[1] != method {
+		return fmt.Errorf("%w: unsupported auth method", ErrProxy)
+	}
+
+	if user != nil {
+		password, _ := user.Password()
+
+		request := []byte{1, byte(len(user.Username()))}
+		request = append(request, user.Username()...)
+		request = append(request, byte(len(password)))
+		request = append(request, password...)
+
+		if _, err := conn.Write(request); err != nil {
+			return err
+		}
+
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return err
+		}
+
+		if reply[1] != 0 {
+			return fmt.Errorf("%w: auth failed", ErrProxy)
+		}
+	}
+
+	request := []byte{socksVersion, socksConnect, 0}
+	if ip := net.ParseIP(host); ip.To4() != nil {
+		request = append(append(request, socksIPv4), ip.To4()...)
+	} else if ip != nil {
+		request = append(append(request, socksIPv6), ip...)
+	} else {
+		request = append(append(request, socksDomain, byte(len(host))), host...)
+	}
+
+	request = binary.BigEndian.AppendUint16(request, uint16(port))
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+
+	if header[1] != 0 {
+		return fmt.Errorf("%w: socks reply %d", ErrProxy, header[1])
+	}
+
+	// The bound address is not used but must be read.
+	var size int
+
+	switch header[3] {
+	case socksIPv4:
+		size = net.IPv4len
+	case socksIPv6:
+		size = net.IPv6len
+	case socksDomain:
+		if _, err := io.ReadFull(conn, header[:1]); err != nil {
+			return err
+		}
+
+		size = int(header[0])
+	default:
+		return fmt.Errorf("%w: unsupported socks address type %d", ErrProxy, header[3])
+	}
+
+	_, err = io.ReadFull(conn, make([]byte, size+2))
+
+	return err
+}
+
+// httpConnectTo asks HTTP proxy to connect to the address with CONNECT
+// request. Data the proxy sent after the response is kept in the returned
+// connection.
+func httpConnectTo(conn net.Conn, user *url.Userinfo, address string) (net.Conn, error) {
+	request := "CONNECT " + address + " HTTP/1.1\r\nHost: " + address + "\r\n"
+	if user != nil {
+		password, _ := user.Password()
+		request += "Proxy-Authorization: Basic " +
+			base64.StdEncoding.EncodeToString([]byte(user.Username()+":"+password)) + "\r\n"
+	}
+
+	if _, err := io.WriteString(conn, request+"\r\n"); err != nil {
+		return conn, err
+	}
+
+	r := bufio.NewReader(conn)
+
+	response, err := http.ReadResponse(r, &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		return conn, err
+	}
+
+	_ = response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return conn, fmt.Errorf("%w: %s", ErrProxy, response.Status)
+	}
+
+	return &bufferedConn{Conn: conn, r: r}, nil
+}
+
+// bufferedConn reads the connection through the buffered reader.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (bc *bufferedConn) Read(p []byte) (int, error) {
+	return bc.r.Read(p)
+}
@@ -0,0 +1,149 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/a2s"
+	"github.com/urfave/cli/v2"
+)
+
+// queryResult is the output of query command. Only requested parts are
+// set.
+type queryResult struct {
+	Info    *a2s.Info         `json:"info,omitempty"`
+	Players []a2s.Player      `json:"players,omitempty"`
+	Rules   map[string]string `json:"rules,omitempty"`
+}
+
+// query requests server info, players and rules over Source query protocol.
+// The address defaults to query_address or address of the environment.
+// Without flags only the info is requested.
+func (executor *Executor) query(c *cli.Context) error {
+	output := c.String("output")
+	if output != OutputText && output != OutputJSON {
+		return fmt.Errorf("%w: %s", ErrUnsupportedOutput, output)
+	}
+
+	ses, err := executor.NewSession(c)
+	if err != nil {
+		return err
+	}
+
+	address := c.Args().First()
+	if address == "" {
+		address = ses.QueryAddress
+	}
+
+	if address == "" {
+		address = ses.Address
+	}
+
+	if address == "" {
+		return ErrEmptyAddress
+	}
+
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, a2s.DefaultPort)
+	}
+
+	client, err := a2s.Dial(address, a2s.SetDialTimeout(ses.DialTimeout()), a2s.SetDeadline(ses.ExecuteTimeout()))
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+	defer client.Close()
+
+	var result queryResult
+
+	if c.Bool("info") || !c.Bool("players") && !c.Bool("rules") {
+		info, err := client.Info()
+		if err != nil {
+			return fmt.Errorf("query: info: %w", err)
+		}
+
+		result.Info = &info
+	}
+
+	if c.Bool("players") {
+		if result.Players, err = client.Players(); err != nil {
+			return fmt.Errorf("query: players: %w", err)
+		}
+	}
+
+	if c.Bool("rules") {
+		if result.Rules, err = client.Rules(); err != nil {
+			return fmt.Errorf("query: rules: %w", err)
+		}
+	}
+
+	if output == OutputJSON {
+		encoder := json.NewEncoder(executor.w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(result)
+	}
+
+	return executor.printQuery(c, result)
+}
+
+// printQuery prints the query result as text.
+func (executor *Executor) printQuery(c *cli.Context, result queryResult) error {
+	tw := tabwriter.NewWriter(executor.w, 0, 0, 2, ' ', 0)
+
+	if info := result.Info; info != nil {
+		server := []string{info.ServerType, info.Environment}
+		if info.VAC {
+			server = append(server, "VAC")
+		}
+
+		if info.Private {
+			server = append(server, "password")
+		}
+
+		_, _ = fmt.Fprintf(tw, "Name:\t%s\n", info.Name)
+		_, _ = fmt.Fprintf(tw, "Map:\t%s\n", info.Map)
+		_, _ = fmt.Fprintf(tw, "Game:\t%s (%s)\n", info.Game, info.Folder)
+		_, _ = fmt.Fprintf(tw, "Players:\t%d/%d (%d bots)\n", info.Players, info.MaxPlayers, info.Bots)
+		_, _ = fmt.Fprintf(tw, "Server:\t%s\n", strings.Join(server, ", "))
+		_, _ = fmt.Fprintf(tw, "Version:\t%s\n", info.Version)
+		_, _ = fmt.Fprintf(tw, "Ping:\t%s\n", info.Ping.Round(time.Microsecond))
+	}
+
+	if c.Bool("players") {
+		if result.Info != nil {
+			_, _ = fmt.Fprintln(tw)
+		}
+
+		_, _ = fmt.Fprintln(tw, "NAME\tSCORE\tDURATION")
+
+		for _, player := range result.Players {
+			_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\n", player.Name, player.Score, player.Duration)
+		}
+	}
+
+	if c.Bool("rules") {
+		if result.Info != nil || c.Bool("players") {
+			_, _ = fmt.Fprintln(tw)
+		}
+
+		names := make([]string, 0, len(result.Rules))
+		for name := range result.Rules {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		_, _ = fmt.Fprintln(tw, "RULE\tVALUE")
+
+		for _, name := range names {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\n", name, result.Rules[name])
+		}
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,138 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"regexp"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+)
+
+// DefaultWaitInterval is the default pause between readiness checks of
+// --wait.
+const DefaultWaitInterval = 5 * time.Second
+
+// Readiness stages of the server which are reported by --wait. Stages are
+// checked in order and the first failed one is reported.
+const (
+	stagePortClosed       = "port closed"
+	stageAuthFailing      = "port open, auth failing"
+	stageCommandFailing   = "auth ok, ready command failing"
+	stageResponseMismatch = "auth ok, unexpected ready response"
+)
+
+// Wait errors.
+var (
+	// ErrNotReady is returned when the server did not become ready within
+	// the --wait duration.
+	ErrNotReady = errors.New("server is not ready")
+
+	// ErrWaitCanceled is returned when waiting for the server was canceled
+	// by interrupt signal.
+	ErrWaitCanceled = errors.New("waiting for server canceled")
+)
+
+// waitReady holds the process until the server passes all readiness stages:
+// the port accepts connections, auth succeeds and the ready command returns
+// the expected response. The progress is printed to stderr.
+func (executor *Executor) waitReady(ses *config.Session, timeout time.Duration, interval time.Duration) error {
+	var expect *regexp.Regexp
+
+	if ses.ReadyExpect != "" {
+		var err error
+		if expect, err = regexp.Compile(ses.ReadyExpect); err != nil {
+			return fmt.Errorf("wait: ready expect: %w", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	progress := isTerminal(executor.ew)
+	deadline := time.Now().Add(timeout)
+
+	for attempt := 1; ; attempt++ {
+		stage := executor.probe(ses, expect)
+		if progress {
+			_, _ = fmt.Fprint(executor.ew, "\r\033[K")
+		}
+
+		if stage == "" {
+			return nil
+		}
+
+		if !time.Now().Add(interval).Before(deadline) {
+			return fmt.Errorf("wait: %w within %s: %s (attempt %d)", ErrNotReady, timeout, stage, attempt)
+		}
+
+		if progress {
+			_, _ = fmt.Fprintf(executor.ew, "Waiting for %s: %s (attempt %d)", ses.Address, stage, attempt)
+		} else {
+			_, _ = fmt.Fprintf(executor.ew, "Waiting for %s: %s (attempt %d)\n", ses.Address, stage, attempt)
+		}
+
+		timer := time.NewTimer(interval)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			if progress {
+				_, _ = fmt.Fprintln(executor.ew)
+			}
+
+			return ErrWaitCanceled
+		case <-timer.C:
+		}
+	}
+}
+
+// probe checks readiness stages of the server once and returns the first
+// failed stage. Empty stage means the server is ready. Probe connections
+// are closed before return so a long boot does not exhaust connection slots
+// of the server.
+func (executor *Executor) probe(ses *config.Session, expect *regexp.Regexp) string {
+	// Connection established before, e.g. by the setup wizard, would hide
+	// the current state of the server.
+	_ = executor.disconnect()
+
+	defer func() {
+		_ = executor.disconnect()
+	}()
+
+	// The proxy command may be the only route to the server, the plain TCP
+	// check is meaningless then. BattlEye works over UDP, the address of
+	// Pterodactyl is the panel URL.
+	if ses.ProxyCommand == "" && ses.Type != config.ProtocolBattlEye && ses.Type != config.ProtocolPterodactyl {
+		conn, err := net.DialTimeout("tcp", config.AddressWithPort(ses.Address, ses.Type), ses.DialTimeout())
+		if err != nil {
+			return stagePortClosed
+		}
+
+		_ = conn.Close()
+	}
+
+	if err := executor.Dial(ses); err != nil {
+		return stageAuthFailing
+	}
+
+	if ses.ReadyCommand == "" {
+		return ""
+	}
+
+	response, err := executor.client.Execute(ses.SignCommand(ses.ReadyCommand))
+	if err != nil {
+		return stageCommandFailing
+	}
+
+	if expect != nil && !expect.MatchString(response) {
+		return stageResponseMismatch
+	}
+
+	return ""
+}
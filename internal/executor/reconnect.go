@@ -0,0 +1,69 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+)
+
+// isConnectionError reports whether the error means that the server refused
+// or dropped the connection, so connecting again can help. Timeouts are not
+// connection errors, the server could execute the command.
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return false
+	}
+
+	for _, target := range []error{
+		io.EOF, io.ErrUnexpectedEOF, net.ErrClosed, syscall.ECONNREFUSED, syscall.ECONNRESET, syscall.ECONNABORTED, syscall.EPIPE,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reconnect connects to the server again after the connection error. The
+// pause starts at the retry backoff of the session and doubles after every
+// attempt. Other errors and sessions without reconnect_retries return the
+// cause as is.
+func (executor *Executor) reconnect(ses *config.Session, cause error) error {
+	if ses.ReconnectRetries <= 0 || !isConnectionError(cause) {
+		return cause
+	}
+
+	backoff := time.Duration(ses.RetryBackoff)
+	if backoff == 0 {
+		backoff = config.DefaultRetryBackoff
+	}
+
+	err := cause
+
+	for attempt := 1; attempt <= ses.ReconnectRetries; attempt++ {
+		_ = executor.disconnect()
+
+		_, _ = fmt.Fprintf(executor.ew, "Reconnecting to %s in %s: %s (attempt %d of %d)\n",
+			ses.Address, backoff, err, attempt, ses.ReconnectRetries)
+
+		time.Sleep(backoff)
+		backoff *= 2
+
+		if err = executor.Dial(ses); err == nil {
+			return nil
+		}
+
+		if !isConnectionError(err) {
+			return err
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,55 @@
+package executor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+)
+
+// callRetry executes command on the remote server and repeats it while the
+// response matches retry_on patterns of the session. The banner is removed
+// from the response before matching. Response of the last attempt is
+// returned even if it still matches.
+func (executor *Executor) callRetry(ses *config.Session, command string) (string, error) {
+	retries := ses.MaxRetries
+	if retries == 0 {
+		retries = config.DefaultMaxRetries
+	}
+
+	backoff := time.Duration(ses.RetryBackoff)
+	if backoff == 0 {
+		backoff = config.DefaultRetryBackoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		result, err := executor.call(ses, command)
+		result = executor.cutBanner(ses, result)
+
+		if err != nil || attempt > retries || !executor.retryMatch(result) {
+			return result, err
+		}
+
+		_, _ = fmt.Fprintf(executor.ew, "Retrying %q in %s: response matches retry_on (retry %d of %d)\n",
+			executor.redactor.Redact(command), backoff, attempt, retries)
+
+		time.Sleep(backoff)
+		backoff *= 2
+
+		// Server could close the connection while it was starting up.
+		if err := executor.Dial(ses); err != nil {
+			return result, err
+		}
+	}
+}
+
+// retryMatch reports whether the response matches any retry_on pattern.
+func (executor *Executor) retryMatch(response string) bool {
+	for _, re := range executor.retryOn {
+		if re.MatchString(response) {
+			return true
+		}
+	}
+
+	return false
+}
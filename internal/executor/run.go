@@ -0,0 +1,40 @@
+package executor
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// runIDLength is the number of random bytes in the generated run ID.
+const runIDLength = 4
+
+// run identifies the invocation of the CLI. The ID and the sequence number
+// of the command are written to the changes file and to JSON outputs, so
+// the artifacts of the same run can be correlated.
+type run struct {
+	id  string
+	seq int
+}
+
+// newRun creates a run with the ID. Random ID is generated if it is empty.
+func newRun(id string) *run {
+	if id == "" {
+		b := make([]byte, runIDLength)
+		_, _ = rand.Read(b)
+		id = hex.EncodeToString(b)
+	}
+
+	return &run{id: id}
+}
+
+// nextSeq returns the sequence number of the next executed command in the
+// run.
+func (executor *Executor) nextSeq() int {
+	if executor.run == nil {
+		executor.run = newRun("")
+	}
+
+	executor.run.seq++
+
+	return executor.run.seq
+}
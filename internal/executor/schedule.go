@@ -0,0 +1,155 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+	"github.com/urfave/cli/v2"
+)
+
+// ScheduleDialLead is the duration before the scheduled moment when the
+// connection to the remote server is established.
+const ScheduleDialLead = 5 * time.Second
+
+// Schedule errors.
+var (
+	// ErrScheduleCanceled is returned when scheduled execution was canceled
+	// by interrupt signal.
+	ErrScheduleCanceled = errors.New("scheduled execution canceled")
+
+	// ErrScheduleInPast is returned when --at time has already passed today
+	// and rolling to tomorrow is not allowed.
+	ErrScheduleInPast = errors.New("scheduled time has already passed: add --at-tomorrow to run it tomorrow")
+
+	// ErrScheduleConflict is returned when both --at and --in flags are set.
+	ErrScheduleConflict = errors.New("flags --at and --in cannot be used together")
+
+	// ErrScheduleInteractive is returned when schedule flags are used without
+	// commands.
+	ErrScheduleInteractive = errors.New("scheduled execution requires commands to execute")
+)
+
+// scheduledAt returns the moment when the commands must be executed. Zero
+// time is returned if no schedule flags are set.
+func scheduledAt(c *cli.Context, now time.Time) (time.Time, error) {
+	at, in := c.String("at"), time.Duration(durationFlag(c, "in"))
+
+	switch {
+	case at != "" && in != 0:
+		return time.Time{}, ErrScheduleConflict
+	case in != 0:
+		return now.Add(in), nil
+	case at == "":
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, at); err == nil {
+		if t.Before(now) {
+			return time.Time{}, ErrScheduleInPast
+		}
+
+		return t, nil
+	}
+
+	var clock time.Time
+	var err error
+
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if clock, err = time.ParseInLocation(layout, at, now.Location()); err == nil {
+			break
+		}
+	}
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse --at %q: expected HH:MM, HH:MM:SS or RFC3339 time", at)
+	}
+
+	t := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, now.Location())
+	if t.Before(now) {
+		if !c.Bool("at-tomorrow") {
+			return time.Time{}, ErrScheduleInPast
+		}
+
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return t, nil
+}
+
+// wait holds the process until the scheduled moment. The countdown is
+// printed to stderr if it is a terminal. The connection of the session is
+// established ScheduleDialLead before the moment, nil session is not
+// dialed.
+func (executor *Executor) wait(ses *config.Session, at time.Time) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	countdown := isTerminal(executor.ew)
+	dialAt := at.Add(-ScheduleDialLead)
+	dialed := ses == nil
+
+	for {
+		now := time.Now()
+		left := at.Sub(now)
+
+		if left <= 0 {
+			if countdown {
+				_, _ = fmt.Fprint(executor.ew, "\r\033[K")
+			}
+
+			return nil
+		}
+
+		if !dialed && !now.Before(dialAt) {
+			if err := executor.Dial(ses); err != nil {
+				return fmt.Errorf("execute: %w", err)
+			}
+
+			dialed = true
+		}
+
+		if countdown {
+			_, _ = fmt.Fprintf(executor.ew, "\r\033[KExecuting in %s (press ^C to cancel)", left.Round(time.Second))
+		}
+
+		step := left % time.Second
+		if step == 0 {
+			step = time.Second
+		}
+
+		if !dialed && dialAt.Sub(now) < step {
+			step = dialAt.Sub(now)
+		}
+
+		timer := time.NewTimer(step)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			if countdown {
+				_, _ = fmt.Fprintln(executor.ew)
+			}
+
+			return ErrScheduleCanceled
+		case <-timer.C:
+		}
+	}
+}
+
+// isTerminal reports whether the reader or writer is a terminal.
+func isTerminal(v any) bool {
+	file, ok := v.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := file.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,78 @@
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+	"github.com/gorcon/rcon-cli/internal/script"
+	"github.com/urfave/cli/v2"
+)
+
+// scriptRuntime executes the script commands over the connection to the
+// current environment of the script.
+type scriptRuntime struct {
+	executor *Executor
+	c        *cli.Context
+	ses      *config.Session
+	conn     *Executor
+}
+
+// runScript executes the script file.
+func (executor *Executor) runScript(c *cli.Context) error {
+	s, err := script.Load(c.String("script"))
+	if err != nil {
+		return err
+	}
+
+	rt := &scriptRuntime{executor: executor, c: c}
+	defer rt.close()
+
+	return s.Run(rt)
+}
+
+// Connect closes the connection to the previous environment and switches
+// the following commands to env.
+func (rt *scriptRuntime) Connect(env string) error {
+	ses, err := rt.executor.newSession(rt.c, env)
+	if err != nil {
+		return err
+	}
+
+	rt.close()
+
+	rt.ses = ses
+	rt.conn = NewExecutor(nil, io.Discard, rt.executor.version)
+	// Commands of all environments are numbered in the same run.
+	rt.conn.run = rt.executor.run
+
+	return nil
+}
+
+// Send executes the command, prints and returns its response. Commands
+// before the first CONNECT are sent to the --env environment.
+func (rt *scriptRuntime) Send(command string) (string, error) {
+	if rt.conn == nil {
+		if err := rt.Connect(rt.c.String("env")); err != nil {
+			return "", err
+		}
+	}
+
+	w := bytes.Buffer{}
+	if err := rt.conn.Execute(&w, rt.ses, command); err != nil {
+		return "", err
+	}
+
+	_, _ = fmt.Fprint(rt.executor.w, w.String())
+
+	return strings.TrimSpace(w.String()), nil
+}
+
+// close closes the connection to the current environment.
+func (rt *scriptRuntime) close() {
+	if rt.conn != nil {
+		_ = rt.conn.Close()
+	}
+}
@@ -0,0 +1,78 @@
+package executor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+	"github.com/urfave/cli/v2"
+)
+
+// ErrEmptySecret is returned when `secret set` reads empty password.
+var ErrEmptySecret = errors.New("secret is empty")
+
+// secretRef returns the keyring reference from the argument. Defaults to
+// password_keyring of --env environment or to the reference of the
+// environment name in DefaultKeyringService.
+func secretRef(c *cli.Context) string {
+	if ref := c.Args().First(); ref != "" {
+		return ref
+	}
+
+	env := c.String("env")
+	if env == "" {
+		env = config.DefaultConfigEnv
+	}
+
+	if cfg, err := config.NewConfig(c.String("config")); err == nil {
+		if ses, err := cfg.GetEnv(env); err == nil && ses.PasswordKeyring != "" {
+			return ses.PasswordKeyring
+		}
+	}
+
+	return config.DefaultKeyringService + "/" + env
+}
+
+// secretSet stores the password in the keyring. The password is read
+// without echo if stdin is a terminal.
+func (executor *Executor) secretSet(c *cli.Context) error {
+	ref := secretRef(c)
+
+	password := askPassword(bufio.NewReader(executor.r), executor.r, executor.ew)
+	if password == "" {
+		return fmt.Errorf("secret: %w", ErrEmptySecret)
+	}
+
+	if err := config.StorePassword(ref, password); err != nil {
+		return fmt.Errorf("secret: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(executor.ew, "Stored password in keyring %s\n", ref)
+
+	return nil
+}
+
+// secretGet prints the password stored in the keyring.
+func (executor *Executor) secretGet(c *cli.Context) error {
+	password, err := config.LoadPassword(secretRef(c))
+	if err != nil {
+		return fmt.Errorf("secret: %w", err)
+	}
+
+	_, _ = fmt.Fprintln(executor.w, password)
+
+	return nil
+}
+
+// secretDelete removes the password from the keyring.
+func (executor *Executor) secretDelete(c *cli.Context) error {
+	ref := secretRef(c)
+	if err := config.DeletePassword(ref); err != nil {
+		return fmt.Errorf("secret: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(executor.ew, "Deleted password from keyring %s\n", ref)
+
+	return nil
+}
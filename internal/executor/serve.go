@@ -0,0 +1,274 @@
+package executor
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+	"github.com/urfave/cli/v2"
+)
+
+// DefaultServeListen is the default address of the HTTP API of serve
+// command. The API executes commands without the password, so it listens
+// on the loopback interface.
+const DefaultServeListen = "127.0.0.1:8080"
+
+// serveShutdownTimeout is how long serve waits for the running requests
+// on ^C.
+const serveShutdownTimeout = 10 * time.Second
+
+// execRequest is the body of `POST /env/{name}/exec`. Command is added
+// before Commands.
+type execRequest struct {
+	Command  string   `json:"command"`
+	Commands []string `json:"commands"`
+}
+
+// apiError is the body of failed API responses.
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// apiEnv keeps the connection of the environment between requests. Commands
+// of the environment are executed one request at a time.
+type apiEnv struct {
+	mu   sync.Mutex
+	ses  *config.Session
+	exec *Executor
+}
+
+// api is the HTTP API of serve command.
+type api struct {
+	executor *Executor
+	c        *cli.Context
+	token    string
+
+	mu   sync.Mutex
+	envs map[string]*apiEnv
+}
+
+// serve starts the HTTP API which executes commands on the config
+// environments over persistent connections until ^C.
+func (executor *Executor) serve(c *cli.Context) error {
+	cfg, err := config.NewConfig(c.String("config"))
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	a := &api{executor: executor, c: c, token: c.String("token"), envs: make(map[string]*apiEnv)}
+	defer a.close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	listener, err := net.Listen("tcp", c.String("listen"))
+	if err != nil {
+		return fmt.Errorf("serve: %w", err)
+	}
+
+	server := &http.Server{Handler: a.handler(), ReadHeaderTimeout: config.DefaultTimeout}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+		defer cancel()
+
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	_, _ = fmt.Fprintf(executor.w, "Listening on http://%s\n", listener.Addr())
+
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serve: %w", err)
+	}
+
+	return nil
+}
+
+// handler returns the routes of the API:
+//
+//	GET  /env             names of the config environments
+//	POST /env/{name}/exec execute commands and return their records
+func (a *api) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/env", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+
+			return
+		}
+
+		cfg, err := config.NewConfig(a.c.String("config"))
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, apiError{Error: err.Error()})
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, cfg.Names())
+	})
+
+	mux.HandleFunc("/env/", func(w http.ResponseWriter, r *http.Request) {
+		name, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/env/"), "/")
+		if name == "" || action != "exec" {
+			writeJSON(w, http.StatusNotFound, apiError{Error: "not found"})
+
+			return
+		}
+
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+
+			return
+		}
+
+		a.exec(w, r, name)
+	})
+
+	return a.authorize(mux)
+}
+
+// authorize requires the bearer token if it is set.
+func (a *api) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if a.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, apiError{Error: "unauthorized"})
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// exec executes commands of the request on the environment. Records are
+// returned with status 200 if all commands succeeded and with status 502
+// otherwise.
+func (a *api) exec(w http.ResponseWriter, r *http.Request, name string) {
+	var req execRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid request body: " + err.Error()})
+
+		return
+	}
+
+	commands := req.Commands
+	if req.Command != "" {
+		commands = append([]string{req.Command}, commands...)
+	}
+
+	if len(commands) == 0 {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: ErrCommandEmpty.Error()})
+
+		return
+	}
+
+	env, err := a.env(name)
+	if errors.Is(err, config.ErrSessionNotFound) {
+		writeJSON(w, http.StatusNotFound, apiError{Error: err.Error()})
+
+		return
+	}
+
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: err.Error()})
+
+		return
+	}
+
+	env.mu.Lock()
+	defer env.mu.Unlock()
+
+	records := []Record{}
+
+	err = env.exec.executeRecords(env.ses, commands, func(record Record) error {
+		records = append(records, record)
+
+		return nil
+	})
+	if err != nil {
+		// The next request dials the server again.
+		_ = env.exec.disconnect()
+
+		_, _ = fmt.Fprintf(a.executor.ew, "[%s] error: %s\n", name, err)
+
+		writeJSON(w, http.StatusBadGateway, records)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, records)
+}
+
+// env returns the environment with the kept connection. The session is
+// created on the first request to the environment, only environments of
+// the config file are allowed.
+func (a *api) env(name string) (*apiEnv, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if env, ok := a.envs[name]; ok {
+		return env, nil
+	}
+
+	cfg, err := config.NewConfig(a.c.String("config"))
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	if _, err := cfg.GetEnv(name); err != nil {
+		return nil, err
+	}
+
+	ses, err := a.executor.newSession(a.c, name)
+	if err != nil {
+		return nil, err
+	}
+
+	exec := NewExecutor(nil, io.Discard, a.executor.version)
+	exec.ew = a.executor.ew
+	exec.run = newRun(a.executor.run.id)
+
+	env := &apiEnv{ses: ses, exec: exec}
+	a.envs[name] = env
+
+	return env, nil
+}
+
+// close closes connections of all environments.
+func (a *api) close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, env := range a.envs {
+		env.mu.Lock()
+		_ = env.exec.Close()
+		env.mu.Unlock()
+	}
+}
+
+// writeJSON writes the value as JSON response with the status.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(v)
+}
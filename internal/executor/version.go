@@ -0,0 +1,80 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/urfave/cli/v2"
+)
+
+// BuildInfo contains build metadata of the binary.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime"`
+	GoVersion string `json:"goVersion"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
+// SetBuild sets the commit and the build time of the binary which are
+// injected with ldflags.
+func (executor *Executor) SetBuild(commit, buildTime string) {
+	executor.commit, executor.buildTime = commit, buildTime
+}
+
+// buildInfo returns build metadata of the binary. Commit and build time
+// which were not injected with ldflags are taken from VCS info embedded by
+// the Go toolchain.
+func (executor *Executor) buildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   executor.version,
+		Commit:    executor.commit,
+		BuildTime: executor.buildTime,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = s.Value
+			}
+		}
+	}
+
+	return info
+}
+
+// printVersion prints build metadata of the binary as text or JSON.
+func (executor *Executor) printVersion(c *cli.Context) error {
+	info := executor.buildInfo()
+
+	if c.String("output") == OutputJSON {
+		if err := json.NewEncoder(executor.w).Encode(info); err != nil {
+			return fmt.Errorf("version: %w", err)
+		}
+
+		return nil
+	}
+
+	_, _ = fmt.Fprintf(executor.w, "%s version %s\n", c.App.Name, info.Version)
+
+	if info.Commit != "" {
+		_, _ = fmt.Fprintf(executor.w, "commit: %s\n", info.Commit)
+	}
+
+	if info.BuildTime != "" {
+		_, _ = fmt.Fprintf(executor.w, "build time: %s\n", info.BuildTime)
+	}
+
+	_, _ = fmt.Fprintf(executor.w, "go: %s %s/%s\n", info.GoVersion, info.OS, info.Arch)
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+)
+
+// ErrWatchInteractive is returned when --watch is used without commands.
+var ErrWatchInteractive = errors.New("watch mode requires commands to execute")
+
+// watchOptions contains the flags of watch mode.
+type watchOptions struct {
+	interval time.Duration
+	// diff prints the result only if it differs from the previous one.
+	diff bool
+	// count is the number of runs, zero means until interrupt.
+	count int
+}
+
+// watch executes the commands every interval over the kept connection and
+// prints every result after the timestamp. Failed runs are retried after
+// the backoff which starts at config.DefaultRetryBackoff and doubles up to
+// the interval. ^C stops watching.
+func (executor *Executor) watch(ses *config.Session, commands []string, opts watchOptions) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var previous string
+	var backoff time.Duration
+
+	for run := 1; ; run++ {
+		var buf bytes.Buffer
+
+		err := executor.Execute(&buf, ses, commands...)
+		if err != nil {
+			// The next run dials the server again.
+			_ = executor.disconnect()
+
+			_, _ = fmt.Fprintf(&buf, "error: %s\n", err)
+		}
+
+		if result := buf.String(); !opts.diff || run == 1 || result != previous {
+			_, _ = fmt.Fprintf(executor.w, "[%s]\n%s", time.Now().Format(time.DateTime), result)
+			previous = result
+		}
+
+		if opts.count > 0 && run >= opts.count {
+			return nil
+		}
+
+		pause := opts.interval
+		if err != nil {
+			backoff = min(max(2*backoff, config.DefaultRetryBackoff), opts.interval)
+			pause = backoff
+		} else {
+			backoff = 0
+		}
+
+		timer := time.NewTimer(pause)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return nil
+		case <-timer.C:
+		}
+	}
+}
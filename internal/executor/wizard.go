@@ -0,0 +1,165 @@
+package executor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+)
+
+// wizardProtocols contains protocols in the order they are offered by the
+// setup wizard.
+var wizardProtocols = []string{config.ProtocolRCON, config.ProtocolTELNET, config.ProtocolWebRCON, config.ProtocolBattlEye}
+
+// Wizard asks for connection details of a new environment, tests the
+// connection and offers to save the environment to the config file name.
+// It is started on the first run when no config file was found.
+func (executor *Executor) Wizard(r io.Reader, w io.Writer, ses *config.Session, name string) error {
+	br := bufio.NewReader(r)
+
+	_, _ = fmt.Fprintln(w, "No config file found, let's set up a connection.")
+
+	env := ask(br, w, "Environment name", config.DefaultConfigEnv)
+
+	if ses.Address = ask(br, w, "Remote host and port [ip:port]", ""); ses.Address == "" {
+		return ErrEmptyAddress
+	}
+
+	ses.Type = askProtocol(br, w)
+
+	if ses.Password == "" {
+		ses.Password = askPassword(br, r, w)
+	}
+
+	cfg := config.File{Config: config.Config{env: {Address: ses.Address, Password: ses.Password, Type: ses.Type}}}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(w, "Testing connection to %s... ", ses.Address)
+
+	if err := executor.Dial(ses); err != nil {
+		_, _ = fmt.Fprintln(w, "failed")
+
+		return err
+	}
+
+	_, _ = fmt.Fprintln(w, "ok")
+
+	if !confirm(br, w, fmt.Sprintf("Save %s environment to %s?", env, name), true) {
+		return nil
+	}
+
+	if err := cfg.WriteToFile(name); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(w, "Saved %s environment to %s\n", env, name)
+
+	if env != config.DefaultConfigEnv {
+		_, _ = fmt.Fprintf(w, "Add -e %s to connect next time\n", env)
+	}
+
+	return nil
+}
+
+// wizardAllowed reports whether the setup wizard can be started. It requires
+// a terminal and no config file on the disk.
+func (executor *Executor) wizardAllowed(c *cli.Context) bool {
+	if c.Bool("no-wizard") || !isTerminal(executor.r) || !isTerminal(executor.w) {
+		return false
+	}
+
+	cfg, err := config.NewConfig(c.String("config"))
+
+	return err == nil && slices.Equal(cfg.Sources(), []string{config.NoConfigFileSource})
+}
+
+// ask prints the question and reads the answer line. Empty answer is
+// replaced with the default value.
+func ask(br *bufio.Reader, w io.Writer, question string, value string) string {
+	if value != "" {
+		question += " [" + value + "]"
+	}
+
+	_, _ = fmt.Fprint(w, question+": ")
+
+	line, _ := br.ReadString('\n')
+	if line = strings.TrimSpace(line); line != "" {
+		return line
+	}
+
+	return value
+}
+
+// askProtocol prints the menu of protocols and reads the choice by number
+// or by name. The question is repeated until a valid answer is given.
+func askProtocol(br *bufio.Reader, w io.Writer) string {
+	_, _ = fmt.Fprintln(w, "Protocol type:")
+
+	for i, protocol := range wizardProtocols {
+		_, _ = fmt.Fprintf(w, "  %d) %s\n", i+1, protocol)
+	}
+
+	for {
+		_, _ = fmt.Fprint(w, "Choose [1]: ")
+
+		line, err := br.ReadString('\n')
+
+		answer := strings.TrimSpace(line)
+		if answer == "" {
+			answer = "1"
+		}
+
+		if n, err := strconv.Atoi(answer); err == nil && n > 0 && n <= len(wizardProtocols) {
+			return wizardProtocols[n-1]
+		}
+
+		if slices.Contains(wizardProtocols, answer) {
+			return answer
+		}
+
+		// Input is over, there is no one to ask again.
+		if err != nil {
+			return config.DefaultProtocol
+		}
+
+		_, _ = fmt.Fprintf(w, "Unknown protocol %q\n", answer)
+	}
+}
+
+// confirm asks the yes or no question. Empty answer means the default.
+func confirm(br *bufio.Reader, w io.Writer, question string, yes bool) bool {
+	hint := " [y/N]"
+	if yes {
+		hint = " [Y/n]"
+	}
+
+	answer := ask(br, w, question+hint, "")
+	if answer == "" {
+		return yes
+	}
+
+	return strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes")
+}
+
+// askPassword reads the password without echo if the reader is a terminal.
+func askPassword(br *bufio.Reader, r io.Reader, w io.Writer) string {
+	file, ok := r.(*os.File)
+	if !ok || !term.IsTerminal(int(file.Fd())) {
+		return ask(br, w, "Password", "")
+	}
+
+	_, _ = fmt.Fprint(w, "Password: ")
+	password, _ := term.ReadPassword(int(file.Fd()))
+	_, _ = fmt.Fprintln(w)
+
+	return string(password)
+}
@@ -1,10 +1,13 @@
 package logger
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -14,9 +17,63 @@ const DefaultTimeLayout = "2006-01-02 15:04:05"
 // DefaultLineFormat is format to log line record.
 const DefaultLineFormat = "[%s] %s: %s\n%s\n\n"
 
+// Log formats.
+const (
+	// FormatText writes records as blocks of text with DefaultLineFormat.
+	FormatText = "text"
+	// FormatJSON writes records as JSON lines.
+	FormatJSON = "json"
+)
+
+// Rotation periods. The file is rotated before the first record of the new
+// period.
+const (
+	RotateDaily  = "daily"
+	RotateHourly = "hourly"
+)
+
+// Placeholders of the file name which are replaced on every write.
+const (
+	PlaceholderEnv  = "${env}"
+	PlaceholderDate = "${date}"
+)
+
+// DateLayout is the layout of PlaceholderDate.
+const DateLayout = "2006-01-02"
+
+// rotatedLayout is the layout of the suffix of rotated files. Rotated files
+// are sorted by the suffix from the oldest to the newest.
+const rotatedLayout = "2006-01-02T15-04-05"
+
 // ErrEmptyFileName is returned when trying to open file with empty name.
 var ErrEmptyFileName = errors.New("empty file name")
 
+// Settings contains the format and rotation of the log file.
+type Settings struct {
+	// Format is FormatText or FormatJSON. Empty value means FormatText.
+	Format string
+	// MaxSize rotates the file when it grows over the size in bytes. Zero
+	// disables the size based rotation.
+	MaxSize int64
+	// Rotate is RotateDaily or RotateHourly. Empty value disables the time
+	// based rotation.
+	Rotate string
+	// MaxBackups is the number of rotated files which are kept. Zero keeps
+	// all files.
+	MaxBackups int
+}
+
+// Record is the logged command and response. Env and User tell who ran the
+// command against which environment.
+type Record struct {
+	Time     time.Time `json:"time"`
+	Env      string    `json:"env,omitempty"`
+	User     string    `json:"user,omitempty"`
+	Address  string    `json:"address"`
+	Command  string    `json:"command"`
+	Response string    `json:"response"`
+}
+
 // OpenFile opens file for append strings. Creates file if file not exist.
 func OpenFile(name string) (*os.File, error) {
 	if name == "" {
@@ -72,3 +129,133 @@ func Write(name string, address string, request string, response string) error {
 
 	return nil
 }
+
+// WriteRecord saves the record to the log file in the format of the settings
+// and rotates the file before the write if it is needed. Placeholders of
+// the name are replaced with the environment and the date of the record.
+func WriteRecord(name string, settings Settings, record Record) error {
+	// Disable logging if log file name is empty.
+	if name == "" {
+		return nil
+	}
+
+	name = Path(name, record.Env, record.Time)
+
+	var line []byte
+
+	switch settings.Format {
+	case "", FormatText:
+		line = []byte(fmt.Sprintf(DefaultLineFormat,
+			record.Time.Format(DefaultTimeLayout), record.Address, record.Command, record.Response))
+	case FormatJSON:
+		data, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("write: %w", err)
+		}
+
+		line = append(data, '\n')
+	default:
+		return fmt.Errorf("unsupported log format %q", settings.Format)
+	}
+
+	if err := rotate(name, settings, record.Time, int64(len(line))); err != nil {
+		return fmt.Errorf("rotate: %w", err)
+	}
+
+	file, err := OpenFile(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err = file.Write(line); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
+
+// Path replaces placeholders of the file name with the environment and the
+// date.
+func Path(name string, env string, now time.Time) string {
+	return strings.NewReplacer(PlaceholderEnv, env, PlaceholderDate, now.Format(DateLayout)).Replace(name)
+}
+
+// rotate renames the file if the line does not fit into the max size or the
+// file was last written in the previous period. Rotated files have the time
+// of the last write as suffix.
+func rotate(name string, settings Settings, now time.Time, size int64) error {
+	info, err := os.Stat(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	full := settings.MaxSize > 0 && info.Size() > 0 && info.Size()+size > settings.MaxSize
+
+	var expired bool
+
+	switch settings.Rotate {
+	case RotateDaily:
+		expired = info.ModTime().Format(DateLayout) != now.Format(DateLayout)
+	case RotateHourly:
+		expired = !info.ModTime().Truncate(time.Hour).Equal(now.Truncate(time.Hour))
+	}
+
+	if !full && !expired {
+		return nil
+	}
+
+	rotated := name + "." + info.ModTime().Format(rotatedLayout)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(rotated); os.IsNotExist(err) {
+			break
+		}
+
+		rotated = fmt.Sprintf("%s.%s.%d", name, info.ModTime().Format(rotatedLayout), i)
+	}
+
+	if err := os.Rename(name, rotated); err != nil {
+		return err
+	}
+
+	return removeBackups(name, settings.MaxBackups)
+}
+
+// removeBackups removes the oldest rotated files of the log file over the
+// limit.
+func removeBackups(name string, limit int) error {
+	if limit <= 0 {
+		return nil
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(name))
+	if err != nil {
+		return err
+	}
+
+	prefix := filepath.Base(name) + "."
+
+	var backups []string
+
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), prefix) {
+			backups = append(backups, filepath.Join(filepath.Dir(name), entry.Name()))
+		}
+	}
+
+	sort.Strings(backups)
+
+	for len(backups) > limit {
+		if err := os.Remove(backups[0]); err != nil {
+			return err
+		}
+
+		backups = backups[1:]
+	}
+
+	return nil
+}
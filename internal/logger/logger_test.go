@@ -2,7 +2,9 @@ package logger_test
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/gorcon/rcon-cli/internal/logger"
 	"github.com/stretchr/testify/assert"
@@ -60,3 +62,75 @@ func TestWrite(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestWriteRecord(t *testing.T) {
+	record := logger.Record{
+		Time: time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC), Env: "prod", User: "admin",
+		Address: "127.0.0.1:16200", Command: "players", Response: "Players connected (0):",
+	}
+
+	t.Run("json", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "${env}-${date}.jsonl")
+
+		err := logger.WriteRecord(name, logger.Settings{Format: logger.FormatJSON}, record)
+		assert.NoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(filepath.Dir(name), "prod-2026-10-15.jsonl"))
+		assert.NoError(t, err)
+		assert.Equal(t, `{"time":"2026-10-15T12:00:00Z","env":"prod","user":"admin","address":"127.0.0.1:16200",`+
+			`"command":"players","response":"Players connected (0):"}`+"\n", string(data))
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "rcon.log")
+
+		err := logger.WriteRecord(name, logger.Settings{Format: "xml"}, record)
+		assert.EqualError(t, err, `unsupported log format "xml"`)
+	})
+
+	t.Run("max size", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "rcon.log")
+		settings := logger.Settings{MaxSize: 100, MaxBackups: 2}
+
+		for i := 0; i < 5; i++ {
+			err := logger.WriteRecord(name, settings, record)
+			assert.NoError(t, err)
+
+			// Rotated files are named by the time of the last write.
+			os.Chtimes(name, time.Now(), time.Now().Add(time.Duration(i)*time.Second))
+		}
+
+		backups, err := filepath.Glob(name + ".*")
+		assert.NoError(t, err)
+		assert.Len(t, backups, 2)
+
+		data, err := os.ReadFile(name)
+		assert.NoError(t, err)
+		assert.Equal(t, "[2026-10-15 12:00:00] 127.0.0.1:16200: players\nPlayers connected (0):\n\n", string(data))
+	})
+
+	t.Run("daily", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "rcon.log")
+		settings := logger.Settings{Rotate: logger.RotateDaily}
+
+		// Files are rotated by the time of the last write.
+		record := record
+		record.Time = time.Now()
+
+		err := logger.WriteRecord(name, settings, record)
+		assert.NoError(t, err)
+
+		err = logger.WriteRecord(name, settings, record)
+		assert.NoError(t, err)
+
+		yesterday := record.Time.Add(-24 * time.Hour)
+		os.Chtimes(name, yesterday, yesterday)
+
+		err = logger.WriteRecord(name, settings, record)
+		assert.NoError(t, err)
+
+		backups, err := filepath.Glob(name + ".*")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{name + "." + yesterday.Local().Format("2006-01-02T15-04-05")}, backups)
+	})
+}
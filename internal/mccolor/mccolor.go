@@ -0,0 +1,299 @@
+// Package mccolor renders Minecraft formatting codes of the responses.
+//
+// Minecraft servers format the text with legacy `§` codes: `§0`-`§f` are
+// colors, `§k`-`§o` are styles and `§r` resets the formatting. Bukkit forks
+// also send RGB colors as `§x§r§r§g§g§b§b`. JSON chat components are
+// converted to the legacy codes first.
+package mccolor
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// SectionSign starts the formatting code.
+const SectionSign = '§'
+
+// Reset is the ANSI sequence which resets colors and styles.
+const Reset = "\x1b[0m"
+
+// colors maps color codes to ANSI foreground colors.
+var colors = map[rune]int{
+	'0': 30, '1': 34, '2': 32, '3': 36, '4': 31, '5': 35, '6': 33, '7': 37,
+	'8': 90, '9': 94, 'a': 92, 'b': 96, 'c': 91, 'd': 95, 'e': 93, 'f': 97,
+}
+
+// styles maps style codes to ANSI attributes. Obfuscated text `§k` has no
+// ANSI equivalent and is printed as is.
+var styles = map[rune]int{'l': 1, 'm': 9, 'n': 4, 'o': 3}
+
+// names maps color names of JSON chat components to color codes.
+var names = map[string]rune{
+	"black": '0', "dark_blue": '1', "dark_green": '2', "dark_aqua": '3',
+	"dark_red": '4', "dark_purple": '5', "gold": '6', "gray": '7',
+	"dark_gray": '8', "blue": '9', "green": 'a', "aqua": 'b',
+	"red": 'c', "light_purple": 'd', "yellow": 'e', "white": 'f',
+}
+
+// ToANSI replaces formatting codes with ANSI escape sequences. Colors reset
+// the styles like in the game. The text ends with Reset if any code was
+// replaced.
+func ToANSI(text string) string {
+	return replace(text, true)
+}
+
+// Strip removes formatting codes from the text.
+func Strip(text string) string {
+	return replace(text, false)
+}
+
+// HasCodes reports whether the text contains formatting codes.
+func HasCodes(text string) bool {
+	return strings.ContainsRune(text, SectionSign)
+}
+
+// replace walks the formatting codes and writes their ANSI sequences if
+// ansi is set.
+func replace(text string, ansi bool) string {
+	if !HasCodes(text) {
+		return text
+	}
+
+	runes := []rune(text)
+
+	var b strings.Builder
+
+	formatted := false
+
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != SectionSign || i+1 == len(runes) {
+			b.WriteRune(runes[i])
+
+			continue
+		}
+
+		code := toLower(runes[i+1])
+		i++
+
+		if code == 'x' {
+			if rgb, ok := hexColor(runes[i+1:]); ok {
+				i += 12
+
+				if ansi {
+					b.WriteString("\x1b[0;38;2;" + rgb + "m")
+					formatted = true
+				}
+
+				continue
+			}
+		}
+
+		if !ansi {
+			continue
+		}
+
+		switch {
+		case code == 'r':
+			b.WriteString(Reset)
+		case colors[code] != 0:
+			b.WriteString("\x1b[0;" + strconv.Itoa(colors[code]) + "m")
+		case styles[code] != 0:
+			b.WriteString("\x1b[" + strconv.Itoa(styles[code]) + "m")
+		default:
+			continue
+		}
+
+		formatted = true
+	}
+
+	if formatted {
+		b.WriteString(Reset)
+	}
+
+	return b.String()
+}
+
+// hexColor parses the `§r§r§g§g§b§b` digits which follow `§x` and returns
+// the color as `r;g;b`.
+func hexColor(runes []rune) (string, bool) {
+	const digits = 6
+
+	if len(runes) < 2*digits {
+		return "", false
+	}
+
+	hex := make([]rune, 0, digits)
+
+	for i := 0; i < 2*digits; i += 2 {
+		if runes[i] != SectionSign {
+			return "", false
+		}
+
+		hex = append(hex, runes[i+1])
+	}
+
+	return rgb(string(hex))
+}
+
+// rgb converts `rrggbb` hex color to `r;g;b`.
+func rgb(hex string) (string, bool) {
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return "", false
+	}
+
+	return strconv.Itoa(int(value>>16)) + ";" + strconv.Itoa(int(value>>8&0xff)) + ";" + strconv.Itoa(int(value&0xff)), true
+}
+
+// toLower lowers ASCII letters of the codes.
+func toLower(r rune) rune {
+	if r >= 'A' && r <= 'Z' {
+		return r + 'a' - 'A'
+	}
+
+	return r
+}
+
+// component is the JSON chat component. Styles are pointers, so unset
+// styles are inherited from the parent component.
+type component struct {
+	Text          string      `json:"text"`
+	Translate     string      `json:"translate"`
+	Color         string      `json:"color"`
+	Bold          *bool       `json:"bold"`
+	Italic        *bool       `json:"italic"`
+	Underlined    *bool       `json:"underlined"`
+	Strikethrough *bool       `json:"strikethrough"`
+	Obfuscated    *bool       `json:"obfuscated"`
+	Extra         []component `json:"extra"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Components can be plain
+// strings and arrays, where the first element is the parent of the rest.
+func (c *component) UnmarshalJSON(data []byte) error {
+	switch strings.TrimSpace(string(data))[0] {
+	case '"':
+		return json.Unmarshal(data, &c.Text)
+	case '[':
+		var list []component
+		if err := json.Unmarshal(data, &list); err != nil {
+			return err
+		}
+
+		if len(list) > 0 {
+			*c = list[0]
+			c.Extra = append(c.Extra, list[1:]...)
+		}
+
+		return nil
+	}
+
+	type plain component
+
+	return json.Unmarshal(data, (*plain)(c))
+}
+
+// style is the formatting of the component after inheritance.
+type style struct {
+	color                                               string
+	bold, italic, underlined, strikethrough, obfuscated bool
+}
+
+// FromJSON converts the JSON chat component to the text with legacy
+// formatting codes. It reports false if the text is not a chat component.
+func FromJSON(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "{") && !strings.HasPrefix(text, "[") {
+		return "", false
+	}
+
+	var c component
+	if err := json.Unmarshal([]byte(text), &c); err != nil {
+		return "", false
+	}
+
+	if c.Text == "" && c.Translate == "" && len(c.Extra) == 0 {
+		return "", false
+	}
+
+	var b strings.Builder
+
+	c.write(&b, style{})
+
+	return b.String(), true
+}
+
+// write writes the component and its children with the inherited style.
+func (c *component) write(b *strings.Builder, s style) {
+	if c.Color != "" {
+		s.color = c.Color
+	}
+
+	for _, inherit := range []struct {
+		value *bool
+		field *bool
+	}{
+		{c.Bold, &s.bold}, {c.Italic, &s.italic}, {c.Underlined, &s.underlined},
+		{c.Strikethrough, &s.strikethrough}, {c.Obfuscated, &s.obfuscated},
+	} {
+		if inherit.value != nil {
+			*inherit.field = *inherit.value
+		}
+	}
+
+	text := c.Text
+	if text == "" {
+		// Translation keys are not resolved, the key is better than nothing.
+		text = c.Translate
+	}
+
+	if text != "" {
+		b.WriteString(s.codes())
+		b.WriteString(text)
+	}
+
+	for i := range c.Extra {
+		c.Extra[i].write(b, s)
+	}
+}
+
+// codes returns the legacy codes of the style starting with reset.
+func (s style) codes() string {
+	codes := []rune{'r'}
+
+	if code, ok := names[s.color]; ok {
+		codes = append(codes, code)
+	}
+
+	hex := ""
+	if strings.HasPrefix(s.color, "#") && len(s.color) == 7 {
+		hex = string(SectionSign) + "x"
+		for _, digit := range s.color[1:] {
+			hex += string(SectionSign) + string(digit)
+		}
+	}
+
+	for _, flag := range []struct {
+		on   bool
+		code rune
+	}{{s.obfuscated, 'k'}, {s.bold, 'l'}, {s.strikethrough, 'm'}, {s.underlined, 'n'}, {s.italic, 'o'}} {
+		if flag.on {
+			codes = append(codes, flag.code)
+		}
+	}
+
+	var b strings.Builder
+
+	for i, code := range codes {
+		b.WriteRune(SectionSign)
+		b.WriteRune(code)
+
+		// RGB color goes after reset, so the styles are kept.
+		if i == 0 {
+			b.WriteString(hex)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,63 @@
+package mccolor_test
+
+import (
+	"testing"
+
+	"github.com/gorcon/rcon-cli/internal/mccolor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToANSI(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"no codes", "There are 0 of a max 20 players online", "There are 0 of a max 20 players online"},
+		{"color", "§aonline§r: 2", "\x1b[0;92monline\x1b[0m: 2\x1b[0m"},
+		{"style after color", "§6§lGold", "\x1b[0;33m\x1b[1mGold\x1b[0m"},
+		{"upper case", "§CRed", "\x1b[0;91mRed\x1b[0m"},
+		{"rgb", "§x§f§f§8§0§0§0orange", "\x1b[0;38;2;255;128;0morange\x1b[0m"},
+		{"obfuscated", "§kmagic", "magic"},
+		{"trailing sign", "100§", "100§"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mccolor.ToANSI(tt.text))
+		})
+	}
+}
+
+func TestStrip(t *testing.T) {
+	assert.Equal(t, "There are 2 players: Steve, Alex", mccolor.Strip("§6There are §c2§6 players: §x§f§f§8§0§0§0Steve§r, §lAlex"))
+	assert.Equal(t, "plain", mccolor.Strip("plain"))
+}
+
+func TestFromJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+		ok   bool
+	}{
+		{"text", `{"text":"Hello"}`, "§rHello", true},
+		{"inherited style", `{"text":"A","color":"red","bold":true,"extra":[{"text":"B","bold":false},"C"]}`,
+			"§r§c§lA§r§cB§r§c§lC", true},
+		{"array", `["",{"text":"Day ","color":"gold"},{"text":"42"}]`, "§r§6Day §r42", true},
+		{"rgb", `{"text":"X","color":"#FF8000","italic":true}`, "§r§x§F§F§8§0§0§0§oX", true},
+		{"translate", `{"translate":"commands.list.players"}`, "§rcommands.list.players", true},
+		{"not component", `{"players":2}`, "", false},
+		{"not json", "There are 2 players", "", false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := mccolor.FromJSON(tt.text)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,423 @@
+// Package pterodactyl contains the client of the server console of
+// Pterodactyl panel.
+//
+// The console is not RCON: Wings daemon exposes it over the websocket which
+// is authorized with a short-lived token. The token and the websocket URL are
+// requested from the client API of the panel with the API key. Wings warns
+// about the expiration of the token with "token expiring" event and the
+// client sends the new token in the same connection.
+//
+// Console output has no relation to the commands, so the response to the
+// command is the output received until the console is quiet for the quiet
+// period. The output received between commands is passed to the console
+// handler.
+package pterodactyl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+)
+
+// DefaultDialTimeout, DefaultDeadline and DefaultQuietPeriod are used when
+// the options are not set.
+const (
+	DefaultDialTimeout = 5 * time.Second
+	DefaultDeadline    = 5 * time.Second
+	DefaultQuietPeriod = 500 * time.Millisecond
+)
+
+// Websocket events of Wings.
+const (
+	EventAuth          = "auth"
+	EventAuthSuccess   = "auth success"
+	EventSendCommand   = "send command"
+	EventConsoleOutput = "console output"
+	EventDaemonMessage = "daemon message"
+	EventDaemonError   = "daemon error"
+	EventTokenExpiring = "token expiring"
+	EventTokenExpired  = "token expired"
+	EventJWTError      = "jwt error"
+)
+
+// Errors.
+var (
+	// ErrAuthFailed is returned when the panel rejected the API key or Wings
+	// rejected the token.
+	ErrAuthFailed = errors.New("authentication failed")
+
+	// ErrCommandEmpty is returned when executed command length equal 0.
+	ErrCommandEmpty = errors.New("command is not set")
+
+	// ErrDaemon is returned when Wings reported an error.
+	ErrDaemon = errors.New("daemon error")
+)
+
+// Message is the websocket message of Wings.
+type Message struct {
+	Event string   `json:"event"`
+	Args  []string `json:"args,omitempty"`
+}
+
+// Credentials is the response of the websocket endpoint of the client API.
+type Credentials struct {
+	Token  string `json:"token"`
+	Socket string `json:"socket"`
+}
+
+// Settings contains options of Conn.
+type Settings struct {
+	dialTimeout time.Duration
+	deadline    time.Duration
+	quiet       time.Duration
+}
+
+// Option allows to inject settings to Settings.
+type Option func(s *Settings)
+
+// SetDialTimeout injects the timeout of the token request, the websocket
+// handshake and auth to Settings.
+func SetDialTimeout(timeout time.Duration) Option {
+	return func(s *Settings) {
+		s.dialTimeout = timeout
+	}
+}
+
+// SetDeadline injects the timeout of the command response to Settings.
+func SetDeadline(timeout time.Duration) Option {
+	return func(s *Settings) {
+		s.deadline = timeout
+	}
+}
+
+// SetQuietPeriod injects the pause in console output which completes the
+// command response to Settings.
+func SetQuietPeriod(period time.Duration) Option {
+	return func(s *Settings) {
+		s.quiet = period
+	}
+}
+
+// Conn is the authorized console connection.
+type Conn struct {
+	panel    string
+	apiKey   string
+	server   string
+	settings Settings
+	ws       *gorilla.Conn
+
+	// writeMu serializes writes to the websocket.
+	writeMu sync.Mutex
+
+	mu         sync.Mutex
+	console    func(line string)
+	collecting bool
+	response   []string
+	// notify receives a value when a line is added to the response.
+	notify chan struct{}
+	// done is closed when the connection is broken, err contains the cause.
+	done chan struct{}
+	err  error
+}
+
+// Dial requests the token with the API key and connects to the console of
+// the server. The panel is the URL of the panel, the server is the UUID or
+// the short identifier of the server.
+func Dial(panel string, apiKey string, server string, options ...Option) (*Conn, error) {
+	settings := Settings{dialTimeout: DefaultDialTimeout, deadline: DefaultDeadline, quiet: DefaultQuietPeriod}
+	for _, option := range options {
+		option(&settings)
+	}
+
+	c := Conn{
+		panel:    strings.TrimSuffix(panel, "/"),
+		apiKey:   apiKey,
+		server:   server,
+		settings: settings,
+		notify:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+
+	credentials, err := c.credentials()
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := gorilla.Dialer{HandshakeTimeout: settings.dialTimeout, Proxy: http.ProxyFromEnvironment}
+
+	// Wings checks the origin against the panel URL.
+	c.ws, _, err = dialer.Dial(credentials.Socket, http.Header{"Origin": {c.panel}})
+	if err != nil {
+		return nil, fmt.Errorf("pterodactyl: %w", err)
+	}
+
+	if err := c.auth(credentials.Token); err != nil {
+		_ = c.ws.Close()
+
+		return nil, err
+	}
+
+	go c.read()
+
+	return &c, nil
+}
+
+// SetConsole sets the handler of the console output which is received
+// between commands. The output is dropped without the handler.
+func (c *Conn) SetConsole(handler func(line string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.console = handler
+}
+
+// Execute sends the command to the console and returns the output received
+// until the console is quiet for the quiet period.
+func (c *Conn) Execute(command string) (string, error) {
+	if command == "" {
+		return "", ErrCommandEmpty
+	}
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+
+		return "", c.err
+	}
+
+	c.collecting, c.response = true, nil
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.collecting, c.response = false, nil
+		c.mu.Unlock()
+	}()
+
+	if err := c.write(Message{Event: EventSendCommand, Args: []string{command}}); err != nil {
+		return "", err
+	}
+
+	var timeout <-chan time.Time
+	if c.settings.deadline > 0 {
+		timer := time.NewTimer(c.settings.deadline)
+		defer timer.Stop()
+
+		timeout = timer.C
+	}
+
+	quiet := time.NewTimer(c.settings.quiet)
+	defer quiet.Stop()
+
+	for {
+		select {
+		case <-c.notify:
+			if !quiet.Stop() {
+				<-quiet.C
+			}
+
+			quiet.Reset(c.settings.quiet)
+		case <-quiet.C:
+			return c.joined(), nil
+		case <-timeout:
+			return c.joined(), fmt.Errorf("pterodactyl: %w", os.ErrDeadlineExceeded)
+		case <-c.done:
+			return c.joined(), c.err
+		}
+	}
+}
+
+// Send sends the command without waiting for the output. The output is
+// received by Stream.
+func (c *Conn) Send(command string) error {
+	if command == "" {
+		return ErrCommandEmpty
+	}
+
+	return c.write(Message{Event: EventSendCommand, Args: []string{command}})
+}
+
+// Stream passes the console output to the handler until the connection is
+// closed.
+func (c *Conn) Stream(handler func(line string)) error {
+	c.SetConsole(handler)
+	<-c.done
+
+	return c.err
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	return c.ws.Close()
+}
+
+// credentials requests the token and the websocket URL from the client API
+// of the panel.
+func (c *Conn) credentials() (Credentials, error) {
+	endpoint := c.panel + "/api/client/servers/" + url.PathEscape(c.server) + "/websocket"
+
+	request, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("pterodactyl: %w", err)
+	}
+
+	request.Header.Set("Authorization", "Bearer "+c.apiKey)
+	request.Header.Set("Accept", "application/json")
+
+	client := http.Client{Timeout: c.settings.dialTimeout}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("pterodactyl: %w", err)
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return Credentials{}, fmt.Errorf("pterodactyl: %w: %s", ErrAuthFailed, response.Status)
+	default:
+		return Credentials{}, fmt.Errorf("pterodactyl: %s: %s", endpoint, response.Status)
+	}
+
+	var body struct {
+		Data Credentials `json:"data"`
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return Credentials{}, fmt.Errorf("pterodactyl: %w", err)
+	}
+
+	return body.Data, nil
+}
+
+// auth sends the token and waits for the result.
+func (c *Conn) auth(token string) error {
+	if c.settings.dialTimeout > 0 {
+		if err := c.ws.SetReadDeadline(time.Now().Add(c.settings.dialTimeout)); err != nil {
+			return fmt.Errorf("pterodactyl: %w", err)
+		}
+
+		defer func() {
+			_ = c.ws.SetReadDeadline(time.Time{})
+		}()
+	}
+
+	if err := c.write(Message{Event: EventAuth, Args: []string{token}}); err != nil {
+		return err
+	}
+
+	for {
+		var message Message
+		if err := c.ws.ReadJSON(&message); err != nil {
+			return fmt.Errorf("pterodactyl: %w", err)
+		}
+
+		switch message.Event {
+		case EventAuthSuccess:
+			return nil
+		case EventJWTError, EventTokenExpired:
+			return fmt.Errorf("pterodactyl: %w: %s", ErrAuthFailed, strings.Join(message.Args, " "))
+		}
+	}
+}
+
+// read receives the events until the connection is closed. The token is
+// renewed when Wings reports its expiration.
+func (c *Conn) read() {
+	for {
+		var message Message
+		if err := c.ws.ReadJSON(&message); err != nil {
+			c.fail(fmt.Errorf("pterodactyl: %w", err))
+
+			return
+		}
+
+		switch message.Event {
+		case EventConsoleOutput, EventDaemonMessage:
+			for _, line := range message.Args {
+				c.output(line)
+			}
+		case EventTokenExpiring, EventTokenExpired:
+			credentials, err := c.credentials()
+			if err == nil {
+				err = c.write(Message{Event: EventAuth, Args: []string{credentials.Token}})
+			}
+
+			if err != nil {
+				c.fail(err)
+				_ = c.ws.Close()
+
+				return
+			}
+		case EventJWTError:
+			c.fail(fmt.Errorf("pterodactyl: %w: %s", ErrAuthFailed, strings.Join(message.Args, " ")))
+			_ = c.ws.Close()
+
+			return
+		case EventDaemonError:
+			c.output(fmt.Sprintf("%s: %s", ErrDaemon, strings.Join(message.Args, " ")))
+		}
+	}
+}
+
+// output adds the line to the response of the executed command or passes it
+// to the console handler.
+func (c *Conn) output(line string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.collecting {
+		c.response = append(c.response, line)
+
+		select {
+		case c.notify <- struct{}{}:
+		default:
+		}
+
+		return
+	}
+
+	if c.console != nil {
+		c.console(line)
+	}
+}
+
+// fail saves the cause of the broken connection and wakes up Execute.
+func (c *Conn) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err == nil {
+		c.err = err
+		close(c.done)
+	}
+}
+
+// joined returns the lines of the response.
+func (c *Conn) joined() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return strings.Join(c.response, "\n")
+}
+
+// write sends the message to Wings.
+func (c *Conn) write(message Message) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := c.ws.WriteJSON(message); err != nil {
+		return fmt.Errorf("pterodactyl: %w", err)
+	}
+
+	return nil
+}
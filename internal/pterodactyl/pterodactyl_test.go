@@ -0,0 +1,88 @@
+package pterodactyl_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/pterodactyl"
+	"github.com/gorcon/rcon-cli/internal/pterodactyl/pterodactyltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConn(t *testing.T) {
+	server := pterodactyltest.NewServer("ptlc_key", "1a7ce997", func(command string) []string {
+		switch command {
+		case "list":
+			return []string{"There are 2 of a max of 20 players online:", "alice, bob"}
+		case "save-all":
+			return nil
+		}
+
+		return []string{"Unknown command"}
+	})
+	defer server.Close()
+
+	options := []pterodactyl.Option{
+		pterodactyl.SetDeadline(time.Second), pterodactyl.SetQuietPeriod(50 * time.Millisecond),
+	}
+
+	t.Run("auth failed", func(t *testing.T) {
+		_, err := pterodactyl.Dial(server.URL(), "wrong", "1a7ce997")
+		assert.ErrorIs(t, err, pterodactyl.ErrAuthFailed)
+	})
+
+	t.Run("unknown server", func(t *testing.T) {
+		_, err := pterodactyl.Dial(server.URL(), "ptlc_key", "ffffffff")
+		assert.ErrorContains(t, err, "404")
+	})
+
+	conn, err := pterodactyl.Dial(server.URL()+"/", "ptlc_key", "1a7ce997", options...)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	t.Run("execute", func(t *testing.T) {
+		response, err := conn.Execute("list")
+		assert.NoError(t, err)
+		assert.Equal(t, "There are 2 of a max of 20 players online:\nalice, bob", response)
+
+		response, err = conn.Execute("save-all")
+		assert.NoError(t, err)
+		assert.Equal(t, "", response)
+	})
+
+	t.Run("console", func(t *testing.T) {
+		lines := make(chan string, 1)
+		conn.SetConsole(func(line string) {
+			lines <- line
+		})
+
+		server.Console("[Server thread/INFO]: alice joined the game")
+
+		select {
+		case line := <-lines:
+			assert.Equal(t, "[Server thread/INFO]: alice joined the game", line)
+		case <-time.After(time.Second):
+			t.Error("console output is not received")
+		}
+
+		conn.SetConsole(nil)
+	})
+
+	t.Run("token expiring", func(t *testing.T) {
+		tokens := server.Tokens()
+		server.ExpireToken()
+
+		assert.Eventually(t, func() bool { return server.Tokens() == tokens+1 }, time.Second, 10*time.Millisecond)
+
+		response, err := conn.Execute("list")
+		assert.NoError(t, err)
+		assert.Equal(t, "There are 2 of a max of 20 players online:\nalice, bob", response)
+	})
+
+	t.Run("empty command", func(t *testing.T) {
+		_, err := conn.Execute("")
+		assert.ErrorIs(t, err, pterodactyl.ErrCommandEmpty)
+	})
+}
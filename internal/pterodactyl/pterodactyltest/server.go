@@ -0,0 +1,176 @@
+// Package pterodactyltest contains Pterodactyl panel and Wings console
+// server for tests.
+package pterodactyltest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorcon/rcon-cli/internal/pterodactyl"
+	gorilla "github.com/gorilla/websocket"
+)
+
+// Handler returns the console output lines of the command.
+type Handler func(command string) []string
+
+// Server serves the websocket endpoint of the client API and the console
+// websocket on a random local port.
+type Server struct {
+	server   *httptest.Server
+	apiKey   string
+	id       string
+	handler  Handler
+	upgrader gorilla.Upgrader
+
+	mu     sync.Mutex
+	tokens int
+	token  string
+	conns  []*gorilla.Conn
+	// write serializes writes to the connections.
+	write sync.Mutex
+}
+
+// NewServer starts the server which accepts the API key for the server
+// with the identifier and answers the commands with the handler.
+func NewServer(apiKey string, id string, handler Handler) *Server {
+	s := Server{apiKey: apiKey, id: id, handler: handler}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/client/servers/"+id+"/websocket", s.credentials)
+	mux.HandleFunc("/api/servers/"+id+"/ws", s.console)
+
+	s.server = httptest.NewServer(mux)
+
+	return &s
+}
+
+// URL returns the panel URL of the server.
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Tokens returns the number of issued tokens.
+func (s *Server) Tokens() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.tokens
+}
+
+// Console sends the line of console output to the connected clients.
+func (s *Server) Console(line string) {
+	s.broadcast(pterodactyl.Message{Event: pterodactyl.EventConsoleOutput, Args: []string{line}})
+}
+
+// ExpireToken warns the connected clients about the expiration of the token.
+// The clients have to send the new token.
+func (s *Server) ExpireToken() {
+	s.broadcast(pterodactyl.Message{Event: pterodactyl.EventTokenExpiring})
+}
+
+// Close stops the server.
+func (s *Server) Close() {
+	s.mu.Lock()
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.server.Close()
+}
+
+// credentials issues the new token if the API key is valid.
+func (s *Server) credentials(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+s.apiKey {
+		w.WriteHeader(http.StatusUnauthorized)
+
+		return
+	}
+
+	s.mu.Lock()
+	s.tokens++
+	s.token = "token-" + strconv.Itoa(s.tokens)
+	credentials := pterodactyl.Credentials{
+		Token:  s.token,
+		Socket: "ws" + strings.TrimPrefix(s.server.URL, "http") + "/api/servers/" + s.id + "/ws",
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]pterodactyl.Credentials{"data": credentials})
+}
+
+// console authorizes the client with the last issued token and executes
+// its commands.
+func (s *Server) console(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Origin") != s.server.URL {
+		w.WriteHeader(http.StatusForbidden)
+
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	s.mu.Lock()
+	s.conns = append(s.conns, conn)
+	s.mu.Unlock()
+
+	authorized := false
+
+	for {
+		var message pterodactyl.Message
+		if err := conn.ReadJSON(&message); err != nil {
+			return
+		}
+
+		switch message.Event {
+		case pterodactyl.EventAuth:
+			s.mu.Lock()
+			authorized = len(message.Args) == 1 && message.Args[0] == s.token
+			s.mu.Unlock()
+
+			if !authorized {
+				s.send(conn, pterodactyl.Message{Event: pterodactyl.EventJWTError, Args: []string{"invalid token"}})
+
+				return
+			}
+
+			s.send(conn, pterodactyl.Message{Event: pterodactyl.EventAuthSuccess})
+		case pterodactyl.EventSendCommand:
+			if !authorized || len(message.Args) != 1 {
+				continue
+			}
+
+			for _, line := range s.handler(message.Args[0]) {
+				s.send(conn, pterodactyl.Message{Event: pterodactyl.EventConsoleOutput, Args: []string{line}})
+			}
+		}
+	}
+}
+
+// broadcast sends the message to the connected clients.
+func (s *Server) broadcast(message pterodactyl.Message) {
+	s.mu.Lock()
+	conns := append([]*gorilla.Conn(nil), s.conns...)
+	s.mu.Unlock()
+
+	for _, conn := range conns {
+		s.send(conn, message)
+	}
+}
+
+// send writes the message to the connection.
+func (s *Server) send(conn *gorilla.Conn, message pterodactyl.Message) {
+	s.write.Lock()
+	defer s.write.Unlock()
+
+	_ = conn.WriteJSON(message)
+}
@@ -0,0 +1,259 @@
+// Package rconmulti contains the client of Source RCON protocol which
+// reassembles responses split into several packets.
+//
+// The server splits responses longer than 4096 bytes into packets with the
+// ID of the command and does not mark the last one. The client sends an
+// empty SERVERDATA_RESPONSE_VALUE packet with another ID right after the
+// command. The server answers requests in order, so the response to the
+// command is complete when the mirror of the sentinel packet is received.
+// Source servers follow the mirror with one more packet, Minecraft answers
+// with "Unknown request". Packets of the previous requests are skipped by
+// their ID.
+//
+// Reassembling can be disabled for servers which do not answer the sentinel
+// packet. The single response packet is read then and the packets have the
+// fixed IDs like in github.com/gorcon/rcon.
+package rconmulti
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gorcon/rcon"
+)
+
+// DefaultDialTimeout and DefaultDeadline are used when the options are not
+// set.
+const (
+	DefaultDialTimeout = 5 * time.Second
+	DefaultDeadline    = 5 * time.Second
+)
+
+// rustWorkaroundType is the undocumented packet type of Rust server which
+// goes before the response.
+const rustWorkaroundType = 4
+
+// Settings contains options of Conn.
+type Settings struct {
+	dialTimeout time.Duration
+	deadline    time.Duration
+	multiPacket bool
+	conn        net.Conn
+}
+
+// Option allows to inject settings to Settings.
+type Option func(s *Settings)
+
+// SetDialTimeout injects the timeout of dial and auth to Settings.
+func SetDialTimeout(timeout time.Duration) Option {
+	return func(s *Settings) {
+		s.dialTimeout = timeout
+	}
+}
+
+// SetDeadline injects the timeout of the command response to Settings.
+func SetDeadline(timeout time.Duration) Option {
+	return func(s *Settings) {
+		s.deadline = timeout
+	}
+}
+
+// SetMultiPacket injects whether the response is reassembled from several
+// packets to Settings. Enabled by default.
+func SetMultiPacket(enabled bool) Option {
+	return func(s *Settings) {
+		s.multiPacket = enabled
+	}
+}
+
+// SetConn injects the established connection to Settings. Dial uses it
+// instead of dialing the address.
+func SetConn(conn net.Conn) Option {
+	return func(s *Settings) {
+		s.conn = conn
+	}
+}
+
+// Conn is the authorized RCON connection.
+type Conn struct {
+	conn     net.Conn
+	settings Settings
+	// id is the ID of the last sent packet.
+	id int32
+}
+
+// Dial connects to the server and authorizes with the password.
+func Dial(address string, password string, options ...Option) (*Conn, error) {
+	settings := Settings{dialTimeout: DefaultDialTimeout, deadline: DefaultDeadline, multiPacket: true}
+	for _, option := range options {
+		option(&settings)
+	}
+
+	conn := settings.conn
+	if conn == nil {
+		var err error
+		if conn, err = net.DialTimeout("tcp", address, settings.dialTimeout); err != nil {
+			return nil, err
+		}
+	}
+
+	c := Conn{conn: conn, settings: settings}
+	if err := c.auth(password); err != nil {
+		_ = conn.Close()
+
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// Execute sends the command followed by the sentinel packet and returns
+// bodies of all response packets joined in order. Without multi-packet
+// responses only the command is sent and the body of the single response
+// packet is returned.
+func (c *Conn) Execute(command string) (string, error) {
+	if command == "" {
+		return "", rcon.ErrCommandEmpty
+	}
+
+	if len(command) > rcon.MaxCommandLen {
+		return "", rcon.ErrCommandTooLong
+	}
+
+	if err := c.conn.SetDeadline(deadline(c.settings.deadline)); err != nil {
+		return "", err
+	}
+
+	if !c.settings.multiPacket {
+		if _, err := rcon.NewPacket(rcon.SERVERDATA_EXECCOMMAND, rcon.SERVERDATA_EXECCOMMAND_ID, command).WriteTo(c.conn); err != nil {
+			return "", err
+		}
+
+		return c.read()
+	}
+
+	id := c.next()
+	if _, err := rcon.NewPacket(rcon.SERVERDATA_EXECCOMMAND, id, command).WriteTo(c.conn); err != nil {
+		return "", err
+	}
+
+	sentinel := c.next()
+	if _, err := rcon.NewPacket(rcon.SERVERDATA_RESPONSE_VALUE, sentinel, "").WriteTo(c.conn); err != nil {
+		return "", err
+	}
+
+	var response strings.Builder
+
+	for {
+		var packet rcon.Packet
+
+		_, err := packet.ReadFrom(c.conn)
+		if errors.Is(err, rcon.ErrInvalidPacketPadding) && packet.ID != id {
+			// Trailing packet of the previous sentinel.
+			continue
+		}
+
+		if err != nil {
+			return response.String(), err
+		}
+
+		switch {
+		case packet.ID == sentinel:
+			return response.String(), nil
+		case packet.ID == id && packet.Type != rustWorkaroundType:
+			response.WriteString(packet.Body())
+		}
+	}
+}
+
+// read reads the single response packet to the command. Rust answers some
+// commands with ID -1 after the workaround packet, it is accepted too.
+func (c *Conn) read() (string, error) {
+	rust := false
+
+	for {
+		var packet rcon.Packet
+		if _, err := packet.ReadFrom(c.conn); err != nil {
+			return "", err
+		}
+
+		if packet.Type == rustWorkaroundType {
+			rust = true
+
+			continue
+		}
+
+		if packet.ID != rcon.SERVERDATA_EXECCOMMAND_ID && !(rust && packet.ID == -1) {
+			return "", fmt.Errorf("%w: got %d, want %d", rcon.ErrInvalidPacketID, packet.ID, rcon.SERVERDATA_EXECCOMMAND_ID)
+		}
+
+		return packet.Body(), nil
+	}
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// auth sends SERVERDATA_AUTH request and waits for the result. Empty
+// SERVERDATA_RESPONSE_VALUE before the result is skipped.
+func (c *Conn) auth(password string) error {
+	if err := c.conn.SetDeadline(deadline(c.settings.dialTimeout)); err != nil {
+		return err
+	}
+
+	id := rcon.SERVERDATA_AUTH_ID
+	if c.settings.multiPacket {
+		id = c.next()
+	}
+
+	if _, err := rcon.NewPacket(rcon.SERVERDATA_AUTH, id, password).WriteTo(c.conn); err != nil {
+		return err
+	}
+
+	for {
+		var packet rcon.Packet
+		if _, err := packet.ReadFrom(c.conn); err != nil {
+			return err
+		}
+
+		if packet.Type != rcon.SERVERDATA_AUTH_RESPONSE {
+			continue
+		}
+
+		if packet.ID == -1 {
+			return rcon.ErrAuthFailed
+		}
+
+		if packet.ID != id {
+			return fmt.Errorf("%w: got %d, want %d", rcon.ErrInvalidPacketID, packet.ID, id)
+		}
+
+		return nil
+	}
+}
+
+// next returns the ID of the next packet. IDs are positive, so they are
+// never -1 of the failed auth.
+func (c *Conn) next() int32 {
+	c.id++
+	if c.id <= 0 {
+		c.id = 1
+	}
+
+	return c.id
+}
+
+// deadline returns the deadline after the timeout. Zero timeout means no
+// deadline.
+func deadline(timeout time.Duration) time.Time {
+	if timeout == 0 {
+		return time.Time{}
+	}
+
+	return time.Now().Add(timeout)
+}
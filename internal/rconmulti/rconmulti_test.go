@@ -0,0 +1,87 @@
+package rconmulti_test
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorcon/rcon"
+	"github.com/gorcon/rcon-cli/internal/rconmulti"
+	"github.com/gorcon/rcon-cli/internal/rconmulti/rconmultitest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConn(t *testing.T) {
+	help := strings.Repeat("/help shows the list of commands\n", 10)
+
+	server := rconmultitest.NewServer("password", func(command string) string {
+		switch command {
+		case "/help":
+			return help
+		case "status":
+			return "hostname: Black Mesa"
+		}
+
+		return "Unknown command"
+	})
+	defer server.Close()
+
+	t.Run("auth failed", func(t *testing.T) {
+		_, err := rconmulti.Dial(server.Addr(), "wrong")
+		assert.ErrorIs(t, err, rcon.ErrAuthFailed)
+	})
+
+	conn, err := rconmulti.Dial(server.Addr(), "password", rconmulti.SetDeadline(time.Second))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	// Packets of the previous sentinel are skipped by the next commands.
+	t.Run("execute", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			response, err := conn.Execute("/help")
+			assert.NoError(t, err)
+			assert.Equal(t, help, response)
+
+			response, err = conn.Execute("status")
+			assert.NoError(t, err)
+			assert.Equal(t, "hostname: Black Mesa", response)
+		}
+	})
+
+	t.Run("empty command", func(t *testing.T) {
+		_, err := conn.Execute("")
+		assert.ErrorIs(t, err, rcon.ErrCommandEmpty)
+	})
+
+	t.Run("single packet", func(t *testing.T) {
+		conn, err := rconmulti.Dial(server.Addr(), "password", rconmulti.SetMultiPacket(false))
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+
+		response, err := conn.Execute("unknown")
+		assert.NoError(t, err)
+		assert.Equal(t, "Unknown command", response)
+	})
+
+	t.Run("established conn", func(t *testing.T) {
+		established, err := net.Dial("tcp", server.Addr())
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		conn, err := rconmulti.Dial("", "password", rconmulti.SetConn(established))
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+
+		response, err := conn.Execute("status")
+		assert.NoError(t, err)
+		assert.Equal(t, "hostname: Black Mesa", response)
+	})
+}
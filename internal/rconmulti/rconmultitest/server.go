@@ -0,0 +1,108 @@
+// Package rconmultitest contains Source RCON server for tests which splits
+// long responses into several packets.
+package rconmultitest
+
+import (
+	"net"
+
+	"github.com/gorcon/rcon"
+)
+
+// PartSize is the maximum body size of the response packet. Longer
+// responses are split into several packets.
+const PartSize = 16
+
+// Handler returns the response to the command.
+type Handler func(command string) string
+
+// Server is Source RCON server which listens on a random local TCP port.
+// It mirrors empty SERVERDATA_RESPONSE_VALUE packets and follows the mirror
+// with one more packet like Source servers do.
+type Server struct {
+	listener net.Listener
+	password string
+	handler  Handler
+}
+
+// NewServer starts the server which accepts the password and answers the
+// commands with the handler.
+func NewServer(password string, handler Handler) *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+
+	s := Server{listener: listener, password: password, handler: handler}
+
+	go s.serve()
+
+	return &s
+}
+
+// Addr returns the address of the server.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the server.
+func (s *Server) Close() {
+	_ = s.listener.Close()
+}
+
+// serve accepts connections until the server is closed.
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handle(conn)
+	}
+}
+
+// handle answers the requests of the connection.
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		var request rcon.Packet
+		if _, err := request.ReadFrom(conn); err != nil {
+			return
+		}
+
+		switch request.Type {
+		case rcon.SERVERDATA_AUTH:
+			id := request.ID
+			if request.Body() != s.password {
+				id = -1
+			}
+
+			_, _ = rcon.NewPacket(rcon.SERVERDATA_RESPONSE_VALUE, request.ID, "").WriteTo(conn)
+			_, _ = rcon.NewPacket(rcon.SERVERDATA_AUTH_RESPONSE, id, "").WriteTo(conn)
+		case rcon.SERVERDATA_EXECCOMMAND:
+			response := s.handler(request.Body())
+
+			for len(response) > PartSize {
+				_, _ = rcon.NewPacket(rcon.SERVERDATA_RESPONSE_VALUE, request.ID, response[:PartSize]).WriteTo(conn)
+				response = response[PartSize:]
+			}
+
+			_, _ = rcon.NewPacket(rcon.SERVERDATA_RESPONSE_VALUE, request.ID, response).WriteTo(conn)
+		case rcon.SERVERDATA_RESPONSE_VALUE:
+			_, _ = rcon.NewPacket(rcon.SERVERDATA_RESPONSE_VALUE, request.ID, "").WriteTo(conn)
+			_, _ = conn.Write(trailer(request.ID))
+		}
+	}
+}
+
+// trailer returns the packet which Source servers send after the mirror of
+// the empty response packet. Its body is 0x00 0x01 0x00 0x00.
+func trailer(id int32) []byte {
+	return []byte{
+		14, 0, 0, 0,
+		byte(id), byte(id >> 8), byte(id >> 16), byte(id >> 24),
+		0, 0, 0, 0,
+		0, 1, 0, 0, 0, 0,
+	}
+}
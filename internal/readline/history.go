@@ -0,0 +1,51 @@
+package readline
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/adrg/xdg"
+)
+
+// MaxHistory is the number of the newest history entries which are kept.
+const MaxHistory = 1000
+
+// DefaultHistoryFile returns path to the history file of the environment in
+// XDG data directory.
+func DefaultHistoryFile(env string) (string, error) {
+	return xdg.DataFile(filepath.Join("gorcon", "history", env))
+}
+
+// createHistory creates the history file and its directory if they do not
+// exist. The history is readable only by the owner.
+func createHistory(name string) error {
+	const dirPerm, filePerm = 0o700, 0o600
+
+	if err := os.MkdirAll(filepath.Dir(name), dirPerm); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_RDONLY, filePerm)
+	if err != nil {
+		return fmt.Errorf("create history: %w", err)
+	}
+
+	return file.Close()
+}
+
+// AddHistory adds the line to the history and to the history file if it is
+// set. Empty lines and multi-line pastes are skipped, repeats of the
+// previous entry are skipped by the editor.
+func (e *Editor) AddHistory(line string) error {
+	if strings.TrimSpace(line) == "" || strings.Contains(line, "\n") {
+		return nil
+	}
+
+	if err := e.instance.SaveHistory(line); err != nil {
+		return fmt.Errorf("write history: %w", err)
+	}
+
+	return nil
+}
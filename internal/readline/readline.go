@@ -0,0 +1,240 @@
+// Package readline contains the line editor of interactive mode with
+// history, reverse search and tab completion on top of chzyer/readline.
+//
+// The editor switches the terminal to raw mode only while the line is read,
+// so the output of commands is printed in the normal mode. The editor owns
+// the prompt, because it redraws the whole line on every key.
+package readline
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	chzyer "github.com/chzyer/readline"
+	"golang.org/x/term"
+)
+
+// Markers of bracketed paste which the terminal sends around pasted text.
+const (
+	pasteStart = "\x1b[200~"
+	pasteEnd   = "\x1b[201~"
+)
+
+// defaultWidth is the width of the line if the size of the terminal is
+// unknown.
+const defaultWidth = 80
+
+// ErrInterrupted is returned when the user presses Ctrl-C.
+var ErrInterrupted = chzyer.ErrInterrupt
+
+// Editor reads lines from the terminal. Editor implements io.Reader which
+// returns the read lines terminated with a newline.
+type Editor struct {
+	instance *chzyer.Instance
+	paste    *pasteReader
+
+	pending []byte
+}
+
+// New creates the editor which reads in and echoes to out. Raw mode is used
+// when in is a terminal. Completions are the commands suggested by Tab. The
+// history is read from and appended to the history file if it is set.
+func New(in io.Reader, out io.Writer, completions []string, historyFile string) (*Editor, error) {
+	if historyFile != "" {
+		if err := createHistory(historyFile); err != nil {
+			return nil, err
+		}
+	}
+
+	fd := -1
+	if file, ok := in.(*os.File); ok && term.IsTerminal(int(file.Fd())) {
+		fd = int(file.Fd())
+	}
+
+	completions = slices.Clone(completions)
+	slices.Sort(completions)
+
+	items := make([]chzyer.PrefixCompleterInterface, 0, len(completions))
+	for _, completion := range slices.Compact(completions) {
+		items = append(items, chzyer.PcItem(completion))
+	}
+
+	paste := &pasteReader{in: bufio.NewReader(in)}
+
+	var state *term.State
+
+	instance, err := chzyer.NewEx(&chzyer.Config{
+		Stdin:                  io.NopCloser(paste),
+		Stdout:                 out,
+		Stderr:                 out,
+		AutoComplete:           chzyer.NewPrefixCompleter(items...),
+		HistoryFile:            historyFile,
+		HistoryLimit:           MaxHistory,
+		DisableAutoSaveHistory: true,
+		FuncIsTerminal:         func() bool { return fd >= 0 },
+		FuncMakeRaw: func() error {
+			if fd < 0 {
+				return nil
+			}
+
+			var err error
+			state, err = term.MakeRaw(fd)
+
+			return err
+		},
+		FuncExitRaw: func() error {
+			if state == nil {
+				return nil
+			}
+
+			return term.Restore(fd, state)
+		},
+		FuncGetWidth: func() int {
+			if width, _, err := term.GetSize(fd); err == nil {
+				return width
+			}
+
+			return defaultWidth
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Editor{instance: instance, paste: paste}, nil
+}
+
+// SetPrompt sets the prompt which is printed before the next line.
+func (e *Editor) SetPrompt(prompt string) {
+	e.instance.SetPrompt(prompt)
+}
+
+// Close stops reading of the input.
+func (e *Editor) Close() error {
+	return e.instance.Close()
+}
+
+// Read implements io.Reader.
+func (e *Editor) Read(p []byte) (int, error) {
+	if len(e.pending) == 0 {
+		line, err := e.ReadLine()
+		if err != nil {
+			return 0, err
+		}
+
+		e.pending = append([]byte(line), '\n')
+	}
+
+	n := copy(p, e.pending)
+	e.pending = e.pending[n:]
+
+	return n, nil
+}
+
+// ReadLine reads the line. Returns io.EOF when the user presses Ctrl-D on
+// the empty line and ErrInterrupted on Ctrl-C. Multi-line paste is returned
+// as one line wrapped in bracketed paste markers.
+func (e *Editor) ReadLine() (string, error) {
+	line, err := e.instance.Readline()
+
+	if pasted := e.paste.take(); pasted != "" {
+		return pasteStart + line + pasted + pasteEnd, nil
+	}
+
+	// The last line of piped input may have no newline.
+	if errors.Is(err, io.EOF) && line != "" {
+		return line, nil
+	}
+
+	return line, err
+}
+
+// pasteReader passes the input to the editor. Single line bracketed paste
+// is passed without the markers and is inserted to the edited line.
+// Multi-line paste is kept aside and the line is entered at once.
+type pasteReader struct {
+	in *bufio.Reader
+
+	mu     sync.Mutex
+	pasted string
+
+	pending []byte
+}
+
+// Read implements io.Reader.
+func (p *pasteReader) Read(b []byte) (int, error) {
+	for len(p.pending) == 0 {
+		r, _, err := p.in.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+
+		p.pending = []byte(string(r))
+
+		// The terminal sends the marker at once, single Escape key is passed
+		// without waiting for the next keys.
+		marker := pasteStart[1:]
+		if r != rune(pasteStart[0]) || p.in.Buffered() < len(marker) {
+			continue
+		}
+
+		if next, _ := p.in.Peek(len(marker)); string(next) != marker {
+			continue
+		}
+
+		_, _ = p.in.Discard(len(marker))
+
+		if p.pending, err = p.readPaste(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(b, p.pending)
+	p.pending = p.pending[n:]
+
+	return n, nil
+}
+
+// readPaste reads the text until the end of bracketed paste and returns the
+// input for the editor.
+func (p *pasteReader) readPaste() ([]byte, error) {
+	var text strings.Builder
+
+	for !strings.HasSuffix(text.String(), pasteEnd) {
+		r, _, err := p.in.ReadRune()
+		if err != nil {
+			return nil, err
+		}
+
+		text.WriteRune(r)
+	}
+
+	pasted := strings.TrimSuffix(text.String(), pasteEnd)
+	pasted = strings.ReplaceAll(strings.ReplaceAll(pasted, "\r\n", "\n"), "\r", "\n")
+
+	if !strings.Contains(pasted, "\n") {
+		return []byte(pasted), nil
+	}
+
+	p.mu.Lock()
+	p.pasted = pasted
+	p.mu.Unlock()
+
+	return []byte("\r"), nil
+}
+
+// take returns and forgets the multi-line paste.
+func (p *pasteReader) take() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pasted := p.pasted
+	p.pasted = ""
+
+	return pasted
+}
@@ -0,0 +1,139 @@
+package readline_test
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorcon/rcon-cli/internal/readline"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	up    = "\x1b[A"
+	down  = "\x1b[B"
+	left  = "\x1b[D"
+	ctrlR = "\x12"
+)
+
+// newEditor creates the editor which reads the input.
+func newEditor(t *testing.T, input string, w io.Writer, completions []string, history string) *readline.Editor {
+	t.Helper()
+
+	e, err := readline.New(strings.NewReader(input), w, completions, history)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { e.Close() })
+
+	return e
+}
+
+// readLines returns all lines which the editor reads from the input.
+func readLines(e *readline.Editor) []string {
+	var lines []string
+
+	scanner := bufio.NewScanner(e)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	return lines
+}
+
+func TestEditor_ReadLine(t *testing.T) {
+	t.Run("editing", func(t *testing.T) {
+		e := newEditor(t, "plers"+left+left+left+"ay\x05\r"+"kick x\x7fBob\x01\x0bstatus\r", io.Discard, nil, "")
+
+		assert.Equal(t, []string{"players", "status"}, readLines(e))
+	})
+
+	t.Run("history", func(t *testing.T) {
+		e := newEditor(t, "new"+up+"\r"+"x"+up+down+"\r", io.Discard, nil, "")
+		assert.NoError(t, e.AddHistory("list"))
+		assert.NoError(t, e.AddHistory("save"))
+		assert.NoError(t, e.AddHistory("save"))
+
+		assert.Equal(t, []string{"save", "x"}, readLines(e))
+	})
+
+	t.Run("reverse search", func(t *testing.T) {
+		e := newEditor(t, ctrlR+"sa\r"+ctrlR+"sa"+ctrlR+"\x05 now\r", io.Discard, nil, "")
+		assert.NoError(t, e.AddHistory("save-all"))
+		assert.NoError(t, e.AddHistory("list"))
+		assert.NoError(t, e.AddHistory("say hello"))
+
+		assert.Equal(t, []string{"say hello", "save-all now"}, readLines(e))
+	})
+
+	t.Run("completion", func(t *testing.T) {
+		var w bytes.Buffer
+
+		e := newEditor(t, "pl\t\r"+"save\t\t\r", &w, []string{"save-off", "save-on", "players", "players"}, "")
+		e.SetPrompt("> ")
+
+		assert.Equal(t, []string{"players ", "save-o"}, readLines(e))
+		assert.Contains(t, w.String(), "save-off   save-on")
+	})
+
+	t.Run("paste", func(t *testing.T) {
+		e := newEditor(t, "say \x1b[200~hi\x1b[201~\r\x1b[200~list\rsave\x1b[201~", io.Discard, nil, "")
+
+		assert.Equal(t, []string{"say hi", "\x1b[200~list", "save\x1b[201~"}, readLines(e))
+	})
+
+	t.Run("eof and interrupt", func(t *testing.T) {
+		e := newEditor(t, "list\x04", io.Discard, nil, "")
+		line, err := e.ReadLine()
+		assert.NoError(t, err)
+		assert.Equal(t, "list", line)
+
+		_, err = newEditor(t, "\x04list\r", io.Discard, nil, "").ReadLine()
+		assert.ErrorIs(t, err, io.EOF)
+
+		_, err = newEditor(t, "list\x03", io.Discard, nil, "").ReadLine()
+		assert.ErrorIs(t, err, readline.ErrInterrupted)
+	})
+}
+
+func TestEditor_AddHistory(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "history", "prod")
+
+	e := newEditor(t, up+up+"\r", io.Discard, nil, name)
+	assert.NoError(t, e.AddHistory("list"))
+	assert.NoError(t, e.AddHistory("save"))
+	assert.NoError(t, e.AddHistory(" "))
+
+	data, err := os.ReadFile(name)
+	assert.NoError(t, err)
+	assert.Equal(t, "list\nsave\n", string(data))
+
+	info, err := os.Stat(name)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	e = newEditor(t, up+up+"\r", io.Discard, nil, name)
+	assert.Equal(t, []string{"list"}, readLines(e))
+
+	t.Run("max history", func(t *testing.T) {
+		lines := make([]string, readline.MaxHistory+10)
+		for i := range lines {
+			lines[i] = "command"
+		}
+
+		lines[10] = "first"
+		assert.NoError(t, os.WriteFile(name, []byte(strings.Join(lines, "\n")), 0o600))
+
+		e := newEditor(t, strings.Repeat(up, len(lines))+"\r", io.Discard, nil, name)
+		assert.Equal(t, []string{"first"}, readLines(e))
+
+		data, err := os.ReadFile(name)
+		assert.NoError(t, err)
+		assert.Len(t, strings.Split(strings.TrimSpace(string(data)), "\n"), readline.MaxHistory)
+	})
+}
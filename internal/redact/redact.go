@@ -0,0 +1,82 @@
+// Package redact masks secrets in commands and responses before they are
+// written to any output.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Mask replaces the secret parts of the text.
+const Mask = "***"
+
+// Redactor replaces matches of the patterns with Mask. If a pattern has
+// capture groups only the groups are replaced, otherwise the whole match.
+type Redactor struct {
+	patterns []*regexp.Regexp
+}
+
+// New compiles the patterns and creates a new Redactor.
+func New(patterns []string) (*Redactor, error) {
+	redactor := Redactor{patterns: make([]*regexp.Regexp, 0, len(patterns))}
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redact pattern %q: %w", pattern, err)
+		}
+
+		redactor.patterns = append(redactor.patterns, re)
+	}
+
+	return &redactor, nil
+}
+
+// Redact returns the text with masked secrets. Nil Redactor returns the text
+// as is.
+func (r *Redactor) Redact(text string) string {
+	if r == nil {
+		return text
+	}
+
+	for _, re := range r.patterns {
+		if re.NumSubexp() == 0 {
+			text = re.ReplaceAllLiteralString(text, Mask)
+
+			continue
+		}
+
+		text = replaceGroups(re, text)
+	}
+
+	return text
+}
+
+// replaceGroups replaces the capture groups of every match with Mask.
+func replaceGroups(re *regexp.Regexp, text string) string {
+	matches := re.FindAllStringSubmatchIndex(text, -1)
+	if matches == nil {
+		return text
+	}
+
+	result := make([]byte, 0, len(text))
+	last := 0
+
+	for _, match := range matches {
+		for group := 1; group <= re.NumSubexp(); group++ {
+			start, end := match[2*group], match[2*group+1]
+			// Skip not participating and nested groups.
+			if start < 0 || start < last {
+				continue
+			}
+
+			result = append(result, text[last:start]...)
+			result = append(result, Mask...)
+			last = end
+		}
+	}
+
+	result = append(result, text[last:]...)
+
+	return string(result)
+}
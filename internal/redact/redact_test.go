@@ -0,0 +1,44 @@
+package redact_test
+
+import (
+	"testing"
+
+	"github.com/gorcon/rcon-cli/internal/redact"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor_Redact(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		text     string
+		want     string
+	}{
+		{"no patterns", nil, "login secret", "login secret"},
+		{"whole match", []string{`secret\d+`}, "login secret42 now", "login *** now"},
+		{"capture group", []string{`^login (\S+)`}, "login secret42", "login ***"},
+		{"several groups", []string{`user (\S+) pass (\S+)`}, "user bob pass 123", "user *** pass ***"},
+		{"several matches", []string{`token=(\w+)`}, "token=a token=b", "token=*** token=***"},
+		{"optional group", []string{`key(=(\w+))?`}, "key=a key", "key*** key"},
+		{"no match", []string{`^login (\S+)`}, "status", "status"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			redactor, err := redact.New(tt.patterns)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, redactor.Redact(tt.text))
+		})
+	}
+
+	t.Run("nil redactor", func(t *testing.T) {
+		var redactor *redact.Redactor
+		assert.Equal(t, "login secret", redactor.Redact("login secret"))
+	})
+}
+
+func TestNew(t *testing.T) {
+	_, err := redact.New([]string{"("})
+	assert.ErrorContains(t, err, `redact pattern "("`)
+}
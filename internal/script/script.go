@@ -0,0 +1,260 @@
+// Package script contains the interpreter of the simple scripting language
+// for RCON automation.
+//
+// Every line contains one statement, empty lines and lines starting with #
+// are skipped:
+//
+//	CONNECT prod
+//	SEND "say Restart in 5 minutes"
+//	SLEEP 5m
+//	LOOP 3
+//	  SEND "save"
+//	  IF RESPONSE MATCHES /failed/ THEN
+//	    SLEEP 10s
+//	  END
+//	END
+//	EXPECT /saved/
+package script
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+)
+
+// Statements of the language.
+const (
+	StatementConnect = "CONNECT"
+	StatementSend    = "SEND"
+	StatementExpect  = "EXPECT"
+	StatementSleep   = "SLEEP"
+	StatementLoop    = "LOOP"
+	StatementIf      = "IF"
+	StatementEnd     = "END"
+)
+
+// Script errors.
+var (
+	// ErrSyntax is returned when the script cannot be parsed.
+	ErrSyntax = errors.New("syntax error")
+
+	// ErrExpectFailed is returned when the last response does not match
+	// the EXPECT pattern.
+	ErrExpectFailed = errors.New("expectation failed")
+)
+
+// Runtime executes the statements which talk to the servers.
+type Runtime interface {
+	// Connect switches the following commands to the config environment.
+	Connect(env string) error
+	// Send executes the command and returns its response.
+	Send(command string) (string, error)
+}
+
+// Script is the parsed script.
+type Script struct {
+	statements []statement
+}
+
+// statement is the parsed line of the script. Block statements contain
+// the nested statements in body.
+type statement struct {
+	line     int
+	keyword  string
+	arg      string
+	re       *regexp.Regexp
+	duration time.Duration
+	count    int
+	body     []statement
+}
+
+// Load reads and parses the script file.
+func Load(name string) (*Script, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("read script %s: %w", name, err)
+	}
+	defer file.Close()
+
+	s, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("parse script %s: %w", name, err)
+	}
+
+	return s, nil
+}
+
+// Parse parses the script.
+func Parse(r io.Reader) (*Script, error) {
+	// The top of the stack is the body the statements are added to.
+	stack := [][]statement{nil}
+	var blocks []statement
+
+	scanner := bufio.NewScanner(r)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		st, err := parseLine(n, line)
+		if err != nil {
+			return nil, err
+		}
+
+		switch st.keyword {
+		case StatementLoop, StatementIf:
+			blocks = append(blocks, st)
+			stack = append(stack, nil)
+		case StatementEnd:
+			if len(blocks) == 0 {
+				return nil, fmt.Errorf("%w: line %d: END without LOOP or IF", ErrSyntax, n)
+			}
+
+			block := blocks[len(blocks)-1]
+			block.body = stack[len(stack)-1]
+			blocks, stack = blocks[:len(blocks)-1], stack[:len(stack)-1]
+			stack[len(stack)-1] = append(stack[len(stack)-1], block)
+		default:
+			stack[len(stack)-1] = append(stack[len(stack)-1], st)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(blocks) != 0 {
+		block := blocks[len(blocks)-1]
+
+		return nil, fmt.Errorf("%w: line %d: %s without END", ErrSyntax, block.line, block.keyword)
+	}
+
+	return &Script{statements: stack[0]}, nil
+}
+
+// parseLine parses the statement and its arguments.
+func parseLine(n int, line string) (statement, error) {
+	keyword, arg, _ := strings.Cut(line, " ")
+	st := statement{line: n, keyword: strings.ToUpper(keyword), arg: strings.TrimSpace(arg)}
+
+	var err error
+
+	switch st.keyword {
+	case StatementConnect:
+		if st.arg == "" {
+			err = errors.New("environment is not set")
+		}
+	case StatementSend:
+		st.arg, err = parseCommand(st.arg)
+	case StatementExpect:
+		st.re, err = parsePattern(st.arg)
+	case StatementSleep:
+		st.duration, err = config.ParseDuration(st.arg)
+	case StatementLoop:
+		if st.count, err = strconv.Atoi(st.arg); err == nil && st.count < 0 {
+			err = errors.New("negative count")
+		}
+	case StatementIf:
+		const prefix, suffix = "RESPONSE MATCHES ", " THEN"
+
+		upper := strings.ToUpper(st.arg)
+		if !strings.HasPrefix(upper, prefix) || !strings.HasSuffix(upper, suffix) {
+			err = errors.New("expected IF RESPONSE MATCHES /regex/ THEN")
+
+			break
+		}
+
+		st.re, err = parsePattern(strings.TrimSpace(st.arg[len(prefix) : len(st.arg)-len(suffix)]))
+	case StatementEnd:
+		if st.arg != "" {
+			err = errors.New("unexpected arguments")
+		}
+	default:
+		err = fmt.Errorf("unknown statement %q", keyword)
+	}
+
+	if err != nil {
+		return st, fmt.Errorf("%w: line %d: %w", ErrSyntax, n, err)
+	}
+
+	return st, nil
+}
+
+// parseCommand parses the quoted command. Unquoted text is taken as is.
+func parseCommand(arg string) (string, error) {
+	if arg == "" {
+		return "", errors.New("command is not set")
+	}
+
+	if !strings.HasPrefix(arg, `"`) {
+		return arg, nil
+	}
+
+	return strconv.Unquote(arg)
+}
+
+// parsePattern compiles the regular expression between slashes.
+func parsePattern(arg string) (*regexp.Regexp, error) {
+	if len(arg) < 2 || !strings.HasPrefix(arg, "/") || !strings.HasSuffix(arg, "/") {
+		return nil, errors.New("expected /regex/")
+	}
+
+	return regexp.Compile(arg[1 : len(arg)-1])
+}
+
+// Run executes the script statements in order. The first failed statement
+// stops the script.
+func (s *Script) Run(rt Runtime) error {
+	var response string
+
+	return run(rt, s.statements, &response)
+}
+
+// run executes the statements. Response contains the last response.
+func run(rt Runtime, statements []statement, response *string) error {
+	for _, st := range statements {
+		var err error
+
+		switch st.keyword {
+		case StatementConnect:
+			err = rt.Connect(st.arg)
+		case StatementSend:
+			*response, err = rt.Send(st.arg)
+		case StatementExpect:
+			if !st.re.MatchString(*response) {
+				err = fmt.Errorf("%w: response does not match /%s/", ErrExpectFailed, st.re)
+			}
+		case StatementSleep:
+			time.Sleep(st.duration)
+		case StatementLoop:
+			for i := 0; i < st.count && err == nil; i++ {
+				err = run(rt, st.body, response)
+			}
+		case StatementIf:
+			if st.re.MatchString(*response) {
+				err = run(rt, st.body, response)
+			}
+		}
+
+		if err != nil {
+			// Errors of nested statements already have the line.
+			if st.keyword == StatementLoop || st.keyword == StatementIf {
+				return err
+			}
+
+			return fmt.Errorf("line %d: %s: %w", st.line, st.keyword, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package script_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gorcon/rcon-cli/internal/script"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockRuntime records the calls and answers commands from responses.
+type mockRuntime struct {
+	calls     []string
+	responses map[string]string
+}
+
+func (m *mockRuntime) Connect(env string) error {
+	m.calls = append(m.calls, "connect "+env)
+
+	if env == "missing" {
+		return errors.New("environment not found")
+	}
+
+	return nil
+}
+
+func (m *mockRuntime) Send(command string) (string, error) {
+	m.calls = append(m.calls, command)
+
+	return m.responses[command], nil
+}
+
+func TestRun(t *testing.T) {
+	t.Run("statements", func(t *testing.T) {
+		s, err := script.Parse(strings.NewReader(`# Restart announcement
+CONNECT prod
+send "say \"hi\""
+LOOP 2
+  SEND save
+  IF RESPONSE MATCHES /failed/ THEN
+    SEND "retry"
+  END
+END
+IF response matches /saved/ then
+  SEND never
+END
+SLEEP 1ms
+SEND status
+EXPECT /^online$/
+`))
+		assert.NoError(t, err)
+
+		rt := &mockRuntime{responses: map[string]string{"save": "save failed", "status": "online"}}
+
+		err = s.Run(rt)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"connect prod", `say "hi"`, "save", "retry", "save", "retry", "status"}, rt.calls)
+	})
+
+	t.Run("expect failed", func(t *testing.T) {
+		s, err := script.Parse(strings.NewReader("SEND status\nLOOP 1\n  EXPECT /online/\nEND\n"))
+		assert.NoError(t, err)
+
+		err = s.Run(&mockRuntime{responses: map[string]string{"status": "offline"}})
+		assert.ErrorIs(t, err, script.ErrExpectFailed)
+		assert.ErrorContains(t, err, "line 3: EXPECT")
+	})
+
+	t.Run("runtime error", func(t *testing.T) {
+		s, err := script.Parse(strings.NewReader("CONNECT missing\nSEND status\n"))
+		assert.NoError(t, err)
+
+		rt := &mockRuntime{}
+
+		err = s.Run(rt)
+		assert.EqualError(t, err, "line 1: CONNECT: environment not found")
+		assert.Equal(t, []string{"connect missing"}, rt.calls)
+	})
+}
+
+func TestParse(t *testing.T) {
+	tests := map[string]string{
+		"unknown statement": "JUMP 10",
+		"empty send":        "SEND",
+		"bad pattern":       "EXPECT online",
+		"bad regex":         "EXPECT /(/",
+		"bad duration":      "SLEEP soon",
+		"bad count":         "LOOP many\nEND",
+		"bad if":            "IF RESPONSE /x/ THEN\nEND",
+		"missing end":       "LOOP 2\nSEND save",
+		"extra end":         "SEND save\nEND",
+	}
+
+	for name, text := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := script.Parse(strings.NewReader(text))
+			assert.ErrorIs(t, err, script.ErrSyntax)
+		})
+	}
+}
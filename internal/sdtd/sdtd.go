@@ -0,0 +1,107 @@
+// Package sdtd contains helpers for the 7 Days to Die telnet console.
+//
+// 7DTD interleaves asynchronous log lines with command output. The server
+// echoes every executed command with an INF line, which is used as the start
+// marker of the response. The response ends with the line ending of its last
+// line or with the start marker of the next command. Any log line received
+// before the marker or in the middle of the output is treated as an event and
+// not as part of the response.
+package sdtd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrIncomplete is returned when the response does not end with the line
+// ending, e.g. it is cut by the quiet period or the deadline of the client.
+var ErrIncomplete = errors.New("incomplete 7dtd response")
+
+// ExecutingCommandMarker is the part of the log line that 7DTD prints when
+// it starts to execute a command received from telnet.
+const ExecutingCommandMarker = "INF Executing command '%s' by Telnet from "
+
+// logLineRegexp matches 7DTD log lines, e.g.
+// `2020-11-14T23:09:20 31220.643 INF Time: 521.09m FPS: 36.94`.
+var logLineRegexp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2} \d+\.\d+ (INF|WRN|ERR|EXC|LOG) `)
+
+// Response contains command output separated from asynchronous log lines.
+type Response struct {
+	// Output is the command output without the echoed start marker.
+	Output string
+	// Events contains log lines which were received together with the
+	// command output but do not belong to it.
+	Events []string
+}
+
+// IsLogLine reports whether the line is a 7DTD log line.
+func IsLogLine(line string) bool {
+	return logLineRegexp.MatchString(line)
+}
+
+// isCommandMarker reports whether the line is the start marker of a command.
+func isCommandMarker(line string) bool {
+	return IsLogLine(line) && strings.Contains(line, "INF Executing command '")
+}
+
+// Parse splits raw telnet response to the command into output and events.
+// The raw response must keep its line endings. If the start marker is not
+// found, all non log lines are treated as output. Lines after the start
+// marker of the next command are events. ErrIncomplete is returned if the
+// last line of the response is not terminated.
+func Parse(command string, raw string) (Response, error) {
+	var response Response
+
+	raw = strings.ReplaceAll(raw, "\r\n", "\n")
+	if raw != "" && !strings.HasSuffix(raw, "\n") {
+		last := raw[strings.LastIndex(raw, "\n")+1:]
+
+		return response, fmt.Errorf("%w: no line ending after %q", ErrIncomplete, last)
+	}
+
+	marker := fmt.Sprintf(ExecutingCommandMarker, command)
+	lines := strings.Split(strings.TrimSuffix(raw, "\n"), "\n")
+
+	start, end := -1, len(lines)
+	for i, line := range lines {
+		if start < 0 && IsLogLine(line) && strings.Contains(line, marker) {
+			start = i
+
+			continue
+		}
+
+		if start >= 0 && isCommandMarker(line) {
+			end = i
+
+			break
+		}
+	}
+
+	output := make([]string, 0, len(lines))
+
+	for i, line := range lines {
+		switch {
+		case i == start:
+			continue
+		case IsLogLine(line):
+			response.Events = append(response.Events, line)
+		case i >= end:
+			// The output of the next command is not the response.
+			if strings.TrimSpace(line) != "" {
+				response.Events = append(response.Events, line)
+			}
+		case i > start:
+			output = append(output, line)
+		case strings.TrimSpace(line) != "":
+			// Non log lines before the start marker are leftovers from the
+			// previous command.
+			response.Events = append(response.Events, line)
+		}
+	}
+
+	response.Output = strings.TrimSpace(strings.Join(output, "\n"))
+
+	return response, nil
+}
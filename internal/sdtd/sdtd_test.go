@@ -0,0 +1,99 @@
+package sdtd_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorcon/rcon-cli/internal/sdtd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		command string
+		output  string
+		events  int
+		err     error
+	}{
+		{
+			name:    "log lines mid-output",
+			file:    "help_with_log_lines.txt",
+			command: "help",
+			output: "*** Generic Console Help ***\n" +
+				"To get further help on a specific topic or command type (without the brackets)\n" +
+				"    help <topic / command>\n\n" +
+				"*** List of Commands ***\n" +
+				" admin => Manage user permission levels\n" +
+				" ban => Manage ban entries\n" +
+				" help => Help on console and specific commands",
+			events: 2,
+		},
+		{
+			name:    "multi chunk response",
+			file:    "version_multi_chunk.txt",
+			command: "version",
+			output: "Game version: Alpha 20.7 (b1) Compatibility Version: Alpha 20.7\n" +
+				"Mod TFP_CommandExtensions: 20.7\n" +
+				"Mod TFP_MapRendering: 20.7",
+			events: 2,
+		},
+		{
+			name:    "no start marker",
+			file:    "no_marker.txt",
+			command: "gettime",
+			output:  "Day 5, 13:42",
+			events:  1,
+		},
+		{
+			name:    "crlf line endings",
+			file:    "gettime_crlf.txt",
+			command: "gettime",
+			output:  "Day 5, 13:45",
+			events:  0,
+		},
+		{
+			name:    "next command",
+			file:    "gettime_interleaved.txt",
+			command: "gettime",
+			output:  "Day 5, 14:02",
+			events:  2,
+		},
+		{
+			name:    "truncated response",
+			file:    "lp_truncated.txt",
+			command: "lp",
+			err:     sdtd.ErrIncomplete,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			response, err := sdtd.Parse(tt.command, string(raw))
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.output, response.Output)
+			assert.Len(t, response.Events, tt.events)
+		})
+	}
+}
+
+func TestIsLogLine(t *testing.T) {
+	assert.True(t, sdtd.IsLogLine("2020-11-14T23:09:20 31220.643 INF Executing command 'help' by Telnet from 127.0.0.1:1"))
+	assert.True(t, sdtd.IsLogLine("2020-11-14T23:09:20 31220.643 ERR Something went wrong"))
+	assert.False(t, sdtd.IsLogLine(" admin => Manage user permission levels"))
+	assert.False(t, sdtd.IsLogLine("Day 5, 13:42"))
+}
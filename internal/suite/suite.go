@@ -0,0 +1,177 @@
+// Package suite contains response assertion suites for server smoke tests.
+package suite
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Result statuses.
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+// ErrEmptyCommand is returned when a suite case has no command.
+var ErrEmptyCommand = errors.New("command is not set")
+
+// Suite contains the list of cases to execute.
+type Suite struct {
+	Cases []Case `json:"cases" yaml:"cases"`
+}
+
+// Case describes one command and the expectations to its response.
+type Case struct {
+	Name           string          `json:"name" yaml:"name"`
+	Env            string          `json:"env" yaml:"env"`
+	Command        string          `json:"command" yaml:"command"`
+	ExpectRegex    string          `json:"expect_regex" yaml:"expect_regex"`
+	NotExpectRegex string          `json:"not_expect_regex" yaml:"not_expect_regex"`
+	Timeout        config.Duration `json:"timeout" yaml:"timeout"`
+	Skip           bool            `json:"skip" yaml:"skip"`
+
+	expect    *regexp.Regexp
+	notExpect *regexp.Regexp
+}
+
+// Result contains the result of the executed case.
+type Result struct {
+	Name     string        `json:"name"`
+	Env      string        `json:"env"`
+	Seq      int           `json:"seq,omitempty"`
+	Command  string        `json:"command"`
+	Status   string        `json:"status"`
+	Response string        `json:"response,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Summary contains the number of cases by status.
+type Summary struct {
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+}
+
+// Load reads the suite file and compiles the case patterns.
+func Load(name string) (*Suite, error) {
+	file, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("read suite %s: %w", name, err)
+	}
+
+	suite := new(Suite)
+	if err := yaml.Unmarshal(file, suite); err != nil {
+		return nil, fmt.Errorf("parse suite %s: %w", name, err)
+	}
+
+	for i := range suite.Cases {
+		if err := suite.Cases[i].compile(); err != nil {
+			return nil, fmt.Errorf("case %d (%s): %w", i+1, suite.Cases[i].Name, err)
+		}
+	}
+
+	return suite, nil
+}
+
+// Check verifies the response against the case expectations.
+func (c *Case) Check(response string) error {
+	if c.expect != nil && !c.expect.MatchString(response) {
+		return fmt.Errorf("response does not match %q", c.ExpectRegex)
+	}
+
+	if c.notExpect != nil && c.notExpect.MatchString(response) {
+		return fmt.Errorf("response matches %q", c.NotExpectRegex)
+	}
+
+	return nil
+}
+
+func (c *Case) compile() error {
+	if c.Command == "" {
+		return ErrEmptyCommand
+	}
+
+	if c.Name == "" {
+		c.Name = c.Command
+	}
+
+	var err error
+
+	if c.ExpectRegex != "" {
+		if c.expect, err = regexp.Compile(c.ExpectRegex); err != nil {
+			return fmt.Errorf("expect_regex: %w", err)
+		}
+	}
+
+	if c.NotExpectRegex != "" {
+		if c.notExpect, err = regexp.Compile(c.NotExpectRegex); err != nil {
+			return fmt.Errorf("not_expect_regex: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Summarize counts results by status.
+func Summarize(results []Result) Summary {
+	var summary Summary
+
+	for _, result := range results {
+		switch result.Status {
+		case StatusPass:
+			summary.Passed++
+		case StatusFail:
+			summary.Failed++
+		case StatusSkip:
+			summary.Skipped++
+		}
+	}
+
+	return summary
+}
+
+// WriteTAP prints results in Test Anything Protocol format.
+func WriteTAP(w io.Writer, results []Result) {
+	_, _ = fmt.Fprintf(w, "TAP version 13\n1..%d\n", len(results))
+
+	for i, result := range results {
+		switch result.Status {
+		case StatusPass:
+			_, _ = fmt.Fprintf(w, "ok %d - %s\n", i+1, result.Name)
+		case StatusSkip:
+			_, _ = fmt.Fprintf(w, "ok %d - %s # SKIP\n", i+1, result.Name)
+		default:
+			_, _ = fmt.Fprintf(w, "not ok %d - %s\n", i+1, result.Name)
+			_, _ = fmt.Fprintf(w, "  ---\n  env: %s\n  command: %s\n  message: %s\n  ...\n",
+				result.Env, result.Command, result.Error)
+		}
+	}
+
+	summary := Summarize(results)
+	_, _ = fmt.Fprintf(w, "# passed %d, failed %d, skipped %d\n", summary.Passed, summary.Failed, summary.Skipped)
+}
+
+// WriteJSON prints results and summary as JSON object. Run ID identifies
+// the run in other outputs, it is omitted if empty.
+func WriteJSON(w io.Writer, runID string, results []Result) error {
+	report := struct {
+		RunID   string   `json:"run_id,omitempty"`
+		Results []Result `json:"results"`
+		Summary Summary  `json:"summary"`
+	}{RunID: runID, Results: results, Summary: Summarize(results)}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(report)
+}
@@ -0,0 +1,82 @@
+package suite_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/gorcon/rcon-cli/internal/suite"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("no errors", func(t *testing.T) {
+		suiteFileName := "suite-test-local.yaml"
+		createFile(suiteFileName, "cases:\n  - command: help\n    expect_regex: help\n  - name: skipped\n    command: list\n    skip: true\n")
+		defer os.Remove(suiteFileName)
+
+		s, err := suite.Load(suiteFileName)
+		assert.NoError(t, err)
+		assert.Len(t, s.Cases, 2)
+		assert.Equal(t, "help", s.Cases[0].Name)
+		assert.True(t, s.Cases[1].Skip)
+	})
+
+	t.Run("invalid regex", func(t *testing.T) {
+		suiteFileName := "suite-test-local.yaml"
+		createFile(suiteFileName, "cases:\n  - command: help\n    expect_regex: \"(\"\n")
+		defer os.Remove(suiteFileName)
+
+		_, err := suite.Load(suiteFileName)
+		assert.ErrorContains(t, err, "case 1 (help): expect_regex")
+	})
+
+	t.Run("empty command", func(t *testing.T) {
+		suiteFileName := "suite-test-local.yaml"
+		createFile(suiteFileName, "cases:\n  - name: nothing\n")
+		defer os.Remove(suiteFileName)
+
+		_, err := suite.Load(suiteFileName)
+		assert.ErrorIs(t, err, suite.ErrEmptyCommand)
+	})
+}
+
+func TestCase_Check(t *testing.T) {
+	suiteFileName := "suite-test-local.yaml"
+	createFile(suiteFileName, "cases:\n  - command: list\n    expect_regex: \"players: \\\\d+\"\n    not_expect_regex: ERROR\n")
+	defer os.Remove(suiteFileName)
+
+	s, err := suite.Load(suiteFileName)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.NoError(t, s.Cases[0].Check("players: 5"))
+	assert.EqualError(t, s.Cases[0].Check("nobody"), `response does not match "players: \\d+"`)
+	assert.EqualError(t, s.Cases[0].Check("players: 5 ERROR"), `response matches "ERROR"`)
+}
+
+func TestWriteTAP(t *testing.T) {
+	w := bytes.Buffer{}
+
+	suite.WriteTAP(&w, []suite.Result{
+		{Name: "help", Status: suite.StatusPass},
+		{Name: "list", Env: "default", Command: "list", Status: suite.StatusFail, Error: "boom"},
+		{Name: "skipped", Status: suite.StatusSkip},
+	})
+
+	assert.Equal(t, "TAP version 13\n1..3\nok 1 - help\nnot ok 2 - list\n"+
+		"  ---\n  env: default\n  command: list\n  message: boom\n  ...\n"+
+		"ok 3 - skipped # SKIP\n# passed 1, failed 1, skipped 1\n", w.String())
+}
+
+func createFile(name, stringBody string) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.WriteString(stringBody)
+
+	return err
+}
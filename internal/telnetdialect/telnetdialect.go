@@ -0,0 +1,391 @@
+// Package telnetdialect contains the client of TELNET consoles which differ
+// in the login handshake and in how the responses end. The dialect tells
+// the client which prompts to wait for and how to find the end of the
+// response: by the command prompt or by silence of the server.
+package telnetdialect
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorcon/telnet"
+)
+
+// Default settings of Conn.
+const (
+	DefaultDialTimeout = 5 * time.Second
+	DefaultDeadline    = 5 * time.Second
+	DefaultQuietPeriod = 500 * time.Millisecond
+)
+
+// readBufferSize is the size of the chunks the connection is read by.
+const readBufferSize = 4096
+
+// Dialect describes the login handshake and the responses of the console.
+// Nil regular expressions are not waited for.
+type Dialect struct {
+	// Username is sent after LoginPrompt.
+	Username    string
+	LoginPrompt *regexp.Regexp
+	// PasswordPrompt is waited for before the password is sent.
+	PasswordPrompt *regexp.Regexp
+	// AuthFailed matches the response to the wrong password.
+	AuthFailed *regexp.Regexp
+	// Prompt matches the command prompt which ends the response. Without it
+	// the response ends when the server is quiet for the quiet period.
+	Prompt *regexp.Regexp
+	// Terminator is appended to the commands. Defaults to CRLF.
+	Terminator string
+	// SkipBannerLines is the number of lines the server sends after the
+	// login. Without it the banner ends with the prompt or by silence.
+	SkipBannerLines int
+}
+
+// Settings contains options of Conn.
+type Settings struct {
+	dialTimeout time.Duration
+	deadline    time.Duration
+	quietPeriod time.Duration
+	conn        net.Conn
+}
+
+// Option allows to inject settings to Settings.
+type Option func(s *Settings)
+
+// SetDialTimeout injects the timeout of dial and login to Settings.
+func SetDialTimeout(timeout time.Duration) Option {
+	return func(s *Settings) {
+		s.dialTimeout = timeout
+	}
+}
+
+// SetDeadline injects the execution timeout of the command to Settings.
+// Zero means no deadline.
+func SetDeadline(timeout time.Duration) Option {
+	return func(s *Settings) {
+		s.deadline = timeout
+	}
+}
+
+// SetQuietPeriod injects the silence which ends the response to Settings.
+func SetQuietPeriod(period time.Duration) Option {
+	return func(s *Settings) {
+		s.quietPeriod = period
+	}
+}
+
+// SetConn injects the established connection to Settings. Dial uses it
+// instead of dialing the address.
+func SetConn(conn net.Conn) Option {
+	return func(s *Settings) {
+		s.conn = conn
+	}
+}
+
+// Conn is the logged in TELNET connection.
+type Conn struct {
+	conn     net.Conn
+	dialect  Dialect
+	settings Settings
+	// pending contains received bytes which are not consumed yet.
+	pending []byte
+
+	// mu serializes the commands.
+	mu sync.Mutex
+}
+
+// Dial connects to the console and logs in with the handshake of the
+// dialect. telnet.ErrAuthFailed is returned if the server rejects the
+// password.
+func Dial(address string, password string, dialect Dialect, options ...Option) (*Conn, error) {
+	settings := Settings{dialTimeout: DefaultDialTimeout, deadline: DefaultDeadline, quietPeriod: DefaultQuietPeriod}
+	for _, option := range options {
+		option(&settings)
+	}
+
+	if dialect.Terminator == "" {
+		dialect.Terminator = telnet.CRLF
+	}
+
+	conn := settings.conn
+	if conn == nil {
+		var err error
+		if conn, err = net.DialTimeout("tcp", address, settings.dialTimeout); err != nil {
+			return nil, fmt.Errorf("telnet: %w", err)
+		}
+	}
+
+	c := Conn{conn: conn, dialect: dialect, settings: settings}
+
+	if err := c.login(password); err != nil {
+		_ = conn.Close()
+
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// Execute sends the command and returns the response.
+// os.ErrDeadlineExceeded is returned if the response does not end within
+// the deadline.
+func (c *Conn) Execute(command string) (string, error) {
+	response, err := c.ExecuteRaw(command)
+
+	return clean(response), err
+}
+
+// ExecuteRaw is Execute which returns the response as it is received with
+// the line endings, so the caller can tell the complete response from the
+// response cut by the quiet period.
+func (c *Conn) ExecuteRaw(command string) (string, error) {
+	if command == "" {
+		return "", telnet.ErrCommandEmpty
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.write(command); err != nil {
+		return "", err
+	}
+
+	var deadline time.Time
+	if c.settings.deadline > 0 {
+		deadline = time.Now().Add(c.settings.deadline)
+	}
+
+	var response string
+	var err error
+
+	if c.dialect.Prompt != nil {
+		response, _, err = c.readUntil(c.dialect.Prompt, deadline)
+	} else {
+		response, err = c.readQuiet(deadline)
+	}
+
+	response = strings.ReplaceAll(response, "\x00", "")
+	if err != nil {
+		return response, fmt.Errorf("telnet: %w", err)
+	}
+
+	return response, nil
+}
+
+// Send sends the command without waiting for the response. The response is
+// received by Stream.
+func (c *Conn) Send(command string) error {
+	if command == "" {
+		return telnet.ErrCommandEmpty
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.write(command)
+}
+
+// Stream passes the lines of the server output to the handler until the
+// connection is closed. Command prompts are removed from the lines.
+func (c *Conn) Stream(handler func(line string)) error {
+	if err := c.conn.SetReadDeadline(time.Time{}); err != nil {
+		return fmt.Errorf("telnet: %w", err)
+	}
+
+	for {
+		for {
+			i := bytes.IndexByte(c.pending, '\n')
+			if i < 0 {
+				break
+			}
+
+			line := string(c.pending[:i])
+			c.pending = c.pending[i+1:]
+
+			if c.dialect.Prompt != nil {
+				line = c.dialect.Prompt.ReplaceAllString(line, "")
+			}
+
+			if line = clean(line); line != "" {
+				handler(line)
+			}
+		}
+
+		if err := c.read(time.Time{}); err != nil {
+			return fmt.Errorf("telnet: %w", err)
+		}
+	}
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// login waits for the prompts of the dialect, sends the username and the
+// password and skips the banner.
+func (c *Conn) login(password string) error {
+	deadline := time.Now().Add(c.settings.dialTimeout)
+
+	if c.dialect.LoginPrompt != nil {
+		if _, _, err := c.readUntil(c.dialect.LoginPrompt, deadline); err != nil {
+			return fmt.Errorf("telnet: login prompt: %w", err)
+		}
+
+		if err := c.write(c.dialect.Username); err != nil {
+			return err
+		}
+	}
+
+	if c.dialect.PasswordPrompt != nil {
+		if _, _, err := c.readUntil(c.dialect.PasswordPrompt, deadline); err != nil {
+			return fmt.Errorf("telnet: password prompt: %w", err)
+		}
+	}
+
+	if err := c.write(password); err != nil {
+		return err
+	}
+
+	var banner string
+	var err error
+
+	switch {
+	case c.dialect.Prompt != nil && c.dialect.AuthFailed != nil:
+		// The prompt does not appear after the wrong password.
+		either := regexp.MustCompile("(?:" + c.dialect.Prompt.String() + ")|(?:" + c.dialect.AuthFailed.String() + ")")
+
+		var match string
+		banner, match, err = c.readUntil(either, deadline)
+		banner += match
+	case c.dialect.Prompt != nil:
+		banner, _, err = c.readUntil(c.dialect.Prompt, deadline)
+	case c.dialect.SkipBannerLines > 0:
+		banner, err = c.readLines(c.dialect.SkipBannerLines, deadline)
+	default:
+		banner, err = c.readQuiet(deadline)
+	}
+
+	if c.dialect.AuthFailed != nil && c.dialect.AuthFailed.MatchString(banner) {
+		return telnet.ErrAuthFailed
+	}
+
+	if err != nil {
+		return fmt.Errorf("telnet: banner: %w", err)
+	}
+
+	return nil
+}
+
+// readUntil reads until the regular expression matches the received text.
+// The text before the match and the match are consumed and returned. On
+// error the text received so far is consumed and returned.
+func (c *Conn) readUntil(re *regexp.Regexp, deadline time.Time) (string, string, error) {
+	for {
+		if loc := re.FindIndex(c.pending); loc != nil {
+			text, match := string(c.pending[:loc[0]]), string(c.pending[loc[0]:loc[1]])
+			c.pending = c.pending[loc[1]:]
+
+			return text, match, nil
+		}
+
+		if err := c.read(deadline); err != nil {
+			return c.drain(), "", err
+		}
+	}
+}
+
+// readLines reads and consumes n lines.
+func (c *Conn) readLines(n int, deadline time.Time) (string, error) {
+	end := 0
+
+	for i := 0; i < n; i++ {
+		for {
+			next := bytes.IndexByte(c.pending[end:], '\n')
+			if next >= 0 {
+				end += next + 1
+
+				break
+			}
+
+			if err := c.read(deadline); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	text := string(c.pending[:end])
+	c.pending = c.pending[end:]
+
+	return text, nil
+}
+
+// readQuiet reads until the server sends nothing for the quiet period and
+// consumes everything received. os.ErrDeadlineExceeded is returned together
+// with the received text if the server is not quiet before the deadline.
+func (c *Conn) readQuiet(deadline time.Time) (string, error) {
+	for {
+		wait := time.Now().Add(c.settings.quietPeriod)
+		if !deadline.IsZero() && deadline.Before(wait) {
+			wait = deadline
+		}
+
+		err := c.read(wait)
+		if errors.Is(err, os.ErrDeadlineExceeded) && (deadline.IsZero() || time.Now().Before(deadline)) {
+			err = nil
+
+			break
+		}
+
+		if err != nil {
+			return c.drain(), err
+		}
+	}
+
+	return c.drain(), nil
+}
+
+// drain returns and consumes all pending bytes.
+func (c *Conn) drain() string {
+	text := string(c.pending)
+	c.pending = nil
+
+	return text
+}
+
+// read appends the next received bytes to pending.
+func (c *Conn) read(deadline time.Time) error {
+	if err := c.conn.SetReadDeadline(deadline); err != nil {
+		return err
+	}
+
+	buf := make([]byte, readBufferSize)
+
+	n, err := c.conn.Read(buf)
+	c.pending = append(c.pending, buf[:n]...)
+
+	return err
+}
+
+// write sends the line terminated by the line ending of the dialect.
+func (c *Conn) write(line string) error {
+	if _, err := c.conn.Write([]byte(line + c.dialect.Terminator)); err != nil {
+		return fmt.Errorf("telnet: %w", err)
+	}
+
+	return nil
+}
+
+// clean removes NUL bytes, carriage returns and surrounding empty lines.
+func clean(text string) string {
+	text = strings.NewReplacer("\x00", "", "\r", "").Replace(text)
+
+	return strings.Trim(text, "\n")
+}
@@ -0,0 +1,183 @@
+package telnetdialect_test
+
+import (
+	"bufio"
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/telnetdialect"
+	"github.com/gorcon/telnet"
+	"github.com/gorcon/telnet/telnettest"
+	"github.com/stretchr/testify/assert"
+)
+
+// serveConsole serves the console which asks for the username and the
+// password and ends the responses with the "> " prompt.
+func serveConsole(t *testing.T) net.Listener {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(conn net.Conn) {
+				defer conn.Close()
+
+				r := bufio.NewReader(conn)
+
+				_, _ = conn.Write([]byte("Welcome\nlogin: "))
+				username, _ := r.ReadString('\n')
+				_, _ = conn.Write([]byte("Password: "))
+				password, _ := r.ReadString('\n')
+
+				if strings.TrimSpace(username) != "admin" || strings.TrimSpace(password) != "password" {
+					_, _ = conn.Write([]byte("Access denied\n"))
+
+					return
+				}
+
+				_, _ = conn.Write([]byte("Logged in\n> "))
+
+				for {
+					command, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+
+					switch strings.TrimSpace(command) {
+					case "players":
+						_, _ = conn.Write([]byte("alice\nbob\n> "))
+					case "say":
+						_, _ = conn.Write([]byte("> "))
+						_, _ = conn.Write([]byte("Chat: hello\n> "))
+					default:
+						_, _ = conn.Write([]byte("Unknown command\n> "))
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	return listener
+}
+
+func TestDial(t *testing.T) {
+	listener := serveConsole(t)
+	defer listener.Close()
+
+	dialect := telnetdialect.Dialect{
+		Username:       "admin",
+		LoginPrompt:    regexp.MustCompile(`login: `),
+		PasswordPrompt: regexp.MustCompile(`Password: `),
+		AuthFailed:     regexp.MustCompile(`Access denied`),
+		Prompt:         regexp.MustCompile(`> `),
+		Terminator:     "\n",
+	}
+
+	t.Run("auth failed", func(t *testing.T) {
+		_, err := telnetdialect.Dial(listener.Addr().String(), "wrong", dialect, telnetdialect.SetDialTimeout(time.Second))
+		assert.ErrorIs(t, err, telnet.ErrAuthFailed)
+	})
+
+	t.Run("execute", func(t *testing.T) {
+		conn, err := telnetdialect.Dial(listener.Addr().String(), "password", dialect)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+
+		response, err := conn.Execute("players")
+		assert.NoError(t, err)
+		assert.Equal(t, "alice\nbob", response)
+
+		response, err = conn.Execute("unknown")
+		assert.NoError(t, err)
+		assert.Equal(t, "Unknown command", response)
+
+		_, err = conn.Execute("")
+		assert.ErrorIs(t, err, telnet.ErrCommandEmpty)
+
+		response, err = conn.ExecuteRaw("players")
+		assert.NoError(t, err)
+		assert.Equal(t, "alice\nbob\n", response)
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		conn, err := telnetdialect.Dial(listener.Addr().String(), "password", dialect)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		lines := make(chan string, 32)
+		done := make(chan error)
+
+		go func() {
+			done <- conn.Stream(func(line string) {
+				lines <- line
+			})
+		}()
+
+		assert.NoError(t, conn.Send("say"))
+
+		select {
+		case line := <-lines:
+			assert.Equal(t, "Chat: hello", line)
+		case <-time.After(time.Second):
+			t.Error("line is not received")
+		}
+
+		assert.NoError(t, conn.Close())
+		assert.Error(t, <-done)
+	})
+}
+
+func TestDial_Quiet(t *testing.T) {
+	server := telnettest.NewServer(
+		telnettest.SetSettings(telnettest.Settings{Password: "password"}),
+		telnettest.SetCommandHandler(func(c *telnettest.Context) {
+			if c.Request() == "version" {
+				_, _ = c.Writer().WriteString("Game version: Alpha 18.4 (b4)" + telnet.CRLF)
+				_ = c.Writer().Flush()
+			}
+		}),
+	)
+	defer server.Close()
+
+	// The dialect of 7 Days to Die: the responses end by silence.
+	dialect := telnetdialect.Dialect{
+		PasswordPrompt: regexp.MustCompile(`Please enter password:?`),
+		AuthFailed:     regexp.MustCompile(`Password incorrect`),
+	}
+
+	options := []telnetdialect.Option{
+		telnetdialect.SetDialTimeout(time.Second), telnetdialect.SetQuietPeriod(100 * time.Millisecond),
+	}
+
+	t.Run("auth failed", func(t *testing.T) {
+		_, err := telnetdialect.Dial(server.Addr(), "wrong", dialect, options...)
+		assert.ErrorIs(t, err, telnet.ErrAuthFailed)
+	})
+
+	t.Run("execute", func(t *testing.T) {
+		conn, err := telnetdialect.Dial(server.Addr(), "password", dialect, options...)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+
+		response, err := conn.Execute("version")
+		assert.NoError(t, err)
+		assert.Equal(t, "Game version: Alpha 18.4 (b4)", response)
+	})
+}
@@ -0,0 +1,167 @@
+// Package telnetstream contains the client of 7 Days to Die TELNET console
+// which passes every line of the server output to the handler.
+//
+// The server pushes log lines (chat, joins, errors) to the connected
+// clients, the responses to the commands are mixed with them. Unlike
+// github.com/gorcon/telnet, the client does not wait for the responses, so
+// nothing is dropped.
+package telnetstream
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorcon/telnet"
+)
+
+// DefaultDialTimeout is used when the option is not set.
+const DefaultDialTimeout = 5 * time.Second
+
+// Settings contains options of Conn.
+type Settings struct {
+	dialTimeout time.Duration
+	conn        net.Conn
+}
+
+// Option allows to inject settings to Settings.
+type Option func(s *Settings)
+
+// SetDialTimeout injects the timeout of dial and auth to Settings.
+func SetDialTimeout(timeout time.Duration) Option {
+	return func(s *Settings) {
+		s.dialTimeout = timeout
+	}
+}
+
+// SetConn injects the established connection to Settings. Dial uses it
+// instead of dialing the address.
+func SetConn(conn net.Conn) Option {
+	return func(s *Settings) {
+		s.conn = conn
+	}
+}
+
+// Conn is the authorized TELNET connection.
+type Conn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	// mu serializes the commands.
+	mu sync.Mutex
+}
+
+// Dial connects to the server and sends the password. Empty password is
+// not sent.
+func Dial(address string, password string, options ...Option) (*Conn, error) {
+	settings := Settings{dialTimeout: DefaultDialTimeout}
+	for _, option := range options {
+		option(&settings)
+	}
+
+	conn := settings.conn
+	if conn == nil {
+		var err error
+		if conn, err = net.DialTimeout("tcp", address, settings.dialTimeout); err != nil {
+			return nil, fmt.Errorf("telnet: %w", err)
+		}
+	}
+
+	c := Conn{conn: conn, reader: bufio.NewReader(conn)}
+
+	if password != "" {
+		if err := c.auth(password, settings.dialTimeout); err != nil {
+			_ = conn.Close()
+
+			return nil, err
+		}
+	}
+
+	return &c, nil
+}
+
+// Send sends the command without waiting for the response. The response is
+// received by Stream.
+func (c *Conn) Send(command string) error {
+	if command == "" {
+		return telnet.ErrCommandEmpty
+	}
+
+	if len(command) > telnet.MaxCommandLen {
+		return telnet.ErrCommandTooLong
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := io.WriteString(c.conn, command+telnet.CRLF); err != nil {
+		return fmt.Errorf("telnet: %w", err)
+	}
+
+	return nil
+}
+
+// Stream passes the lines of the server output to the handler until the
+// connection is closed.
+func (c *Conn) Stream(handler func(line string)) error {
+	for {
+		line, err := c.readLine()
+		if line != "" {
+			handler(line)
+		}
+
+		if err != nil {
+			return fmt.Errorf("telnet: %w", err)
+		}
+	}
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// auth sends the password and reads the lines until the result.
+func (c *Conn) auth(password string, timeout time.Duration) error {
+	if timeout > 0 {
+		if err := c.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+			return fmt.Errorf("telnet: %w", err)
+		}
+
+		defer func() {
+			_ = c.conn.SetDeadline(time.Time{})
+		}()
+	}
+
+	if err := c.Send(password); err != nil {
+		return err
+	}
+
+	for {
+		line, err := c.readLine()
+
+		switch {
+		case strings.Contains(line, telnet.ResponseAuthSuccess):
+			return nil
+		case strings.Contains(line, telnet.ResponseAuthIncorrectPassword),
+			strings.Contains(line, telnet.ResponseAuthTooManyFails):
+			return telnet.ErrAuthFailed
+		}
+
+		if err != nil {
+			return fmt.Errorf("telnet: %w", err)
+		}
+	}
+}
+
+// readLine reads the next line without the line break and null bytes.
+func (c *Conn) readLine() (string, error) {
+	line, err := c.reader.ReadString('\n')
+	line = strings.TrimRight(strings.ReplaceAll(line, telnet.NullString, ""), "\r\n")
+
+	return line, err
+}
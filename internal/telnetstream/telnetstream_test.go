@@ -0,0 +1,71 @@
+package telnetstream_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorcon/rcon-cli/internal/telnetstream"
+	"github.com/gorcon/telnet"
+	"github.com/gorcon/telnet/telnettest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConn(t *testing.T) {
+	server := telnettest.NewServer(
+		telnettest.SetSettings(telnettest.Settings{Password: "password"}),
+		telnettest.SetCommandHandler(func(c *telnettest.Context) {
+			if c.Request() == "version" {
+				_, _ = c.Writer().WriteString("Game version: Alpha 18.4 (b4)" + telnet.CRLF)
+				_ = c.Writer().Flush()
+			}
+		}),
+	)
+	defer server.Close()
+
+	t.Run("auth failed", func(t *testing.T) {
+		_, err := telnetstream.Dial(server.Addr(), "wrongpas", telnetstream.SetDialTimeout(time.Second))
+		assert.ErrorIs(t, err, telnet.ErrAuthFailed)
+	})
+
+	conn, err := telnetstream.Dial(server.Addr(), "password")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	lines := make(chan string, 32)
+	done := make(chan error)
+
+	go func() {
+		done <- conn.Stream(func(line string) {
+			lines <- line
+		})
+	}()
+
+	t.Run("stream", func(t *testing.T) {
+		assert.NoError(t, conn.Send("version"))
+
+		timeout := time.After(time.Second)
+
+		for {
+			select {
+			case line := <-lines:
+				if line == "Game version: Alpha 18.4 (b4)" {
+					return
+				}
+			case <-timeout:
+				t.Error("response is not received")
+
+				return
+			}
+		}
+	})
+
+	t.Run("empty command", func(t *testing.T) {
+		assert.ErrorIs(t, conn.Send(""), telnet.ErrCommandEmpty)
+	})
+
+	t.Run("close", func(t *testing.T) {
+		assert.NoError(t, conn.Close())
+		assert.Error(t, <-done)
+	})
+}
@@ -0,0 +1,222 @@
+// Package webrcon contains the client of Rust WebRCON protocol which
+// supports TLS (wss://) and the connection established by the caller.
+//
+// The protocol is the same as in github.com/gorcon/websocket, which always
+// dials ws:// URL with the default dialer. Messages and errors of that
+// package are reused, so both clients can be handled the same way.
+package webrcon
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorcon/websocket"
+	gorilla "github.com/gorilla/websocket"
+)
+
+// Settings contains options of Conn.
+type Settings struct {
+	dialTimeout time.Duration
+	deadline    time.Duration
+	tls         *tls.Config
+	conn        net.Conn
+}
+
+// Option allows to inject settings to Settings.
+type Option func(s *Settings)
+
+// SetDialTimeout injects the timeout of the websocket handshake to Settings.
+func SetDialTimeout(timeout time.Duration) Option {
+	return func(s *Settings) {
+		s.dialTimeout = timeout
+	}
+}
+
+// SetDeadline injects the timeout of read and write operations to Settings.
+func SetDeadline(timeout time.Duration) Option {
+	return func(s *Settings) {
+		s.deadline = timeout
+	}
+}
+
+// SetTLSConfig injects TLS config to Settings. The server is connected over
+// wss:// if it is set.
+func SetTLSConfig(config *tls.Config) Option {
+	return func(s *Settings) {
+		s.tls = config
+	}
+}
+
+// SetConn injects the established TCP connection to Settings. Dial makes
+// the handshake over it instead of dialing the address. Host header and TLS
+// server name still contain the server address.
+func SetConn(conn net.Conn) Option {
+	return func(s *Settings) {
+		s.conn = conn
+	}
+}
+
+// Conn is the WebRCON connection.
+type Conn struct {
+	conn     *gorilla.Conn
+	settings Settings
+}
+
+// Dial connects to the server. The password is the path of the URL, so the
+// server rejects the handshake if it is wrong.
+func Dial(address string, password string, options ...Option) (*Conn, error) {
+	settings := Settings{dialTimeout: websocket.DefaultDialTimeout, deadline: websocket.DefaultDeadline}
+	for _, option := range options {
+		option(&settings)
+	}
+
+	u := url.URL{Scheme: "ws", Host: address, Path: password}
+	if settings.tls != nil {
+		u.Scheme = "wss"
+	}
+
+	dialer := gorilla.Dialer{HandshakeTimeout: settings.dialTimeout, TLSClientConfig: settings.tls}
+	if settings.conn != nil {
+		dialer.NetDialContext = func(context.Context, string, string) (net.Conn, error) {
+			return settings.conn, nil
+		}
+	}
+
+	conn, _, err := dialer.Dial(u.String(), nil)
+	if err != nil {
+		// Rust closes the connection instead of the handshake response.
+		if err.Error() == `malformed HTTP response "\x88\x02\x03\xe8"` {
+			return nil, websocket.ErrAuthFailed
+		}
+
+		return nil, fmt.Errorf("webrcon: %w", err)
+	}
+
+	return &Conn{conn: conn, settings: settings}, nil
+}
+
+// Execute sends the command and waits for the response with the same
+// identifier. Other messages, e.g. logs, are skipped.
+func (c *Conn) Execute(command string) (string, error) {
+	if command == "" {
+		return "", websocket.ErrCommandEmpty
+	}
+
+	if len(command) > websocket.MaxCommandLen {
+		return "", websocket.ErrCommandTooLong
+	}
+
+	request := websocket.Message{
+		Message:    command,
+		Identifier: rand.Intn(websocket.RandIdentifierLimit),
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("webrcon: %w", err)
+	}
+
+	if err := c.conn.SetWriteDeadline(c.deadline()); err != nil {
+		return "", fmt.Errorf("webrcon: %w", err)
+	}
+
+	if err := c.conn.WriteMessage(gorilla.TextMessage, data); err != nil {
+		return "", fmt.Errorf("webrcon: %w", err)
+	}
+
+	for {
+		if err := c.conn.SetReadDeadline(c.deadline()); err != nil {
+			return "", fmt.Errorf("webrcon: %w", err)
+		}
+
+		_, p, err := c.conn.ReadMessage()
+		if err != nil {
+			return "", fmt.Errorf("webrcon: %w", err)
+		}
+
+		var response websocket.Message
+		if err := json.Unmarshal(p, &response); err != nil {
+			return "", fmt.Errorf("webrcon: %w", err)
+		}
+
+		if response.Identifier == request.Identifier {
+			return response.Message, nil
+		}
+	}
+}
+
+// Send sends the command without waiting for the response. The response is
+// received by Stream.
+func (c *Conn) Send(command string) error {
+	if command == "" {
+		return websocket.ErrCommandEmpty
+	}
+
+	if len(command) > websocket.MaxCommandLen {
+		return websocket.ErrCommandTooLong
+	}
+
+	data, err := json.Marshal(websocket.Message{Message: command, Identifier: rand.Intn(websocket.RandIdentifierLimit)})
+	if err != nil {
+		return fmt.Errorf("webrcon: %w", err)
+	}
+
+	if err := c.conn.SetWriteDeadline(c.deadline()); err != nil {
+		return fmt.Errorf("webrcon: %w", err)
+	}
+
+	if err := c.conn.WriteMessage(gorilla.TextMessage, data); err != nil {
+		return fmt.Errorf("webrcon: %w", err)
+	}
+
+	return nil
+}
+
+// Stream passes the messages of the server, e.g. logs, chat and responses
+// to the sent commands, to the handler until the connection is closed.
+// Messages of several lines are passed line by line.
+func (c *Conn) Stream(handler func(line string)) error {
+	if err := c.conn.SetReadDeadline(time.Time{}); err != nil {
+		return fmt.Errorf("webrcon: %w", err)
+	}
+
+	for {
+		_, p, err := c.conn.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("webrcon: %w", err)
+		}
+
+		var message websocket.Message
+		if err := json.Unmarshal(p, &message); err != nil {
+			return fmt.Errorf("webrcon: %w", err)
+		}
+
+		for _, line := range strings.Split(strings.TrimRight(message.Message, "\n"), "\n") {
+			if line != "" {
+				handler(line)
+			}
+		}
+	}
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// deadline returns the deadline of the next operation. Zero timeout means
+// no deadline.
+func (c *Conn) deadline() time.Time {
+	if c.settings.deadline == 0 {
+		return time.Time{}
+	}
+
+	return time.Now().Add(c.settings.deadline)
+}
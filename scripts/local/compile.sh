@@ -3,6 +3,10 @@
 VERSION="$1"
 if [ -z "${VERSION}" ]; then echo "VERSION is not set. Use ./compile.sh 0.0.0" >&2; exit 1; fi
 
+COMMIT="$(git rev-parse HEAD)"
+BUILD_TIME="$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+LDFLAGS="-s -w -X main.Version=${VERSION} -X main.Commit=${COMMIT} -X main.BuildTime=${BUILD_TIME}"
+
 RELEASE_DIR=".tmp/release"
 
 rm -r "${RELEASE_DIR}"
@@ -20,7 +24,7 @@ make_release() {
     local dir="${RELEASE_DIR}/${release_name}"
 
     mkdir -p "${dir}"
-    env GOARCH="${arch}" GOOS="${os}" CGO_ENABLED=0 go build -ldflags "-s -w -X main.Version=${VERSION}" -o "${dir}/rcon${ext}" ./cmd/gorcon/main.go
+    env GOARCH="${arch}" GOOS="${os}" CGO_ENABLED=0 go build -ldflags "${LDFLAGS}" -o "${dir}/rcon${ext}" ./cmd/gorcon/main.go
 
     cp LICENSE "${dir}"
     cp README.md "${dir}"
@@ -49,4 +53,4 @@ make_release amd64 windows "rcon-${VERSION}-win64" .exe
 make_release amd64 darwin "rcon-${VERSION}-amd64_darwin"
 make_release arm64 darwin "rcon-${VERSION}-arm64_darwin"
 
-env GOARCH="amd64" GOOS="linux" CGO_ENABLED=0 go build -ldflags "-s -w -X main.Version=${VERSION}" -o gorcon ./cmd/gorcon/main.go
+env GOARCH="amd64" GOOS="linux" CGO_ENABLED=0 go build -ldflags "${LDFLAGS}" -o gorcon ./cmd/gorcon/main.go